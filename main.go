@@ -14,10 +14,22 @@ import (
 	_ "github.com/khoa5773/go-server/docs"
 	"github.com/khoa5773/go-server/src/configs"
 	"github.com/khoa5773/go-server/src/domains/auth"
+	cloudstorage "github.com/khoa5773/go-server/src/domains/cloudStorage"
 	"github.com/khoa5773/go-server/src/domains/documents"
+	"github.com/khoa5773/go-server/src/domains/emails"
+	"github.com/khoa5773/go-server/src/domains/events"
+	"github.com/khoa5773/go-server/src/domains/invitations"
+	"github.com/khoa5773/go-server/src/domains/jobs"
+	"github.com/khoa5773/go-server/src/domains/logs"
+	"github.com/khoa5773/go-server/src/domains/models"
+	"github.com/khoa5773/go-server/src/domains/predictions"
 	"github.com/khoa5773/go-server/src/domains/projects"
 	"github.com/khoa5773/go-server/src/domains/repositories"
+	"github.com/khoa5773/go-server/src/domains/selections"
+	"github.com/khoa5773/go-server/src/domains/uploads"
 	"github.com/khoa5773/go-server/src/domains/users"
+	"github.com/khoa5773/go-server/src/domains/validations"
+	"github.com/khoa5773/go-server/src/middleware/requestid"
 	"github.com/khoa5773/go-server/src/shared"
 )
 
@@ -34,20 +46,46 @@ func main() {
 	app := gin.Default()
 
 	app.Use(cors.Default())
+	app.Use(requestid.RequestID)
 	app.Use(shared.ErrorHandler)
 
 	binding.Validator = &shared.DefaultValidator{}
 
+	err := cloudstorage.Setup()
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	err = models.Setup()
+	if err != nil {
+		log.Fatalln(err)
+	}
+
 	swaggerURL := ginSwagger.URL(fmt.Sprintf("http://%s:%d/swagger/doc.json", configs.ConfigsService.Host, configs.ConfigsService.Port))
 	app.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler, swaggerURL))
 
+	cloudstorage.ApplyRoutes(app)
 	auth.ApplyRoutes(app)
 	users.ApplyRoutes(app)
 	projects.ApplyRoutes(app)
 	repositories.ApplyRoutes(app)
 	documents.ApplyRoutes(app)
+	uploads.ApplyRoutes(app)
+	predictions.ApplyRoutes(app)
+	validations.ApplyRoutes(app)
+	models.ApplyRoutes(app)
+	selections.ApplyRoutes(app)
+	invitations.ApplyRoutes(app)
+	jobs.ApplyRoutes(app)
+	logs.ApplyRoutes(app)
+	events.ApplyRoutes(app)
+
+	events.Start()
+	emails.Start()
+	uploads.Start()
+	jobs.Start(configs.ConfigsService.JobWorkers)
 
-	err := app.Run(fmt.Sprintf(":%d", configs.ConfigsService.Port))
+	err = app.Run(fmt.Sprintf(":%d", configs.ConfigsService.Port))
 	if err != nil {
 		log.Fatalln(err)
 	}
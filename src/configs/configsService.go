@@ -4,7 +4,9 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"runtime"
 	"strconv"
+	"strings"
 
 	"github.com/joho/godotenv"
 )
@@ -24,6 +26,27 @@ type EnvConfig struct {
 	GGProjectID          string
 	GGServiceAccountPath string
 	GGCloudStorageBucket string
+	JobWorkers           int
+	AppURL               string
+	EmailTransport       string
+	EmailFrom            string
+	EmailDir             string
+	SMTPHost             string
+	SMTPPort             int
+	SMTPUser             string
+	SMTPPass             string
+	SendGridAPIKey       string
+	AdminIDs             []string
+	StorageDriver        string
+	StorageDir           string
+	StorageSigningKey    string
+	S3Endpoint           string
+	S3Region             string
+	S3Bucket             string
+	S3AccessKey          string
+	S3SecretKey          string
+	S3PathStyle          bool
+	MaxUploadSize        int64
 }
 
 func loadAndValidateEnv() EnvConfig {
@@ -48,6 +71,65 @@ func loadAndValidateEnv() EnvConfig {
 		log.Fatal("Error loading DB port")
 	}
 
+	jobWorkers := runtime.NumCPU()
+	if os.Getenv("JOB_WORKERS") != "" {
+		jobWorkers, err = strconv.Atoi(os.Getenv("JOB_WORKERS"))
+		if err != nil || jobWorkers < 1 {
+			log.Fatal("Error loading job workers")
+		}
+	}
+
+	emailTransport := os.Getenv("EMAIL_TRANSPORT")
+	if emailTransport == "" {
+		// deployments from before EMAIL_TRANSPORT keep booting, their emails wait in the outbox
+		log.Println("Warning: EMAIL_TRANSPORT is not set, emails are queued but not sent until it is smtp, sendgrid, file or memory")
+		emailTransport = "none"
+	}
+	// the memory transport marks emails SENT without delivering them
+	if emailTransport == "memory" && env != "development" {
+		log.Fatal("Error loading email transport, memory is only allowed when GIN_ENV is development")
+	}
+
+	smtpPort := 587
+	if os.Getenv("SMTP_PORT") != "" {
+		smtpPort, err = strconv.Atoi(os.Getenv("SMTP_PORT"))
+		if err != nil {
+			log.Fatal("Error loading SMTP port")
+		}
+	}
+
+	adminIDs := []string{}
+	for _, id := range strings.Split(os.Getenv("ADMIN_IDS"), ",") {
+		if id = strings.TrimSpace(id); id != "" {
+			adminIDs = append(adminIDs, id)
+		}
+	}
+
+	storageDriver := os.Getenv("STORAGE_DRIVER")
+	if storageDriver == "" {
+		// deployments from before STORAGE_DRIVER keep their files in the Google Cloud Storage bucket
+		if os.Getenv("GG_CLOUDSTORAGE_BUCKET") == "" {
+			log.Fatal("Error loading storage driver, STORAGE_DRIVER must be gcs, s3 or local")
+		}
+		storageDriver = "gcs"
+	}
+
+	s3PathStyle := false
+	if os.Getenv("S3_PATH_STYLE") != "" {
+		s3PathStyle, err = strconv.ParseBool(os.Getenv("S3_PATH_STYLE"))
+		if err != nil {
+			log.Fatal("Error loading S3 path style")
+		}
+	}
+
+	var maxUploadSize int64 = 2 << 30
+	if os.Getenv("MAX_UPLOAD_SIZE") != "" {
+		maxUploadSize, err = strconv.ParseInt(os.Getenv("MAX_UPLOAD_SIZE"), 10, 64)
+		if err != nil || maxUploadSize < 1 {
+			log.Fatal("Error loading max upload size")
+		}
+	}
+
 	return EnvConfig{
 		Host:                 os.Getenv("HOST"),
 		Port:                 port,
@@ -63,6 +145,27 @@ func loadAndValidateEnv() EnvConfig {
 		GGProjectID:          os.Getenv("GG_PROJECT_ID"),
 		GGServiceAccountPath: os.Getenv("GG_SERVICE_ACCOUNT_PATH"),
 		GGCloudStorageBucket: os.Getenv("GG_CLOUDSTORAGE_BUCKET"),
+		JobWorkers:           jobWorkers,
+		AppURL:               os.Getenv("APP_URL"),
+		EmailTransport:       emailTransport,
+		EmailFrom:            os.Getenv("EMAIL_FROM"),
+		EmailDir:             os.Getenv("EMAIL_DIR"),
+		SMTPHost:             os.Getenv("SMTP_HOST"),
+		SMTPPort:             smtpPort,
+		SMTPUser:             os.Getenv("SMTP_USER"),
+		SMTPPass:             os.Getenv("SMTP_PASS"),
+		SendGridAPIKey:       os.Getenv("SENDGRID_API_KEY"),
+		AdminIDs:             adminIDs,
+		StorageDriver:        storageDriver,
+		StorageDir:           os.Getenv("STORAGE_DIR"),
+		StorageSigningKey:    os.Getenv("STORAGE_SIGNING_KEY"),
+		S3Endpoint:           os.Getenv("S3_ENDPOINT"),
+		S3Region:             os.Getenv("S3_REGION"),
+		S3Bucket:             os.Getenv("S3_BUCKET"),
+		S3AccessKey:          os.Getenv("S3_ACCESS_KEY"),
+		S3SecretKey:          os.Getenv("S3_SECRET_KEY"),
+		S3PathStyle:          s3PathStyle,
+		MaxUploadSize:        maxUploadSize,
 	}
 }
 
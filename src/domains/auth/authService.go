@@ -8,6 +8,7 @@ import (
 
 	oidc "github.com/coreos/go-oidc"
 	"github.com/khoa5773/go-server/src/configs"
+	"github.com/khoa5773/go-server/src/domains/logs"
 	"github.com/khoa5773/go-server/src/domains/users"
 	"github.com/khoa5773/go-server/src/shared"
 )
@@ -71,6 +72,7 @@ func handleLoginCallback(c context.Context, code string) (string, error) {
 		return "", err
 	}
 
+	logs.Record(credentials, "", logs.USER, credentials.Id, logs.LOGIN, nil, nil)
 	return rawIDToken, nil
 }
 
@@ -155,7 +157,7 @@ func InitUserSetting(userInfo *users.CreateUserDto) {
 		},
 		"LASSO": map[string]interface{}{
 			"default": "time",
-			"time":    1.5,
+			"time":    1.5, // minutes
 		},
 		"G_BLUP": map[string]interface{}{
 			"default": "",
@@ -0,0 +1,138 @@
+package blobstore
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"time"
+
+	cloudStorage "cloud.google.com/go/storage"
+	firebase "firebase.google.com/go"
+	"golang.org/x/oauth2/google"
+	"google.golang.org/api/iterator"
+	"google.golang.org/api/option"
+)
+
+// GCSStore keeps objects in a Google Cloud Storage bucket
+type GCSStore struct {
+	bucketName     string
+	bucket         *cloudStorage.BucketHandle
+	googleAccessID string
+	privateKey     []byte
+}
+
+func NewGCSStore(ctx context.Context, bucketName, serviceAccountPath string) (*GCSStore, error) {
+	saKey, err := ioutil.ReadFile(serviceAccountPath)
+	if err != nil {
+		return nil, err
+	}
+
+	jwtConfig, err := google.JWTConfigFromJSON(saKey)
+	if err != nil {
+		return nil, err
+	}
+
+	app, err := firebase.NewApp(ctx, &firebase.Config{StorageBucket: bucketName}, option.WithCredentialsJSON(saKey))
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := app.Storage(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	bucket, err := client.DefaultBucket()
+	if err != nil {
+		return nil, err
+	}
+
+	return &GCSStore{bucketName: bucketName, bucket: bucket, googleAccessID: jwtConfig.Email, privateKey: jwtConfig.PrivateKey}, nil
+}
+
+func (s *GCSStore) Put(ctx context.Context, key string, content io.Reader, contentType string) (int64, error) {
+	// cancelling before Close discards the object instead of committing what was written so far
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	w := s.bucket.Object(key).NewWriter(ctx)
+	w.ContentType = contentType
+
+	size, err := io.Copy(w, content)
+	if err != nil {
+		cancel()
+		_ = w.Close()
+		return 0, err
+	}
+
+	if err := w.Close(); err != nil {
+		return 0, err
+	}
+
+	return size, nil
+}
+
+func (s *GCSStore) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	reader, err := s.bucket.Object(key).NewReader(ctx)
+	if errors.Is(err, cloudStorage.ErrObjectNotExist) {
+		return nil, ErrNotFound
+	}
+	return reader, err
+}
+
+func (s *GCSStore) GetRange(ctx context.Context, key string, offset, length int64) (io.ReadCloser, error) {
+	reader, err := s.bucket.Object(key).NewRangeReader(ctx, offset, length)
+	if errors.Is(err, cloudStorage.ErrObjectNotExist) {
+		return nil, ErrNotFound
+	}
+	return reader, err
+}
+
+func (s *GCSStore) Delete(ctx context.Context, key string) error {
+	err := s.bucket.Object(key).Delete(ctx)
+	if errors.Is(err, cloudStorage.ErrObjectNotExist) {
+		return ErrNotFound
+	}
+	return err
+}
+
+func (s *GCSStore) Stat(ctx context.Context, key string) (ObjectInfo, error) {
+	attrs, err := s.bucket.Object(key).Attrs(ctx)
+	if errors.Is(err, cloudStorage.ErrObjectNotExist) {
+		return ObjectInfo{}, ErrNotFound
+	}
+	if err != nil {
+		return ObjectInfo{}, err
+	}
+
+	return gcsObjectInfo(attrs), nil
+}
+
+func (s *GCSStore) SignedURL(key string, expires time.Duration) (string, error) {
+	return cloudStorage.SignedURL(s.bucketName, key, &cloudStorage.SignedURLOptions{
+		GoogleAccessID: s.googleAccessID,
+		PrivateKey:     s.privateKey,
+		Method:         "GET",
+		Expires:        time.Now().Add(expires),
+	})
+}
+
+func (s *GCSStore) List(ctx context.Context, prefix string) ([]ObjectInfo, error) {
+	objects := []ObjectInfo{}
+	it := s.bucket.Objects(ctx, &cloudStorage.Query{Prefix: prefix})
+	for {
+		attrs, err := it.Next()
+		if err == iterator.Done {
+			return objects, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		objects = append(objects, gcsObjectInfo(attrs))
+	}
+}
+
+func gcsObjectInfo(attrs *cloudStorage.ObjectAttrs) ObjectInfo {
+	return ObjectInfo{Key: attrs.Name, Size: attrs.Size, ContentType: attrs.ContentType, UpdatedAt: attrs.Updated}
+}
@@ -0,0 +1,241 @@
+package blobstore
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"mime"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// LocalStore keeps objects on the local filesystem, its signed URLs are served by the app
+type LocalStore struct {
+	dir        string
+	signingKey []byte
+	baseURL    string
+}
+
+// NewLocalStore keeps objects in dir, baseURL is where the app serves them
+func NewLocalStore(dir, signingKey, baseURL string) (*LocalStore, error) {
+	if dir == "" {
+		dir = "storage"
+	}
+	err := os.MkdirAll(dir, 0755)
+	if err != nil {
+		return nil, err
+	}
+
+	key := []byte(signingKey)
+	if len(key) == 0 {
+		// URLs signed before a restart stop working, which is fine on a laptop
+		key = make([]byte, 32)
+		_, err = rand.Read(key)
+		if err != nil {
+			return nil, err
+		}
+		log.Println("storage: STORAGE_SIGNING_KEY is not set, using a random key")
+	}
+
+	return &LocalStore{dir: dir, signingKey: key, baseURL: strings.TrimRight(baseURL, "/")}, nil
+}
+
+// path maps a key inside the storage directory, keys can not climb out of it
+func (s *LocalStore) path(key string) (string, error) {
+	cleaned := path.Clean("/" + key)
+	if key == "" || cleaned == "/" || strings.HasSuffix(key, "/") {
+		return "", fmt.Errorf("storage: invalid key %q", key)
+	}
+	return filepath.Join(s.dir, filepath.FromSlash(cleaned[1:])), nil
+}
+
+// Path returns the file holding a key so that the app can serve it
+func (s *LocalStore) Path(key string) (string, error) {
+	return s.path(key)
+}
+
+func (s *LocalStore) Put(ctx context.Context, key string, content io.Reader, contentType string) (int64, error) {
+	target, err := s.path(key)
+	if err != nil {
+		return 0, err
+	}
+
+	err = os.MkdirAll(filepath.Dir(target), 0755)
+	if err != nil {
+		return 0, err
+	}
+
+	// written aside then renamed, so that readers never see a partial object
+	file, err := ioutil.TempFile(filepath.Dir(target), ".upload-*")
+	if err != nil {
+		return 0, err
+	}
+	defer os.Remove(file.Name())
+
+	size, err := io.Copy(file, content)
+	if err != nil {
+		_ = file.Close()
+		return 0, err
+	}
+
+	err = file.Close()
+	if err != nil {
+		return 0, err
+	}
+
+	err = os.Rename(file.Name(), target)
+	if err != nil {
+		return 0, err
+	}
+
+	return size, nil
+}
+
+func (s *LocalStore) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	target, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.Open(target)
+	if os.IsNotExist(err) {
+		return nil, ErrNotFound
+	}
+	return file, err
+}
+
+func (s *LocalStore) GetRange(ctx context.Context, key string, offset, length int64) (io.ReadCloser, error) {
+	content, err := s.Get(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+
+	file := content.(*os.File)
+	_, err = file.Seek(offset, io.SeekStart)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	if length < 0 {
+		return file, nil
+	}
+
+	return struct {
+		io.Reader
+		io.Closer
+	}{io.LimitReader(file, length), file}, nil
+}
+
+func (s *LocalStore) Delete(ctx context.Context, key string) error {
+	target, err := s.path(key)
+	if err != nil {
+		return err
+	}
+
+	err = os.Remove(target)
+	if os.IsNotExist(err) {
+		return ErrNotFound
+	}
+	return err
+}
+
+func (s *LocalStore) Stat(ctx context.Context, key string) (ObjectInfo, error) {
+	target, err := s.path(key)
+	if err != nil {
+		return ObjectInfo{}, err
+	}
+
+	info, err := os.Stat(target)
+	if os.IsNotExist(err) {
+		return ObjectInfo{}, ErrNotFound
+	}
+	if err != nil {
+		return ObjectInfo{}, err
+	}
+
+	return localObjectInfo(key, info), nil
+}
+
+func (s *LocalStore) SignedURL(key string, expires time.Duration) (string, error) {
+	_, err := s.path(key)
+	if err != nil {
+		return "", err
+	}
+
+	expiresAt := strconv.FormatInt(time.Now().Add(expires).Unix(), 10)
+	query := url.Values{"expires": {expiresAt}, "signature": {s.sign(key, expiresAt)}}
+	return fmt.Sprintf("%s/%s?%s", s.baseURL, escapeKey(key), query.Encode()), nil
+}
+
+// Verify checks a URL produced by SignedURL
+func (s *LocalStore) Verify(key, expiresAt, signature string) error {
+	expires, err := strconv.ParseInt(expiresAt, 10, 64)
+	if err != nil || time.Now().Unix() > expires {
+		return errors.New("link has expired")
+	}
+	if !hmac.Equal([]byte(signature), []byte(s.sign(key, expiresAt))) {
+		return errors.New("invalid signature")
+	}
+	return nil
+}
+
+func (s *LocalStore) sign(key, expiresAt string) string {
+	mac := hmac.New(sha256.New, s.signingKey)
+	mac.Write([]byte(key + "\n" + expiresAt))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func (s *LocalStore) List(ctx context.Context, prefix string) ([]ObjectInfo, error) {
+	objects := []ObjectInfo{}
+	err := filepath.Walk(s.dir, func(name string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() || strings.HasPrefix(info.Name(), ".upload-") {
+			return nil
+		}
+
+		relative, err := filepath.Rel(s.dir, name)
+		if err != nil {
+			return err
+		}
+		key := filepath.ToSlash(relative)
+		if strings.HasPrefix(key, prefix) {
+			objects = append(objects, localObjectInfo(key, info))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return objects, nil
+}
+
+func localObjectInfo(key string, info os.FileInfo) ObjectInfo {
+	contentType := mime.TypeByExtension(path.Ext(key))
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	return ObjectInfo{Key: key, Size: info.Size(), ContentType: contentType, UpdatedAt: info.ModTime()}
+}
+
+func escapeKey(key string) string {
+	segments := strings.Split(key, "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+	return strings.Join(segments, "/")
+}
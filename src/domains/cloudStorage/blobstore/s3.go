@@ -0,0 +1,513 @@
+package blobstore
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	s3Algorithm       = "AWS4-HMAC-SHA256"
+	s3UnsignedPayload = "UNSIGNED-PAYLOAD"
+	s3EmptyPayload    = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+	s3DateFormat      = "20060102T150405Z"
+	// s3PartSize is the size of the parts of a multipart upload, S3 takes single PUTs up to 5 GB and
+	// at most 10000 parts
+	s3PartSize = 64 << 20
+	s3MaxParts = 10000
+)
+
+type S3Options struct {
+	Endpoint  string
+	Region    string
+	Bucket    string
+	AccessKey string
+	SecretKey string
+	// PathStyle addresses the bucket in the path, as MinIO expects, instead of the host name
+	PathStyle bool
+}
+
+// S3Store talks to S3-compatible stores with requests signed with AWS Signature Version 4
+type S3Store struct {
+	options  S3Options
+	endpoint *url.URL
+	client   *http.Client
+	now      func() time.Time
+	partSize int64
+}
+
+func NewS3Store(options S3Options) (*S3Store, error) {
+	if options.Bucket == "" || options.AccessKey == "" || options.SecretKey == "" {
+		return nil, errors.New("storage: S3_BUCKET, S3_ACCESS_KEY and S3_SECRET_KEY are required by the s3 driver")
+	}
+	if options.Endpoint == "" {
+		options.Endpoint = "https://s3.amazonaws.com"
+	}
+	if options.Region == "" {
+		options.Region = "us-east-1"
+	}
+
+	endpoint, err := url.Parse(options.Endpoint)
+	if err != nil {
+		return nil, err
+	}
+	if endpoint.Scheme == "" || endpoint.Host == "" {
+		return nil, fmt.Errorf("storage: invalid S3 endpoint %q", options.Endpoint)
+	}
+
+	return &S3Store{options: options, endpoint: endpoint, client: &http.Client{}, now: time.Now, partSize: s3PartSize}, nil
+}
+
+// objectURL addresses a key, or the bucket itself when the key is empty
+func (s *S3Store) objectURL(key string) *url.URL {
+	target := *s.endpoint
+	if s.options.PathStyle {
+		target.Path = "/" + s.options.Bucket + "/" + key
+	} else {
+		target.Host = s.options.Bucket + "." + target.Host
+		target.Path = "/" + key
+	}
+	target.RawPath = s3EscapePath(target.Path)
+	return &target
+}
+
+// Put sends content smaller than a part in a single PUT and larger content as a multipart upload
+func (s *S3Store) Put(ctx context.Context, key string, content io.Reader, contentType string) (int64, error) {
+	// S3 needs the length of every request up front, so each part is spooled to disk and hashed on the way
+	spool, err := ioutil.TempFile("", "s3-upload-*")
+	if err != nil {
+		return 0, err
+	}
+	defer os.Remove(spool.Name())
+	defer spool.Close()
+
+	size, hash, err := s.spoolPart(spool, content)
+	if err != nil {
+		return 0, err
+	}
+	if size < s.partSize {
+		request, err := http.NewRequest(http.MethodPut, s.objectURL(key).String(), ioutil.NopCloser(spool))
+		if err != nil {
+			return 0, err
+		}
+		request.ContentLength = size
+		request.Header.Set("Content-Type", contentType)
+
+		response, err := s.do(ctx, request, hash)
+		if err != nil {
+			return 0, err
+		}
+		response.Body.Close()
+		return size, nil
+	}
+
+	uploadID, err := s.createMultipartUpload(ctx, key, contentType)
+	if err != nil {
+		return 0, err
+	}
+
+	total, err := s.uploadParts(ctx, key, uploadID, spool, size, hash, content)
+	if err != nil {
+		// a multipart upload left open keeps its parts billed until it is aborted
+		_ = s.abortMultipartUpload(context.Background(), key, uploadID)
+		return 0, err
+	}
+	return total, nil
+}
+
+// spoolPart copies the next part of content to the spool, rewound for reading, and returns its size and hash
+func (s *S3Store) spoolPart(spool *os.File, content io.Reader) (int64, string, error) {
+	_, err := spool.Seek(0, io.SeekStart)
+	if err != nil {
+		return 0, "", err
+	}
+	err = spool.Truncate(0)
+	if err != nil {
+		return 0, "", err
+	}
+
+	hash := sha256.New()
+	size, err := io.CopyN(io.MultiWriter(spool, hash), content, s.partSize)
+	if err != nil && err != io.EOF {
+		return 0, "", err
+	}
+
+	_, err = spool.Seek(0, io.SeekStart)
+	if err != nil {
+		return 0, "", err
+	}
+	return size, hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+type s3Part struct {
+	PartNumber int    `xml:"PartNumber"`
+	ETag       string `xml:"ETag"`
+}
+
+// uploadParts sends the part already in the spool and the rest of content, then completes the upload
+func (s *S3Store) uploadParts(ctx context.Context, key, uploadID string, spool *os.File, size int64, hash string, content io.Reader) (int64, error) {
+	var parts []s3Part
+	var total int64
+	for size > 0 {
+		if len(parts) == s3MaxParts {
+			return 0, fmt.Errorf("s3: content is over %d parts of %d bytes", s3MaxParts, s.partSize)
+		}
+
+		target := s.objectURL(key)
+		target.RawQuery = canonicalQuery(url.Values{"partNumber": {strconv.Itoa(len(parts) + 1)}, "uploadId": {uploadID}})
+		request, err := http.NewRequest(http.MethodPut, target.String(), ioutil.NopCloser(spool))
+		if err != nil {
+			return 0, err
+		}
+		request.ContentLength = size
+
+		response, err := s.do(ctx, request, hash)
+		if err != nil {
+			return 0, err
+		}
+		response.Body.Close()
+
+		parts = append(parts, s3Part{PartNumber: len(parts) + 1, ETag: response.Header.Get("ETag")})
+		total += size
+
+		size, hash, err = s.spoolPart(spool, content)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	body, err := xml.Marshal(struct {
+		XMLName xml.Name `xml:"CompleteMultipartUpload"`
+		Parts   []s3Part `xml:"Part"`
+	}{Parts: parts})
+	if err != nil {
+		return 0, err
+	}
+
+	target := s.objectURL(key)
+	target.RawQuery = canonicalQuery(url.Values{"uploadId": {uploadID}})
+	request, err := http.NewRequest(http.MethodPost, target.String(), bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	bodyHash := sha256.Sum256(body)
+
+	response, err := s.do(ctx, request, hex.EncodeToString(bodyHash[:]))
+	if err != nil {
+		return 0, err
+	}
+	defer response.Body.Close()
+
+	// S3 can fail a completion after answering 200, the error is then the body
+	var result struct {
+		XMLName xml.Name
+		Code    string `xml:"Code"`
+		Message string `xml:"Message"`
+	}
+	err = xml.NewDecoder(response.Body).Decode(&result)
+	if err != nil {
+		return 0, err
+	}
+	if result.XMLName.Local == "Error" {
+		return 0, fmt.Errorf("s3: completing the upload of %s: %s: %s", key, result.Code, result.Message)
+	}
+
+	return total, nil
+}
+
+func (s *S3Store) createMultipartUpload(ctx context.Context, key, contentType string) (string, error) {
+	target := s.objectURL(key)
+	target.RawQuery = canonicalQuery(url.Values{"uploads": {""}})
+	request, err := http.NewRequest(http.MethodPost, target.String(), nil)
+	if err != nil {
+		return "", err
+	}
+	request.Header.Set("Content-Type", contentType)
+
+	response, err := s.do(ctx, request, s3EmptyPayload)
+	if err != nil {
+		return "", err
+	}
+	defer response.Body.Close()
+
+	var result struct {
+		UploadID string `xml:"UploadId"`
+	}
+	err = xml.NewDecoder(response.Body).Decode(&result)
+	if err != nil {
+		return "", err
+	}
+	if result.UploadID == "" {
+		return "", fmt.Errorf("s3: no upload ID for %s", key)
+	}
+	return result.UploadID, nil
+}
+
+func (s *S3Store) abortMultipartUpload(ctx context.Context, key, uploadID string) error {
+	target := s.objectURL(key)
+	target.RawQuery = canonicalQuery(url.Values{"uploadId": {uploadID}})
+	request, err := http.NewRequest(http.MethodDelete, target.String(), nil)
+	if err != nil {
+		return err
+	}
+
+	response, err := s.do(ctx, request, s3EmptyPayload)
+	if err != nil {
+		return err
+	}
+	response.Body.Close()
+	return nil
+}
+
+func (s *S3Store) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	request, err := http.NewRequest(http.MethodGet, s.objectURL(key).String(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := s.do(ctx, request, s3UnsignedPayload)
+	if err != nil {
+		return nil, err
+	}
+	return response.Body, nil
+}
+
+func (s *S3Store) GetRange(ctx context.Context, key string, offset, length int64) (io.ReadCloser, error) {
+	request, err := http.NewRequest(http.MethodGet, s.objectURL(key).String(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if length < 0 {
+		request.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+	} else if length > 0 {
+		request.Header.Set("Range", fmt.Sprintf("bytes=%d-%d", offset, offset+length-1))
+	} else {
+		return ioutil.NopCloser(bytes.NewReader(nil)), nil
+	}
+
+	response, err := s.do(ctx, request, s3UnsignedPayload)
+	if err != nil {
+		return nil, err
+	}
+	return response.Body, nil
+}
+
+func (s *S3Store) Delete(ctx context.Context, key string) error {
+	// S3 answers 204 whether or not the key existed
+	_, err := s.Stat(ctx, key)
+	if err != nil {
+		return err
+	}
+
+	request, err := http.NewRequest(http.MethodDelete, s.objectURL(key).String(), nil)
+	if err != nil {
+		return err
+	}
+
+	response, err := s.do(ctx, request, s3UnsignedPayload)
+	if err != nil {
+		return err
+	}
+	response.Body.Close()
+	return nil
+}
+
+func (s *S3Store) Stat(ctx context.Context, key string) (ObjectInfo, error) {
+	request, err := http.NewRequest(http.MethodHead, s.objectURL(key).String(), nil)
+	if err != nil {
+		return ObjectInfo{}, err
+	}
+
+	response, err := s.do(ctx, request, s3UnsignedPayload)
+	if err != nil {
+		return ObjectInfo{}, err
+	}
+	response.Body.Close()
+
+	updatedAt, _ := http.ParseTime(response.Header.Get("Last-Modified"))
+	return ObjectInfo{Key: key, Size: response.ContentLength, ContentType: response.Header.Get("Content-Type"), UpdatedAt: updatedAt}, nil
+}
+
+func (s *S3Store) SignedURL(key string, expires time.Duration) (string, error) {
+	now := s.now().UTC()
+	target := s.objectURL(key)
+
+	query := url.Values{}
+	query.Set("X-Amz-Algorithm", s3Algorithm)
+	query.Set("X-Amz-Credential", s.options.AccessKey+"/"+s.scope(now))
+	query.Set("X-Amz-Date", now.Format(s3DateFormat))
+	query.Set("X-Amz-Expires", strconv.Itoa(int(expires/time.Second)))
+	query.Set("X-Amz-SignedHeaders", "host")
+	target.RawQuery = canonicalQuery(query)
+
+	headers := http.Header{}
+	signature := s.signature(now, http.MethodGet, target, headers, []string{"host"}, s3UnsignedPayload)
+	target.RawQuery += "&X-Amz-Signature=" + signature
+	return target.String(), nil
+}
+
+type s3ListResult struct {
+	Contents []struct {
+		Key          string    `xml:"Key"`
+		Size         int64     `xml:"Size"`
+		LastModified time.Time `xml:"LastModified"`
+	} `xml:"Contents"`
+	IsTruncated           bool   `xml:"IsTruncated"`
+	NextContinuationToken string `xml:"NextContinuationToken"`
+}
+
+func (s *S3Store) List(ctx context.Context, prefix string) ([]ObjectInfo, error) {
+	objects := []ObjectInfo{}
+	continuationToken := ""
+
+	for {
+		target := s.objectURL("")
+		query := url.Values{"list-type": {"2"}, "prefix": {prefix}}
+		if continuationToken != "" {
+			query.Set("continuation-token", continuationToken)
+		}
+		target.RawQuery = canonicalQuery(query)
+
+		request, err := http.NewRequest(http.MethodGet, target.String(), nil)
+		if err != nil {
+			return nil, err
+		}
+
+		response, err := s.do(ctx, request, s3UnsignedPayload)
+		if err != nil {
+			return nil, err
+		}
+
+		var result s3ListResult
+		err = xml.NewDecoder(response.Body).Decode(&result)
+		response.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+
+		for _, content := range result.Contents {
+			objects = append(objects, ObjectInfo{Key: content.Key, Size: content.Size, UpdatedAt: content.LastModified})
+		}
+		if !result.IsTruncated || result.NextContinuationToken == "" {
+			return objects, nil
+		}
+		continuationToken = result.NextContinuationToken
+	}
+}
+
+// do signs and sends a request, an error status is turned into an error
+func (s *S3Store) do(ctx context.Context, request *http.Request, payloadHash string) (*http.Response, error) {
+	now := s.now().UTC()
+	request.Header.Set("Host", request.URL.Host)
+	request.Header.Set("X-Amz-Date", now.Format(s3DateFormat))
+	request.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	signedHeaders := []string{"host", "x-amz-content-sha256", "x-amz-date"}
+	if request.Header.Get("Content-Type") != "" {
+		signedHeaders = []string{"content-type", "host", "x-amz-content-sha256", "x-amz-date"}
+	}
+
+	signature := s.signature(now, request.Method, request.URL, request.Header, signedHeaders, payloadHash)
+	request.Header.Set("Authorization", fmt.Sprintf("%s Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s3Algorithm, s.options.AccessKey, s.scope(now), strings.Join(signedHeaders, ";"), signature))
+	request.Header.Del("Host")
+
+	response, err := s.client.Do(request.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	if response.StatusCode == http.StatusNotFound {
+		response.Body.Close()
+		return nil, ErrNotFound
+	}
+	if response.StatusCode >= 300 {
+		detail, _ := ioutil.ReadAll(io.LimitReader(response.Body, 1024))
+		response.Body.Close()
+		return nil, fmt.Errorf("s3: %s %s: %s: %s", request.Method, request.URL.Path, response.Status, bytes.TrimSpace(detail))
+	}
+
+	return response, nil
+}
+
+func (s *S3Store) scope(now time.Time) string {
+	return now.Format("20060102") + "/" + s.options.Region + "/s3/aws4_request"
+}
+
+func (s *S3Store) signature(now time.Time, method string, target *url.URL, headers http.Header, signedHeaders []string, payloadHash string) string {
+	var canonicalHeaders strings.Builder
+	for _, name := range signedHeaders {
+		value := headers.Get(name)
+		if name == "host" {
+			value = target.Host
+		}
+		canonicalHeaders.WriteString(name + ":" + strings.TrimSpace(value) + "\n")
+	}
+
+	canonicalRequest := strings.Join([]string{
+		method,
+		target.EscapedPath(),
+		target.RawQuery,
+		canonicalHeaders.String(),
+		strings.Join(signedHeaders, ";"),
+		payloadHash,
+	}, "\n")
+
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := strings.Join([]string{s3Algorithm, now.Format(s3DateFormat), s.scope(now), hex.EncodeToString(requestHash[:])}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+s.options.SecretKey), now.Format("20060102"))
+	key = hmacSHA256(key, s.options.Region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	return hex.EncodeToString(hmacSHA256(key, stringToSign))
+}
+
+// canonicalQuery encodes the query as SigV4 expects, sorted and with %20 for spaces
+func canonicalQuery(query url.Values) string {
+	keys := make([]string, 0, len(query))
+	for key := range query {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var pairs []string
+	for _, key := range keys {
+		for _, value := range query[key] {
+			pairs = append(pairs, uriEncode(key)+"="+uriEncode(value))
+		}
+	}
+	return strings.Join(pairs, "&")
+}
+
+func s3EscapePath(path string) string {
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		segments[i] = uriEncode(segment)
+	}
+	return strings.Join(segments, "/")
+}
+
+func uriEncode(value string) string {
+	return strings.Replace(url.QueryEscape(value), "+", "%20", -1)
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
@@ -0,0 +1,29 @@
+package blobstore
+
+import (
+	"context"
+	"errors"
+	"io"
+	"time"
+)
+
+var ErrNotFound = errors.New("object not found")
+
+type ObjectInfo struct {
+	Key         string    `json:"key"`
+	Size        int64     `json:"size"`
+	ContentType string    `json:"contentType"`
+	UpdatedAt   time.Time `json:"updatedAt"`
+}
+
+// BlobStore is implemented by every storage driver. Get and Stat return ErrNotFound for a missing key.
+// GetRange reads length bytes from offset, or up to the end when length is negative.
+type BlobStore interface {
+	Put(ctx context.Context, key string, content io.Reader, contentType string) (int64, error)
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	GetRange(ctx context.Context, key string, offset, length int64) (io.ReadCloser, error)
+	Delete(ctx context.Context, key string) error
+	Stat(ctx context.Context, key string) (ObjectInfo, error)
+	SignedURL(key string, expires time.Duration) (string, error)
+	List(ctx context.Context, prefix string) ([]ObjectInfo, error)
+}
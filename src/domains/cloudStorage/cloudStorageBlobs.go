@@ -0,0 +1,177 @@
+package cloudstorage
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"io"
+	"log"
+	"time"
+
+	"github.com/khoa5773/go-server/src/shared"
+	"gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+)
+
+const (
+	BLOB_KEY_PREFIX   = "blobs/"
+	stagingKeyPrefix  = "staging/"
+	acquireAttempts   = 50
+	acquireRetryDelay = 100 * time.Millisecond
+	stagedContentType = "application/octet-stream"
+)
+
+// BlobKey is where the content with the given SHA-256 is stored
+func BlobKey(sha256 string) string {
+	return BLOB_KEY_PREFIX + sha256
+}
+
+// StageFile streams content, up to maxSize bytes, to a temporary key. The staged file is then either
+// turned into a blob reference by AcquireBlob or removed by DiscardStagedFile.
+func StageFile(ctx context.Context, content io.Reader, maxSize int64) (StagedFile, error) {
+	nonce := make([]byte, 16)
+	_, err := rand.Read(nonce)
+	if err != nil {
+		return StagedFile{}, err
+	}
+
+	key := stagingKeyPrefix + hex.EncodeToString(nonce)
+	size, sha256, err := StreamToObject(ctx, key, content, stagedContentType, maxSize)
+	if err != nil {
+		return StagedFile{}, err
+	}
+
+	return StagedFile{Key: key, Size: size, SHA256: sha256}, nil
+}
+
+// OpenStagedFile reads a staged file back, to inspect it before it becomes a blob
+func OpenStagedFile(ctx context.Context, staged StagedFile) (io.ReadCloser, error) {
+	store, err := Store()
+	if err != nil {
+		return nil, err
+	}
+
+	return store.Get(ctx, staged.Key)
+}
+
+func DiscardStagedFile(staged StagedFile) {
+	removeObject(staged.Key)
+}
+
+// AcquireBlob adds a reference to the blob with the content of the staged file, storing the content
+// only when no other document has it, and returns the key of the blob. The staged file is removed.
+func AcquireBlob(ctx context.Context, staged StagedFile) (string, error) {
+	defer DiscardStagedFile(staged)
+
+	store, err := Store()
+	if err != nil {
+		return "", err
+	}
+
+	key := BlobKey(staged.SHA256)
+	BlobsModel := shared.MongoSession.C("blobs")
+	for attempt := 1; ; attempt++ {
+		now := time.Now()
+		// a blob being deleted is left out of the query, so the upsert collides with it on _id until
+		// the deletion is over and the content is stored again
+		_, err = BlobsModel.Upsert(bson.M{"_id": staged.SHA256, "deleting": bson.M{"$ne": true}}, bson.M{
+			"$inc":         bson.M{"refCount": 1},
+			"$set":         bson.M{"updatedAt": now},
+			"$setOnInsert": bson.M{"key": key, "size": staged.Size, "deleting": false, "createdAt": now},
+		})
+		if err == nil {
+			break
+		}
+		if !mgo.IsDup(err) || attempt == acquireAttempts {
+			return "", err
+		}
+		time.Sleep(acquireRetryDelay)
+	}
+
+	// the reference held above keeps the blob from being deleted, so a content found here stays
+	_, err = store.Stat(ctx, key)
+	if err == ErrNotFound {
+		err = copyObject(ctx, store, staged.Key, key)
+	}
+	if err != nil {
+		releaseErr := ReleaseBlob(context.Background(), staged.SHA256)
+		if releaseErr != nil {
+			log.Println("storage: releasing blob", staged.SHA256, "failed:", releaseErr)
+		}
+		return "", err
+	}
+
+	return key, nil
+}
+
+// AddBlobReference counts one more reference to a blob the caller already holds a reference to
+func AddBlobReference(sha256 string) error {
+	BlobsModel := shared.MongoSession.C("blobs")
+	return BlobsModel.UpdateId(sha256, bson.M{"$inc": bson.M{"refCount": 1}, "$set": bson.M{"updatedAt": time.Now()}})
+}
+
+// ReleaseBlob drops a reference to a blob and deletes its content with the last reference
+func ReleaseBlob(ctx context.Context, sha256 string) error {
+	var blob Blob
+	BlobsModel := shared.MongoSession.C("blobs")
+	_, err := BlobsModel.FindId(sha256).Apply(mgo.Change{
+		Update:    bson.M{"$inc": bson.M{"refCount": -1}, "$set": bson.M{"updatedAt": time.Now()}},
+		ReturnNew: true,
+	}, &blob)
+	if err == mgo.ErrNotFound {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if blob.RefCount > 0 {
+		return nil
+	}
+
+	err = BlobsModel.Update(
+		bson.M{"_id": sha256, "refCount": bson.M{"$lte": 0}, "deleting": bson.M{"$ne": true}},
+		bson.M{"$set": bson.M{"deleting": true}},
+	)
+	if err == mgo.ErrNotFound {
+		// referenced again in the meantime
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	store, err := Store()
+	if err == nil {
+		err = store.Delete(ctx, blob.Key)
+	}
+	if err != nil && err != ErrNotFound {
+		// the blob stays unreferenced, the next upload of the same content uses it again
+		_ = BlobsModel.UpdateId(sha256, bson.M{"$set": bson.M{"deleting": false}})
+		return err
+	}
+
+	return BlobsModel.RemoveId(sha256)
+}
+
+func copyObject(ctx context.Context, store BlobStore, from, to string) error {
+	content, err := store.Get(ctx, from)
+	if err != nil {
+		return err
+	}
+	defer content.Close()
+
+	_, err = store.Put(ctx, to, content, stagedContentType)
+	return err
+}
+
+func removeObject(key string) {
+	store, err := Store()
+	if err != nil {
+		return
+	}
+
+	err = store.Delete(context.Background(), key)
+	if err != nil && err != ErrNotFound {
+		log.Println("storage: removing", key, "failed:", err)
+	}
+}
@@ -0,0 +1,52 @@
+package cloudstorage
+
+import (
+	"net/http"
+	"path"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/khoa5773/go-server/src/domains/cloudStorage/blobstore"
+)
+
+// LOCAL_ROUTE serves the signed URLs of the local driver
+const LOCAL_ROUTE = "/storage"
+
+func ApplyRoutes(r *gin.Engine) {
+	r.GET(LOCAL_ROUTE+"/*key", downloadLocalObjectController)
+}
+
+func downloadLocalObjectController(c *gin.Context) {
+	localStore, ok := store.(*blobstore.LocalStore)
+	if !ok {
+		c.Status(http.StatusNotFound)
+		return
+	}
+
+	key := strings.TrimPrefix(c.Param("key"), "/")
+	err := localStore.Verify(key, c.Query("expires"), c.Query("signature"))
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"errors": []gin.H{{"error": err.Error()}}})
+		return
+	}
+
+	target, err := localStore.Path(key)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	info, err := localStore.Stat(c, key)
+	if err == ErrNotFound {
+		c.Status(http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.Header("Content-Type", info.ContentType)
+	c.Header("Content-Disposition", `attachment; filename="`+path.Base(key)+`"`)
+	c.File(target)
+}
@@ -0,0 +1,21 @@
+package cloudstorage
+
+import "time"
+
+// Blob is a stored content shared by every document with the same SHA-256
+type Blob struct {
+	ID        string    `json:"_id" bson:"_id"`
+	Key       string    `json:"key" bson:"key"`
+	Size      int64     `json:"size" bson:"size"`
+	RefCount  int       `json:"refCount" bson:"refCount"`
+	Deleting  bool      `json:"deleting" bson:"deleting"`
+	CreatedAt time.Time `json:"createdAt" bson:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt" bson:"updatedAt"`
+}
+
+// StagedFile is content written to a temporary key until its hash tells which blob it belongs to
+type StagedFile struct {
+	Key    string
+	Size   int64
+	SHA256 string
+}
@@ -2,105 +2,211 @@ package cloudstorage
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
-	"io/ioutil"
+	"io"
 	"log"
+	"mime"
 	"mime/multipart"
+	"strings"
 	"time"
 
-	cloudStorage "cloud.google.com/go/storage"
-	firebase "firebase.google.com/go"
 	"github.com/khoa5773/go-server/src/configs"
-	"golang.org/x/oauth2/google"
-	"google.golang.org/api/option"
 )
 
-var storageBucket *cloudStorage.BucketHandle
+const (
+	// OBJECT_URL_PREFIX marks a stored reference to an object of the blob store
+	OBJECT_URL_PREFIX = "blob://"
+	signedURLExpiry   = 3 * time.Hour
+)
 
-func init() {
-	ctx := context.Background()
-	config := &firebase.Config{
-		StorageBucket: fmt.Sprintf(configs.ConfigsService.GGCloudStorageBucket),
-	}
-	opt := option.WithCredentialsFile(configs.ConfigsService.GGServiceAccountPath)
-	app, err := firebase.NewApp(ctx, config, opt)
-	if err != nil {
-		log.Fatalln(err)
-	}
+func ObjectKey(dataID string, projectID string, mimeType string) string {
+	return fmt.Sprintf("%s-%s.%s", dataID, projectID, mimeType)
+}
 
-	client, err := app.Storage(ctx)
-	if err != nil {
-		log.Fatalln(err)
-	}
-
-	storageBucket, err = client.DefaultBucket()
-	if err != nil {
-		log.Fatalln(err)
+func contentType(mimeType string) string {
+	if contentType := mime.TypeByExtension("." + mimeType); contentType != "" {
+		return contentType
 	}
+	return "application/octet-stream"
 }
 
+// UploadFileToCloudStorage stores a small uploaded file, such as a picture, and returns a reference to
+// it, see SignObjectURL
 func UploadFileToCloudStorage(
 	ctx context.Context, file *multipart.FileHeader, dataID string, projectID string, mimeType string,
 ) (string, error) {
-	fileName := fmt.Sprintf("%s-%s.%s", dataID, projectID, mimeType)
-	object := storageBucket.Object(fileName)
-	w := object.NewWriter(ctx)
-
 	content, err := file.Open()
 	if err != nil {
 		return "", err
 	}
-
 	defer content.Close()
-	byteData, err := ioutil.ReadAll(content)
+
+	_, err = WriteFileToCloudStorage(ctx, content, dataID, projectID, mimeType)
 	if err != nil {
 		return "", err
 	}
 
-	_, err = w.Write(byteData)
+	return ObjectURL(dataID, projectID, mimeType), nil
+}
+
+func ObjectURL(dataID string, projectID string, mimeType string) string {
+	return OBJECT_URL_PREFIX + ObjectKey(dataID, projectID, mimeType)
+}
 
+func WriteFileToCloudStorage(
+	ctx context.Context, content io.Reader, dataID string, projectID string, mimeType string,
+) (int64, error) {
+	store, err := Store()
 	if err != nil {
-		return "", err
+		return 0, err
 	}
 
-	if err := w.Close(); err != nil {
-		return "", err
+	return store.Put(ctx, ObjectKey(dataID, projectID, mimeType), content, contentType(mimeType))
+}
+
+// TooLargeError is returned when streamed content goes over its size limit
+type TooLargeError struct {
+	Limit int64
+}
+
+func (e *TooLargeError) Error() string {
+	return fmt.Sprintf("file exceeds the maximum upload size of %d bytes", e.Limit)
+}
+
+type limitedReader struct {
+	reader    io.Reader
+	limit     int64
+	remaining int64
+}
+
+func (l *limitedReader) Read(p []byte) (int, error) {
+	if l.remaining <= 0 {
+		// one more byte tells apart content of exactly the limit from larger content
+		n, _ := l.reader.Read(make([]byte, 1))
+		if n > 0 {
+			return 0, &TooLargeError{Limit: l.limit}
+		}
+		return 0, io.EOF
 	}
 
-	return fmt.Sprintf("http://storage.googleapis.com/%s/%s", configs.ConfigsService.GGCloudStorageBucket, fileName), nil
+	if int64(len(p)) > l.remaining {
+		p = p[:l.remaining]
+	}
+	n, err := l.reader.Read(p)
+	l.remaining -= int64(n)
+	return n, err
 }
 
-func GenerateSignedUrl(dataID string, projectID string, mimeType string) (string, error) {
-	fileName := fmt.Sprintf("%s-%s.%s", dataID, projectID, mimeType)
+// StreamFileToCloudStorage writes content as it arrives, up to maxSize bytes, and returns its size and
+// SHA-256. Whatever was stored is removed when the content is too large or the client goes away.
+func StreamFileToCloudStorage(
+	ctx context.Context, content io.Reader, dataID string, projectID string, mimeType string, maxSize int64,
+) (int64, string, error) {
+	return StreamToObject(ctx, ObjectKey(dataID, projectID, mimeType), content, contentType(mimeType), maxSize)
+}
 
-	saKey, err := ioutil.ReadFile(configs.ConfigsService.GGServiceAccountPath)
+// StreamToObject is StreamFileToCloudStorage for an arbitrary key
+func StreamToObject(ctx context.Context, key string, content io.Reader, contentType string, maxSize int64) (int64, string, error) {
+	store, err := Store()
 	if err != nil {
-		log.Fatalln(err)
+		return 0, "", err
 	}
 
-	cfg, err := google.JWTConfigFromJSON(saKey)
+	hash := sha256.New()
+	reader := io.TeeReader(&limitedReader{reader: content, limit: maxSize, remaining: maxSize}, hash)
+
+	size, err := store.Put(ctx, key, reader, contentType)
 	if err != nil {
-		log.Fatalln(err)
+		removeErr := store.Delete(context.Background(), key)
+		if removeErr != nil && removeErr != ErrNotFound {
+			log.Println("storage: removing partial upload failed:", removeErr)
+		}
+
+		var tooLargeErr *TooLargeError
+		if errors.As(err, &tooLargeErr) {
+			return 0, "", err
+		}
+		return 0, "", fmt.Errorf("upload was interrupted: %v", err)
 	}
-	url, err := cloudStorage.SignedURL(configs.ConfigsService.GGCloudStorageBucket, fileName, &cloudStorage.SignedURLOptions{
-		GoogleAccessID: cfg.Email,
-		PrivateKey:     cfg.PrivateKey,
-		Method:         "GET",
-		Expires:        time.Now().Add(3 * time.Hour),
-	})
 
+	return size, hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+func ReadFileFromCloudStorage(
+	ctx context.Context, dataID string, projectID string, mimeType string,
+) (io.ReadCloser, error) {
+	store, err := Store()
+	if err != nil {
+		return nil, err
+	}
+
+	return store.Get(ctx, ObjectKey(dataID, projectID, mimeType))
+}
+
+// ReadRangeFromCloudStorage reads length bytes of an object from offset, or up to the end when length
+// is negative
+func ReadRangeFromCloudStorage(ctx context.Context, key string, offset int64, length int64) (io.ReadCloser, error) {
+	store, err := Store()
+	if err != nil {
+		return nil, err
+	}
+
+	return store.GetRange(ctx, key, offset, length)
+}
+
+// StatFileInCloudStorage returns the size and modification time of an object
+func StatFileInCloudStorage(ctx context.Context, key string) (ObjectInfo, error) {
+	store, err := Store()
+	if err != nil {
+		return ObjectInfo{}, err
+	}
+
+	return store.Stat(ctx, key)
+}
+
+func GenerateSignedUrl(dataID string, projectID string, mimeType string) (string, error) {
+	return SignObjectKey(ObjectKey(dataID, projectID, mimeType))
+}
+
+func SignObjectKey(key string) (string, error) {
+	store, err := Store()
 	if err != nil {
 		return "", err
 	}
 
-	return url, nil
+	return store.SignedURL(key, signedURLExpiry)
+}
+
+// SignObjectURL turns a reference returned by UploadFileToCloudStorage, or a public GCS URL saved
+// before references existed, into a signed URL. Other URLs are returned unchanged.
+func SignObjectURL(objectURL string) (string, error) {
+	key := ""
+	legacyPrefix := fmt.Sprintf("http://storage.googleapis.com/%s/", configs.ConfigsService.GGCloudStorageBucket)
+	switch {
+	case strings.HasPrefix(objectURL, OBJECT_URL_PREFIX):
+		key = strings.TrimPrefix(objectURL, OBJECT_URL_PREFIX)
+	case strings.HasPrefix(objectURL, legacyPrefix):
+		key = strings.TrimPrefix(objectURL, legacyPrefix)
+	default:
+		return objectURL, nil
+	}
+
+	return SignObjectKey(key)
 }
 
 func RemoveFileFromCloudStorage(
 	ctx context.Context, dataID string, projectID string, mimeType string,
 ) (bool, error) {
-	object := storageBucket.Object(fmt.Sprintf("%s-%s.%s", dataID, projectID, mimeType))
-	if err := object.Delete(ctx); err != nil {
+	store, err := Store()
+	if err != nil {
+		return false, err
+	}
+
+	err = store.Delete(ctx, ObjectKey(dataID, projectID, mimeType))
+	if err != nil {
 		return false, err
 	}
 
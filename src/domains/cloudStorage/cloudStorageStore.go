@@ -0,0 +1,54 @@
+package cloudstorage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/khoa5773/go-server/src/configs"
+	"github.com/khoa5773/go-server/src/domains/cloudStorage/blobstore"
+)
+
+// The drivers live in blobstore, which depends on neither the configuration nor the database
+type (
+	BlobStore  = blobstore.BlobStore
+	ObjectInfo = blobstore.ObjectInfo
+)
+
+var ErrNotFound = blobstore.ErrNotFound
+
+var store BlobStore
+
+// Setup opens the driver named by STORAGE_DRIVER
+func Setup() error {
+	config := configs.ConfigsService
+	var err error
+
+	switch config.StorageDriver {
+	case "gcs":
+		store, err = blobstore.NewGCSStore(context.Background(), config.GGCloudStorageBucket, config.GGServiceAccountPath)
+	case "s3":
+		store, err = blobstore.NewS3Store(blobstore.S3Options{
+			Endpoint:  config.S3Endpoint,
+			Region:    config.S3Region,
+			Bucket:    config.S3Bucket,
+			AccessKey: config.S3AccessKey,
+			SecretKey: config.S3SecretKey,
+			PathStyle: config.S3PathStyle,
+		})
+	case "local":
+		store, err = blobstore.NewLocalStore(config.StorageDir, config.StorageSigningKey, fmt.Sprintf("http://%s:%d%s", config.Host, config.Port, LOCAL_ROUTE))
+	default:
+		err = fmt.Errorf("storage: unknown driver %q", config.StorageDriver)
+	}
+
+	return err
+}
+
+// Store returns the configured driver
+func Store() (BlobStore, error) {
+	if store == nil {
+		return nil, errors.New("storage is not configured")
+	}
+	return store, nil
+}
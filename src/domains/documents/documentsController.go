@@ -1,10 +1,20 @@
 package documents
 
 import (
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
 	"net/http"
+	"net/url"
+	"strings"
 
 	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
 	cloudstorage "github.com/khoa5773/go-server/src/domains/cloudStorage"
+	"github.com/khoa5773/go-server/src/domains/projects"
+	"github.com/khoa5773/go-server/src/genomics"
 	"github.com/khoa5773/go-server/src/helpers"
 	"github.com/khoa5773/go-server/src/middleware/auth"
 	"github.com/khoa5773/go-server/src/middleware/authz"
@@ -12,11 +22,20 @@ import (
 	"gopkg.in/mgo.v2/bson"
 )
 
+// maxFormFieldSize bounds the text fields sent along with an upload
+const maxFormFieldSize = 64 << 10
+
 func ApplyRoutes(r *gin.Engine) {
 	DocumentsControllers := r.Group("projects/:projectID/repositories/:repositoryID/documents")
 	DocumentsControllers.GET("", auth.JWTRequired, authz.Scopes([]string{"documents:read", "proj:documents:read"}), findManyDocumentsController)
 	DocumentsControllers.POST("", auth.JWTRequired, authz.Scopes([]string{"documents:create", "proj:documents:create"}), createDocumentController)
 	DocumentsControllers.GET("/:documentID", auth.JWTRequired, authz.Scopes([]string{"documents:read", "proj:documents:read"}), findOneDocumentController)
+	DocumentsControllers.GET("/:documentID/content", auth.JWTRequired, authz.Scopes([]string{"documents:read", "proj:documents:read"}), downloadDocumentController)
+	DocumentsControllers.GET("/:documentID/versions", auth.JWTRequired, authz.Scopes([]string{"documents:read", "proj:documents:read"}), findDocumentVersionsController)
+	DocumentsControllers.POST("/:documentID/versions", auth.JWTRequired, authz.Scopes([]string{"documents:update", "proj:documents:update"}), createDocumentVersionController)
+	DocumentsControllers.GET("/:documentID/versions/:version/content", auth.JWTRequired, authz.Scopes([]string{"documents:read", "proj:documents:read"}), downloadDocumentVersionController)
+	DocumentsControllers.POST("/:documentID/versions/:version/restore", auth.JWTRequired, authz.Scopes([]string{"documents:update", "proj:documents:update"}), restoreDocumentVersionController)
+	DocumentsControllers.POST("/:documentID/qc", auth.JWTRequired, authz.Scopes([]string{"documents:create", "proj:documents:create"}), createQualityControlController)
 	DocumentsControllers.PUT("/:documentID", auth.JWTRequired, authz.Scopes([]string{"documents:update", "proj:documents:update"}), updateDocumentController)
 	DocumentsControllers.DELETE("/:documentID", auth.JWTRequired, authz.Scopes([]string{"documents:delete", "proj:documents:delete"}), deleteDocumentController)
 }
@@ -34,6 +53,7 @@ func findManyDocumentsController(c *gin.Context) {
 		HasPersonalScopes: c.MustGet("hasPersonalScopes").(bool),
 		HasProjectScopes:  c.MustGet("hasProjectScopes").(bool),
 		ProjectIDs:        c.MustGet("userProjectIDs").([]bson.ObjectId),
+		RequestID:         c.GetString("requestID"),
 	}
 
 	documents, err := FindManyDocumentsInRepository(findManyDocumentsDto, credentials)
@@ -58,6 +78,7 @@ func findOneDocumentController(c *gin.Context) {
 		HasPersonalScopes: c.MustGet("hasPersonalScopes").(bool),
 		HasProjectScopes:  c.MustGet("hasProjectScopes").(bool),
 		ProjectIDs:        c.MustGet("userProjectIDs").([]bson.ObjectId),
+		RequestID:         c.GetString("requestID"),
 	}
 
 	document, err := FindOneDocument(&findOneDocumentDto, credentials)
@@ -69,54 +90,276 @@ func findOneDocumentController(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"document": document})
 }
 
+// downloadDocumentController streams the document through the server, so that access is checked on
+// every request and downloads can be audited, unlike the signed URL of the document
+func downloadDocumentController(c *gin.Context) {
+	var findOneDocumentDto FindOneDocumentDto
+	err := c.ShouldBindUri(&findOneDocumentDto)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	credentials := shared.Credentials{
+		Id:                c.MustGet("userID").(string),
+		HasPersonalScopes: c.MustGet("hasPersonalScopes").(bool),
+		HasProjectScopes:  c.MustGet("hasProjectScopes").(bool),
+		ProjectIDs:        c.MustGet("userProjectIDs").([]bson.ObjectId),
+		RequestID:         c.GetString("requestID"),
+	}
+
+	document, err := FindOneDocument(&findOneDocumentDto, credentials)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	serveDocumentContent(c, document, credentials)
+}
+
+func downloadDocumentVersionController(c *gin.Context) {
+	var findOneDocumentVersionDto FindOneDocumentVersionDto
+	err := c.ShouldBindUri(&findOneDocumentVersionDto)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	credentials := shared.Credentials{
+		Id:                c.MustGet("userID").(string),
+		HasPersonalScopes: c.MustGet("hasPersonalScopes").(bool),
+		HasProjectScopes:  c.MustGet("hasProjectScopes").(bool),
+		ProjectIDs:        c.MustGet("userProjectIDs").([]bson.ObjectId),
+		RequestID:         c.GetString("requestID"),
+	}
+
+	document, err := FindOneDocumentVersion(&findOneDocumentVersionDto, credentials)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	serveDocumentContent(c, document, credentials)
+}
+
+// serveDocumentContent answers with the content of the document, or the part of it asked by a Range header
+func serveDocumentContent(c *gin.Context, document Document, credentials shared.Credentials) {
+	etag, err := DocumentETag(c, &document)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.Header("ETag", etag)
+	c.Header("Accept-Ranges", "bytes")
+	c.Header("Cache-Control", "private, no-cache")
+	if etagMatches(c.GetHeader("If-None-Match"), etag) {
+		c.Status(http.StatusNotModified)
+		return
+	}
+
+	offset, length, partial := int64(0), document.Size, false
+	rangeHeader := c.GetHeader("Range")
+	// a stale If-Range means the client holds another version, which gets the whole file
+	if rangeHeader != "" && (c.GetHeader("If-Range") == "" || c.GetHeader("If-Range") == etag) {
+		offset, length, partial, err = helpers.ParseRange(rangeHeader, document.Size)
+		if err != nil {
+			c.Header("Content-Range", fmt.Sprintf("bytes */%d", document.Size))
+			c.AbortWithStatusJSON(http.StatusRequestedRangeNotSatisfiable, gin.H{"errors": []gin.H{{"error": err.Error()}}})
+			return
+		}
+	}
+
+	content, err := OpenDocumentRange(c, document, offset, length)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	defer content.Close()
+
+	status := http.StatusOK
+	headers := map[string]string{
+		"Content-Disposition": fmt.Sprintf(`attachment; filename="%s.%s"`, strings.NewReplacer(`"`, "", "\\", "", "\r", "", "\n", "").Replace(document.Name), document.MimeType),
+	}
+	if partial {
+		status = http.StatusPartialContent
+		headers["Content-Range"] = fmt.Sprintf("bytes %d-%d/%d", offset, offset+length-1, document.Size)
+	}
+
+	RecordDownload(document, offset, credentials)
+	c.DataFromReader(status, length, mime.TypeByExtension("."+document.MimeType), content, headers)
+}
+
+func etagMatches(ifNoneMatch, etag string) bool {
+	if ifNoneMatch == "" {
+		return false
+	}
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == strings.TrimPrefix(etag, "W/") {
+			return true
+		}
+	}
+	return false
+}
+
 func createDocumentController(c *gin.Context) {
 	var createOneDocumentDto CreateOneDocumentDto
-	err := c.ShouldBind(&createOneDocumentDto)
+	createOneDocumentDto.ProjectID = bson.ObjectIdHex(c.Param("projectID"))
+	createOneDocumentDto.ID = bson.NewObjectId()
+
+	staged, mimeType, err := receiveDocumentUpload(c, createOneDocumentDto.ProjectID, &createOneDocumentDto)
+	createOneDocumentDto.MimeType = mimeType
 	if err != nil {
+		var tooLargeErr *cloudstorage.TooLargeError
+		if errors.As(err, &tooLargeErr) {
+			c.AbortWithStatusJSON(http.StatusRequestEntityTooLarge, gin.H{"errors": []gin.H{{"error": err.Error()}}})
+			return
+		}
 		_ = c.Error(err)
 		return
 	}
 
-	file, err := c.FormFile("file")
+	credentials := shared.Credentials{
+		Id:                c.MustGet("userID").(string),
+		HasPersonalScopes: c.MustGet("hasPersonalScopes").(bool),
+		HasProjectScopes:  c.MustGet("hasProjectScopes").(bool),
+		ProjectIDs:        c.MustGet("userProjectIDs").([]bson.ObjectId),
+		RequestID:         c.GetString("requestID"),
+	}
+	createOneDocumentDto.RepositoryID = bson.ObjectIdHex(c.Param("repositoryID"))
+
+	isSuccess, err := CreateOneDocumentFromStagedFile(c, &createOneDocumentDto, staged, credentials)
 	if err != nil {
 		_ = c.Error(err)
 		return
 	}
 
-	mimeType, err := helpers.GetMimeTypeFromFilename(file.Filename)
+	// duplicates are only a notice, the document has been created either way
+	duplicates, _ := FindDuplicateDocuments(createOneDocumentDto.ID, credentials)
+
+	c.JSON(http.StatusCreated, gin.H{"success": isSuccess, "duplicates": duplicates})
+}
+
+func findDocumentVersionsController(c *gin.Context) {
+	var findOneDocumentDto FindOneDocumentDto
+	err := c.ShouldBindUri(&findOneDocumentDto)
 	if err != nil {
 		_ = c.Error(err)
 		return
 	}
 
-	projectID := bson.ObjectIdHex(c.Param("projectID"))
-	documentID := bson.NewObjectId()
+	credentials := shared.Credentials{
+		Id:                c.MustGet("userID").(string),
+		HasPersonalScopes: c.MustGet("hasPersonalScopes").(bool),
+		HasProjectScopes:  c.MustGet("hasProjectScopes").(bool),
+		ProjectIDs:        c.MustGet("userProjectIDs").([]bson.ObjectId),
+		RequestID:         c.GetString("requestID"),
+	}
 
-	url, err := cloudstorage.UploadFileToCloudStorage(c, file, documentID.Hex(), projectID.Hex(), mimeType)
+	versions, err := FindDocumentVersions(&findOneDocumentDto, credentials)
 	if err != nil {
 		_ = c.Error(err)
 		return
 	}
 
+	c.JSON(http.StatusOK, gin.H{"versions": versions})
+}
+
+func createDocumentVersionController(c *gin.Context) {
+	var findOneDocumentDto FindOneDocumentDto
+	err := c.ShouldBindUri(&findOneDocumentDto)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	var createDocumentVersionDto CreateDocumentVersionDto
+	staged, mimeType, err := receiveDocumentUpload(c, findOneDocumentDto.ProjectID, &createDocumentVersionDto)
+	if err != nil {
+		var tooLargeErr *cloudstorage.TooLargeError
+		if errors.As(err, &tooLargeErr) {
+			c.AbortWithStatusJSON(http.StatusRequestEntityTooLarge, gin.H{"errors": []gin.H{{"error": err.Error()}}})
+			return
+		}
+		_ = c.Error(err)
+		return
+	}
+
 	credentials := shared.Credentials{
 		Id:                c.MustGet("userID").(string),
 		HasPersonalScopes: c.MustGet("hasPersonalScopes").(bool),
 		HasProjectScopes:  c.MustGet("hasProjectScopes").(bool),
 		ProjectIDs:        c.MustGet("userProjectIDs").([]bson.ObjectId),
+		RequestID:         c.GetString("requestID"),
+	}
+
+	version, err := CreateDocumentVersion(c, &findOneDocumentDto, staged, mimeType, createDocumentVersionDto.Coding, credentials)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	duplicates, _ := FindDuplicateDocuments(findOneDocumentDto.ID, credentials)
+
+	c.JSON(http.StatusCreated, gin.H{"success": true, "version": version, "duplicates": duplicates})
+}
+
+func createQualityControlController(c *gin.Context) {
+	var findOneDocumentDto FindOneDocumentDto
+	err := c.ShouldBindUri(&findOneDocumentDto)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	var createQualityControlDto CreateQualityControlDto
+	err = c.ShouldBindJSON(&createQualityControlDto)
+	if err != nil {
+		_ = c.Error(err)
+		return
 	}
-	createOneDocumentDto.ProjectID = projectID
-	createOneDocumentDto.RepositoryID = bson.ObjectIdHex(c.Param("repositoryID"))
-	createOneDocumentDto.Path = url
-	createOneDocumentDto.MimeType = mimeType
-	createOneDocumentDto.ID = documentID
 
-	isSuccess, err := CreateOneDocument(&createOneDocumentDto, credentials)
+	credentials := shared.Credentials{
+		Id:                c.MustGet("userID").(string),
+		HasPersonalScopes: c.MustGet("hasPersonalScopes").(bool),
+		HasProjectScopes:  c.MustGet("hasProjectScopes").(bool),
+		ProjectIDs:        c.MustGet("userProjectIDs").([]bson.ObjectId),
+		RequestID:         c.GetString("requestID"),
+	}
+
+	jobID, documentID, err := CreateQualityControl(&findOneDocumentDto, &createQualityControlDto, credentials)
 	if err != nil {
 		_ = c.Error(err)
 		return
 	}
 
-	c.JSON(http.StatusCreated, gin.H{"success": isSuccess})
+	c.JSON(http.StatusAccepted, gin.H{"success": true, "jobID": jobID, "documentID": documentID})
+}
+
+func restoreDocumentVersionController(c *gin.Context) {
+	var findOneDocumentVersionDto FindOneDocumentVersionDto
+	err := c.ShouldBindUri(&findOneDocumentVersionDto)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	credentials := shared.Credentials{
+		Id:                c.MustGet("userID").(string),
+		HasPersonalScopes: c.MustGet("hasPersonalScopes").(bool),
+		HasProjectScopes:  c.MustGet("hasProjectScopes").(bool),
+		ProjectIDs:        c.MustGet("userProjectIDs").([]bson.ObjectId),
+		RequestID:         c.GetString("requestID"),
+	}
+
+	version, err := RestoreDocumentVersion(c, &findOneDocumentVersionDto, credentials)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"success": true, "version": version})
 }
 
 func updateDocumentController(c *gin.Context) {
@@ -140,6 +383,7 @@ func updateDocumentController(c *gin.Context) {
 		HasPersonalScopes: c.MustGet("hasPersonalScopes").(bool),
 		HasProjectScopes:  c.MustGet("hasProjectScopes").(bool),
 		ProjectIDs:        c.MustGet("userProjectIDs").([]bson.ObjectId),
+		RequestID:         c.GetString("requestID"),
 	}
 
 	isSuccess, err := UpdateDocument(&findOneDocumentDto, &updateDocumentDto, credentials)
@@ -165,6 +409,7 @@ func deleteDocumentController(c *gin.Context) {
 		HasPersonalScopes: c.MustGet("hasPersonalScopes").(bool),
 		HasProjectScopes:  c.MustGet("hasProjectScopes").(bool),
 		ProjectIDs:        c.MustGet("userProjectIDs").([]bson.ObjectId),
+		RequestID:         c.GetString("requestID"),
 	}
 
 	isSuccess, err := DeleteDocument(&deleteDocumentDto, credentials)
@@ -175,3 +420,115 @@ func deleteDocumentController(c *gin.Context) {
 
 	c.JSON(http.StatusOK, gin.H{"success": isSuccess})
 }
+
+// receiveDocumentUpload reads the multipart body part by part, so that files are staged in storage as
+// they arrive instead of being buffered, then binds the other form fields to form unless it is nil. The
+// body holds one file, whose mime type is read from its name, or the three files of a PLINK fileset,
+// which are bundled into one archive.
+func receiveDocumentUpload(c *gin.Context, projectID bson.ObjectId, form interface{}) (cloudstorage.StagedFile, string, error) {
+	reader, err := c.Request.MultipartReader()
+	if err != nil {
+		return cloudstorage.StagedFile{}, "", err
+	}
+
+	maxUploadSize, err := projects.MaxUploadSize(projectID)
+	if err != nil {
+		return cloudstorage.StagedFile{}, "", err
+	}
+
+	fields := url.Values{}
+	files := map[string]cloudstorage.StagedFile{}
+	uploadedSize := int64(0)
+	removeUploads := func() {
+		for _, staged := range files {
+			cloudstorage.DiscardStagedFile(staged)
+		}
+	}
+
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			removeUploads()
+			return cloudstorage.StagedFile{}, "", fmt.Errorf("upload was interrupted: %v", err)
+		}
+
+		if part.FormName() != "file" {
+			value, err := ioutil.ReadAll(io.LimitReader(part, maxFormFieldSize))
+			if err != nil {
+				removeUploads()
+				return cloudstorage.StagedFile{}, "", fmt.Errorf("upload was interrupted: %v", err)
+			}
+			fields.Add(part.FormName(), string(value))
+			continue
+		}
+
+		mimeType, err := helpers.GetMimeTypeFromFilename(part.FileName())
+		if err == nil && files[mimeType] != (cloudstorage.StagedFile{}) {
+			err = fmt.Errorf("only one .%s file can be uploaded", mimeType)
+		}
+		if err != nil {
+			removeUploads()
+			return cloudstorage.StagedFile{}, "", err
+		}
+
+		// the files of a fileset share the size limit
+		staged, err := cloudstorage.StageFile(c, part, maxUploadSize-uploadedSize)
+		if err != nil {
+			removeUploads()
+			var tooLargeErr *cloudstorage.TooLargeError
+			if errors.As(err, &tooLargeErr) {
+				err = &cloudstorage.TooLargeError{Limit: maxUploadSize}
+			}
+			return cloudstorage.StagedFile{}, "", err
+		}
+		files[mimeType] = staged
+		uploadedSize += staged.Size
+	}
+
+	var staged cloudstorage.StagedFile
+	var mimeType string
+	switch {
+	case len(files) == 0:
+		return staged, mimeType, errors.New("file is required")
+	case len(files) == 1:
+		for mimeType, staged = range files {
+		}
+	case isPLINKFileset(files):
+		mimeType = genomics.PLINK_MIME_TYPE
+		staged, err = BundlePLINKFileset(c, files)
+		if err != nil {
+			return staged, mimeType, err
+		}
+	default:
+		removeUploads()
+		return staged, mimeType, errors.New("upload one file, or the .bed, .bim and .fam files of a PLINK fileset")
+	}
+
+	if form == nil {
+		return staged, mimeType, nil
+	}
+
+	// the body has been consumed, so the fields are bound through a request carrying them as a query
+	err = binding.Query.Bind(&http.Request{URL: &url.URL{RawQuery: fields.Encode()}}, form)
+	if err != nil {
+		cloudstorage.DiscardStagedFile(staged)
+		return staged, mimeType, err
+	}
+
+	return staged, mimeType, nil
+}
+
+func isPLINKFileset(files map[string]cloudstorage.StagedFile) bool {
+	if len(files) != len(genomics.PLINKParts) {
+		return false
+	}
+	for _, part := range genomics.PLINKParts {
+		if _, ok := files[part]; !ok {
+			return false
+		}
+	}
+	return true
+}
@@ -3,6 +3,7 @@ package documents
 import (
 	"time"
 
+	"github.com/khoa5773/go-server/src/genomics"
 	"gopkg.in/mgo.v2/bson"
 )
 
@@ -25,18 +26,44 @@ type FindOneDocumentDto struct {
 }
 
 type CreateOneDocumentDto struct {
-	ID           bson.ObjectId `bson:"_id"`
-	Name         string        `json:"name" bson:"name" form:"name" binding:"required"`
-	Description  string        `json:"description" bson:"description" form:"description"`
-	Type         DocumentType  `json:"type" bson:"type" form:"type" binding:"required,oneof=GENOTYPE PHENOTYPE"`
-	MimeType     string        `bson:"mimeType"`
-	RepositoryID bson.ObjectId `bson:"repositoryID"`
-	ProjectID    bson.ObjectId `bson:"projectID"`
-	Path         string        `bson:"path"`
-	CreatedBy    string        `bson:"createdBy"`
-	UpdatedBy    string        `bson:"updatedBy"`
-	CreatedAt    time.Time     `bson:"createdAt"`
-	UpdatedAt    time.Time     `bson:"updatedAt"`
+	ID             bson.ObjectId             `bson:"_id"`
+	Name           string                    `json:"name" bson:"name" form:"name" binding:"required"`
+	Description    string                    `json:"description" bson:"description" form:"description"`
+	Type           DocumentType              `json:"type" bson:"type" form:"type" binding:"required,oneof=GENOTYPE PHENOTYPE"`
+	Coding         genomics.GenotypeCoding   `json:"coding" bson:"-" form:"coding" binding:"omitempty,oneof=DOSAGE_012 DOSAGE_101 NUCLEOTIDE IUPAC"`
+	MimeType       string                    `bson:"mimeType"`
+	RepositoryID   bson.ObjectId             `bson:"repositoryID"`
+	ProjectID      bson.ObjectId             `bson:"projectID"`
+	Path           string                    `bson:"path"`
+	Size           int64                     `bson:"size"`
+	SHA256         string                    `bson:"sha256"`
+	BlobKey        string                    `bson:"blobKey,omitempty"`
+	Version        int                       `bson:"version"`
+	SampleIDs      []string                  `bson:"sampleIDs,omitempty"`
+	VariantSummary *genomics.VariantSummary  `bson:"variantSummary,omitempty"`
+	TraitSchema    *genomics.PhenotypeSchema `bson:"traitSchema,omitempty"`
+	Lineage        *Lineage                  `bson:"lineage,omitempty"`
+	CreatedBy      string                    `bson:"createdBy"`
+	UpdatedBy      string                    `bson:"updatedBy"`
+	CreatedAt      time.Time                 `bson:"createdAt"`
+	UpdatedAt      time.Time                 `bson:"updatedAt"`
+}
+
+type CreateDocumentVersionDto struct {
+	Coding genomics.GenotypeCoding `json:"coding" form:"coding" binding:"omitempty,oneof=DOSAGE_012 DOSAGE_101 NUCLEOTIDE IUPAC"`
+}
+
+type CreateQualityControlDto struct {
+	Name       string                `json:"name"`
+	Version    int                   `json:"version" binding:"omitempty,min=1"`
+	Parameters genomics.QCParameters `json:"parameters"`
+}
+
+type FindOneDocumentVersionDto struct {
+	ID           bson.ObjectId `uri:"documentID" binding:"required,mongoid"`
+	ProjectID    bson.ObjectId `uri:"projectID" binding:"required,mongoid"`
+	RepositoryID bson.ObjectId `uri:"repositoryID" binding:"required,mongoid"`
+	Version      int           `uri:"version" binding:"required,min=1"`
 }
 
 type UpdateDocumentDto struct {
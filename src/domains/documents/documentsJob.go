@@ -0,0 +1,53 @@
+package documents
+
+import (
+	"context"
+	"errors"
+
+	"github.com/khoa5773/go-server/src/domains/jobs"
+	"github.com/khoa5773/go-server/src/genomics"
+	"github.com/khoa5773/go-server/src/shared"
+	"gopkg.in/mgo.v2/bson"
+)
+
+const QC_JOB_TYPE = "GENOTYPE_QC"
+
+type qualityControlJobHandler struct{}
+
+func init() {
+	jobs.RegisterHandler(QC_JOB_TYPE, qualityControlJobHandler{})
+}
+
+func (qualityControlJobHandler) Run(ctx context.Context, job *jobs.Job, progress func(percentage int)) (map[string]interface{}, error) {
+	documentID, _ := job.Payload["documentID"].(bson.ObjectId)
+	repositoryID, _ := job.Payload["repositoryID"].(bson.ObjectId)
+	derivedID, _ := job.Payload["derivedID"].(bson.ObjectId)
+	version, _ := job.Payload["version"].(int)
+	name, _ := job.Payload["name"].(string)
+	if documentID == "" || repositoryID == "" || derivedID == "" || version == 0 {
+		return nil, jobs.Permanent(errors.New("job payload has no documentID, repositoryID, derivedID or version"))
+	}
+
+	// the parameters come back from the database as a map
+	var parameters genomics.QCParameters
+	raw, err := bson.Marshal(job.Payload["parameters"])
+	if err == nil {
+		err = bson.Unmarshal(raw, &parameters)
+	}
+	if err != nil {
+		return nil, jobs.Permanent(err)
+	}
+
+	credentials := shared.Credentials{Id: job.CreatedBy, IsAdmin: true}
+	source := &FindOneDocumentDto{ID: documentID, ProjectID: job.ProjectID, RepositoryID: repositoryID}
+	err = RunQualityControl(ctx, job.ID, source, version, derivedID, name, parameters, credentials, progress)
+	if err != nil {
+		return nil, err
+	}
+
+	return map[string]interface{}{"documentID": derivedID}, nil
+}
+
+func (qualityControlJobHandler) Finish(job *jobs.Job) error {
+	return nil
+}
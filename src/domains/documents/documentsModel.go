@@ -3,21 +3,78 @@ package documents
 import (
 	"time"
 
+	"github.com/khoa5773/go-server/src/genomics"
 	"gopkg.in/mgo.v2/bson"
 )
 
 // Document model
 type Document struct {
-	ID           bson.ObjectId `json:"_id" bson:"_id"`
-	Name         string        `json:"name" bson:"name"`
-	Description  string        `json:"description" bson:"description"`
-	MimeType     string        `json:"mimeType" bson:"mimeType"`
-	ProjectID    bson.ObjectId `json:"projectID" bson:"projectID"`
-	RepositoryID bson.ObjectId `json:"repositoryID" bson:"repositoryID"`
-	Type         string        `json:"type" bson:"type"`
-	Path         string        `json:"path" bson:"path"`
-	CreatedBy    string        `json:"createdBy" bson:"createdBy"`
-	CreatedAt    time.Time     `json:"createdAt" bson:"createdAt"`
-	UpdatedAt    time.Time     `json:"updatedAt" bson:"updatedAt"`
-	UpdatedBy    string        `json:"updatedBy" bson:"updatedBy"`
+	ID             bson.ObjectId             `json:"_id" bson:"_id"`
+	Name           string                    `json:"name" bson:"name"`
+	Description    string                    `json:"description" bson:"description"`
+	MimeType       string                    `json:"mimeType" bson:"mimeType"`
+	ProjectID      bson.ObjectId             `json:"projectID" bson:"projectID"`
+	RepositoryID   bson.ObjectId             `json:"repositoryID" bson:"repositoryID"`
+	Type           string                    `json:"type" bson:"type"`
+	Path           string                    `json:"path" bson:"path"`
+	Size           int64                     `json:"size" bson:"size"`
+	SHA256         string                    `json:"sha256" bson:"sha256"`
+	BlobKey        string                    `json:"-" bson:"blobKey,omitempty"`
+	Version        int                       `json:"version" bson:"version,omitempty"`
+	SampleIDs      []string                  `json:"sampleIDs,omitempty" bson:"sampleIDs,omitempty"`
+	VariantSummary *genomics.VariantSummary  `json:"variantSummary,omitempty" bson:"variantSummary,omitempty"`
+	TraitSchema    *genomics.PhenotypeSchema `json:"traitSchema,omitempty" bson:"traitSchema,omitempty"`
+	Lineage        *Lineage                  `json:"lineage,omitempty" bson:"lineage,omitempty"`
+	CreatedBy      string                    `json:"createdBy" bson:"createdBy"`
+	CreatedAt      time.Time                 `json:"createdAt" bson:"createdAt"`
+	UpdatedAt      time.Time                 `json:"updatedAt" bson:"updatedAt"`
+	UpdatedBy      string                    `json:"updatedBy" bson:"updatedBy"`
+}
+
+// VersionDiff compares a version with the one before it
+type VersionDiff struct {
+	SizeDelta       int64 `json:"sizeDelta" bson:"sizeDelta"`
+	ContentChanged  bool  `json:"contentChanged" bson:"contentChanged"`
+	MimeTypeChanged bool  `json:"mimeTypeChanged" bson:"mimeTypeChanged"`
+}
+
+// DocumentVersion is an immutable content of a document, the document mirrors its latest version
+type DocumentVersion struct {
+	ID             bson.ObjectId             `json:"_id" bson:"_id"`
+	DocumentID     bson.ObjectId             `json:"documentID" bson:"documentID"`
+	ProjectID      bson.ObjectId             `json:"projectID" bson:"projectID"`
+	Version        int                       `json:"version" bson:"version"`
+	MimeType       string                    `json:"mimeType" bson:"mimeType"`
+	Size           int64                     `json:"size" bson:"size"`
+	SHA256         string                    `json:"sha256" bson:"sha256"`
+	BlobKey        string                    `json:"-" bson:"blobKey,omitempty"`
+	Path           string                    `json:"path" bson:"-"`
+	SampleIDs      []string                  `json:"sampleIDs,omitempty" bson:"sampleIDs,omitempty"`
+	VariantSummary *genomics.VariantSummary  `json:"variantSummary,omitempty" bson:"variantSummary,omitempty"`
+	TraitSchema    *genomics.PhenotypeSchema `json:"traitSchema,omitempty" bson:"traitSchema,omitempty"`
+	Lineage        *Lineage                  `json:"lineage,omitempty" bson:"lineage,omitempty"`
+	Diff           VersionDiff               `json:"diff" bson:"diff"`
+	RestoredFrom   int                       `json:"restoredFrom,omitempty" bson:"restoredFrom,omitempty"`
+	CreatedBy      string                    `json:"createdBy" bson:"createdBy"`
+	CreatedAt      time.Time                 `json:"createdAt" bson:"createdAt"`
+}
+
+// Lineage links a document computed by the server to the document version it was computed from, with
+// the parameters and report of the operation
+type Lineage struct {
+	SourceID      bson.ObjectId         `json:"sourceID" bson:"sourceID"`
+	SourceVersion int                   `json:"sourceVersion" bson:"sourceVersion"`
+	Operation     string                `json:"operation" bson:"operation"`
+	Parameters    genomics.QCParameters `json:"parameters" bson:"parameters"`
+	Report        genomics.QCReport     `json:"report" bson:"report"`
+	JobID         bson.ObjectId         `json:"jobID" bson:"jobID"`
+}
+
+// Duplicate is another document with the same content, in a repository the user can see
+type Duplicate struct {
+	DocumentID     bson.ObjectId `json:"documentID"`
+	Name           string        `json:"name"`
+	ProjectID      bson.ObjectId `json:"projectID"`
+	RepositoryID   bson.ObjectId `json:"repositoryID"`
+	RepositoryName string        `json:"repositoryName"`
 }
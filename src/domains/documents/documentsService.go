@@ -2,16 +2,30 @@ package documents
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math"
 	"time"
 
 	"github.com/fatih/structs"
 	cloudstorage "github.com/khoa5773/go-server/src/domains/cloudStorage"
+	"github.com/khoa5773/go-server/src/domains/events"
+	"github.com/khoa5773/go-server/src/domains/jobs"
+	"github.com/khoa5773/go-server/src/domains/logs"
+	"github.com/khoa5773/go-server/src/domains/projects"
 	"github.com/khoa5773/go-server/src/domains/repositories"
+	"github.com/khoa5773/go-server/src/genomics"
 	"github.com/khoa5773/go-server/src/shared"
 	"github.com/mitchellh/mapstructure"
+	"gopkg.in/mgo.v2"
 	"gopkg.in/mgo.v2/bson"
 )
 
+// maxDuplicates bounds the duplicates reported for an upload
+const maxDuplicates = 20
+
 func FindManyDocumentsInRepository(findManyDocumentDto FindManyDocumentsDto, credentials shared.Credentials) (shared.Result, error) {
 	var documents []map[string]interface{}
 	DocumentsModel := shared.MongoSession.C("documents")
@@ -27,7 +41,12 @@ func FindManyDocumentsInRepository(findManyDocumentDto FindManyDocumentsDto, cre
 	}
 
 	for _, val := range data.ValidData {
-		val["path"], err = cloudstorage.GenerateSignedUrl(val["_id"].(bson.ObjectId).Hex(), val["projectID"].(bson.ObjectId).Hex(), val["mimeType"].(string))
+		key, _ := val["blobKey"].(string)
+		if key == "" {
+			key = cloudstorage.ObjectKey(val["_id"].(bson.ObjectId).Hex(), val["projectID"].(bson.ObjectId).Hex(), val["mimeType"].(string))
+		}
+		delete(val, "blobKey")
+		val["path"], err = cloudstorage.SignObjectKey(key)
 		if err != nil {
 			return shared.Result{}, err
 		}
@@ -54,7 +73,7 @@ func FindOneDocument(findOneDocumentDto *FindOneDocumentDto, credentials shared.
 		return Document{}, err
 	}
 
-	document.Path, err = cloudstorage.GenerateSignedUrl(document.ID.Hex(), document.ProjectID.Hex(), document.MimeType)
+	document.Path, err = cloudstorage.SignObjectKey(objectKey(document))
 	if err != nil {
 		return Document{}, err
 	}
@@ -63,42 +82,192 @@ func FindOneDocument(findOneDocumentDto *FindOneDocumentDto, credentials shared.
 }
 
 func CreateOneDocument(createOneDocumentDto *CreateOneDocumentDto, credentials shared.Credentials) (bool, error) {
+	isSuccess, _, err := createOneDocument(createOneDocumentDto, credentials)
+	return isSuccess, err
+}
+
+// createOneDocument inserts the document and its first version, and removes them again when a later
+// step fails. leftover tells that the removal failed too, so they may still reference the content.
+func createOneDocument(createOneDocumentDto *CreateOneDocumentDto, credentials shared.Credentials) (isSuccess bool, leftover bool, err error) {
+	createOneDocumentDto.Version = 1
 	createOneDocumentDto.CreatedBy = credentials.Id
 	createOneDocumentDto.UpdatedBy = credentials.Id
 	createOneDocumentDto.CreatedAt = time.Now()
 	createOneDocumentDto.UpdatedAt = time.Now()
 
-	_, err := repositories.CheckRepositoriesExists([]bson.ObjectId{createOneDocumentDto.RepositoryID}, createOneDocumentDto.ProjectID)
+	_, err = repositories.CheckRepositoriesExists([]bson.ObjectId{createOneDocumentDto.RepositoryID}, createOneDocumentDto.ProjectID)
 	if err != nil {
-		return false, err
+		return false, false, err
 	}
 
 	document := &Document{}
 	err = mapstructure.Decode(structs.Map(createOneDocumentDto), document)
 	if err != nil {
-		return false, err
+		return false, false, err
 	}
 
 	_, err = shared.ValidateAccessToSingle(structs.Map(document), credentials)
 	if err != nil {
-		return false, err
+		return false, false, err
 	}
 
 	DocumentsModel := shared.MongoSession.C("documents")
 	err = DocumentsModel.Insert(createOneDocumentDto)
 	if err != nil {
-		return false, err
+		return false, false, err
+	}
+
+	documentVersion := firstVersion(*document)
+	DocumentVersionsModel := shared.MongoSession.C("documentVersions")
+	err = DocumentVersionsModel.Insert(documentVersion)
+	if err != nil {
+		return false, !removeCreatedDocument(createOneDocumentDto.ID, ""), err
 	}
 
 	credentials.IsAdmin = true
 	_, err = repositories.AddDocumentIDs(createOneDocumentDto.RepositoryID, createOneDocumentDto.ProjectID, []bson.ObjectId{createOneDocumentDto.ID}, credentials)
+	if err != nil {
+		return false, !removeCreatedDocument(createOneDocumentDto.ID, documentVersion.ID), err
+	}
+
+	created := publishDocument(events.DOCUMENT_CREATED, createOneDocumentDto.ID)
+	logs.Record(credentials, createOneDocumentDto.ProjectID, logs.DOCUMENT, createOneDocumentDto.ID.Hex(), logs.CREATE, nil, created)
+	return true, false, nil
+}
+
+// ResumeDocumentCreation is for jobs creating a document under an ID chosen beforehand. It tells
+// whether the document exists, and then inserts its first version and adds it to its repository in
+// case a previous attempt stopped before.
+func ResumeDocumentCreation(documentID bson.ObjectId, credentials shared.Credentials) (bool, error) {
+	var document Document
+	DocumentsModel := shared.MongoSession.C("documents")
+	err := DocumentsModel.FindId(documentID).One(&document)
+	if err == mgo.ErrNotFound {
+		return false, nil
+	}
 	if err != nil {
 		return false, err
 	}
 
+	DocumentVersionsModel := shared.MongoSession.C("documentVersions")
+	_, err = DocumentVersionsModel.Upsert(bson.M{"documentID": document.ID, "version": 1}, bson.M{"$setOnInsert": firstVersion(document)})
+	if err != nil {
+		return true, err
+	}
+
+	credentials.IsAdmin = true
+	_, err = repositories.AddDocumentIDs(document.RepositoryID, document.ProjectID, []bson.ObjectId{document.ID}, credentials)
+	if err != nil {
+		return true, err
+	}
+
 	return true, nil
 }
 
+// removeCreatedDocument undoes the inserts of a document whose creation failed halfway and tells
+// whether it succeeded
+func removeCreatedDocument(documentID, versionID bson.ObjectId) bool {
+	if versionID != "" {
+		DocumentVersionsModel := shared.MongoSession.C("documentVersions")
+		err := DocumentVersionsModel.RemoveId(versionID)
+		if err != nil && err != mgo.ErrNotFound {
+			log.Println("documents: removing version", versionID.Hex(), "of a failed creation failed:", err)
+			return false
+		}
+	}
+
+	DocumentsModel := shared.MongoSession.C("documents")
+	err := DocumentsModel.RemoveId(documentID)
+	if err != nil && err != mgo.ErrNotFound {
+		log.Println("documents: removing document", documentID.Hex(), "of a failed creation failed:", err)
+		return false
+	}
+	return true
+}
+
+// CreateOneDocumentFromStagedFile creates a document whose content is the staged file. Documents with
+// the same content share a single stored blob.
+func CreateOneDocumentFromStagedFile(
+	ctx context.Context, createOneDocumentDto *CreateOneDocumentDto, staged cloudstorage.StagedFile, credentials shared.Credentials,
+) (bool, error) {
+	content, err := inspectContent(ctx, staged, string(createOneDocumentDto.Type), createOneDocumentDto.MimeType, createOneDocumentDto.Coding)
+	if err != nil {
+		cloudstorage.DiscardStagedFile(staged)
+		return false, err
+	}
+	createOneDocumentDto.SampleIDs = content.SampleIDs
+	createOneDocumentDto.VariantSummary = content.VariantSummary
+	createOneDocumentDto.TraitSchema = content.TraitSchema
+
+	key, err := cloudstorage.AcquireBlob(ctx, staged)
+	if err != nil {
+		return false, err
+	}
+
+	createOneDocumentDto.BlobKey = key
+	createOneDocumentDto.Path = cloudstorage.OBJECT_URL_PREFIX + key
+	createOneDocumentDto.Size = staged.Size
+	createOneDocumentDto.SHA256 = staged.SHA256
+
+	isSuccess, leftover, err := createOneDocument(createOneDocumentDto, credentials)
+	if err != nil {
+		// the content stays referenced by what could not be removed
+		if !leftover {
+			releaseBlob(staged.SHA256)
+		}
+		return false, err
+	}
+
+	return isSuccess, nil
+}
+
+// FindDuplicateDocuments lists the other documents with the content of the given one, in the projects
+// of the user or created by them
+func FindDuplicateDocuments(documentID bson.ObjectId, credentials shared.Credentials) ([]Duplicate, error) {
+	duplicates := []Duplicate{}
+
+	var document Document
+	DocumentsModel := shared.MongoSession.C("documents")
+	err := DocumentsModel.FindId(documentID).One(&document)
+	if err != nil {
+		return duplicates, err
+	}
+	if document.SHA256 == "" {
+		return duplicates, nil
+	}
+
+	var others []Document
+	err = DocumentsModel.Find(bson.M{
+		"sha256": document.SHA256,
+		"_id":    bson.M{"$ne": document.ID},
+		"$or":    []bson.M{{"projectID": bson.M{"$in": credentials.ProjectIDs}}, {"createdBy": credentials.Id}},
+	}).Sort("createdAt").Limit(maxDuplicates).All(&others)
+	if err != nil {
+		return duplicates, err
+	}
+
+	repositoryIDs := []bson.ObjectId{}
+	for _, other := range others {
+		repositoryIDs = append(repositoryIDs, other.RepositoryID)
+	}
+	repositoryNames, err := repositories.FindRepositoryNames(repositoryIDs)
+	if err != nil {
+		return duplicates, err
+	}
+
+	for _, other := range others {
+		duplicates = append(duplicates, Duplicate{
+			DocumentID:     other.ID,
+			Name:           other.Name,
+			ProjectID:      other.ProjectID,
+			RepositoryID:   other.RepositoryID,
+			RepositoryName: repositoryNames[other.RepositoryID],
+		})
+	}
+
+	return duplicates, nil
+}
+
 func UpdateDocument(findOneDocumentDto *FindOneDocumentDto, updateDocumentDto *UpdateDocumentDto, credentials shared.Credentials) (bool, error) {
 	var document *Document
 	DocumentsModel := shared.MongoSession.C("documents")
@@ -120,6 +289,12 @@ func UpdateDocument(findOneDocumentDto *FindOneDocumentDto, updateDocumentDto *U
 
 	_ = mapstructure.Decode(data, document)
 
+	// the sample IDs, variant summary or trait schema were read for the type the file was uploaded as
+	if updateDocumentDto.Type != document.Type &&
+		(inspectedAs(document.Type, document.MimeType) || inspectedAs(updateDocumentDto.Type, document.MimeType)) {
+		return false, fmt.Errorf("the type of a %s document can not be changed, upload the file again as a %s document", document.MimeType, updateDocumentDto.Type)
+	}
+
 	updateDocumentDto.UpdatedAt = time.Now()
 	updateDocumentDto.UpdatedBy = credentials.Id
 
@@ -129,6 +304,8 @@ func UpdateDocument(findOneDocumentDto *FindOneDocumentDto, updateDocumentDto *U
 	}
 
 	if updateDocumentDto.RepositoryID == "" {
+		updated := publishDocument(events.DOCUMENT_UPDATED, document.ID)
+		logs.Record(credentials, document.ProjectID, logs.DOCUMENT, document.ID.Hex(), logs.UPDATE, document, updated)
 		return true, nil
 	}
 
@@ -142,6 +319,8 @@ func UpdateDocument(findOneDocumentDto *FindOneDocumentDto, updateDocumentDto *U
 		return false, err
 	}
 
+	updated := publishDocument(events.DOCUMENT_UPDATED, document.ID)
+	logs.Record(credentials, document.ProjectID, logs.DOCUMENT, document.ID.Hex(), logs.UPDATE, document, updated)
 	return true, nil
 }
 
@@ -168,7 +347,7 @@ func DeleteDocument(deleteDocumentDto *DeleteDocumentDto, credentials shared.Cre
 		return false, err
 	}
 
-	_, err = cloudstorage.RemoveFileFromCloudStorage(context.Background(), document.ID.Hex(), document.ProjectID.Hex(), document.MimeType)
+	err = releaseVersions(*document)
 	if err != nil {
 		return false, err
 	}
@@ -178,5 +357,699 @@ func DeleteDocument(deleteDocumentDto *DeleteDocumentDto, credentials shared.Cre
 		return false, err
 	}
 
+	publishSummary(events.DOCUMENT_DELETED, document)
+	logs.Record(credentials, document.ProjectID, logs.DOCUMENT, document.ID.Hex(), logs.DELETE, document, nil)
 	return true, nil
 }
+
+// CurrentVersion is the version a document mirrors, documents created before versions existed are at
+// their first version
+func CurrentVersion(document Document) int {
+	if document.Version == 0 {
+		return 1
+	}
+	return document.Version
+}
+
+// ResolveTrait checks a trait name against the trait schema of a phenotype document and names the first
+// numeric trait when it is empty. Documents without a schema are read as they are when the job runs.
+func ResolveTrait(document Document, trait string) (string, error) {
+	if document.TraitSchema == nil {
+		return trait, nil
+	}
+
+	schema, err := document.TraitSchema.Trait(trait)
+	if err != nil {
+		return "", fmt.Errorf("phenotype %s: %v", document.Name, err)
+	}
+	if schema.Type != genomics.NUMERIC {
+		return "", fmt.Errorf("phenotype %s: trait %s is categorical, only numeric traits can be predicted", document.Name, schema.Name)
+	}
+
+	return schema.Name, nil
+}
+
+// FindDocumentVersions lists the versions of a document, the latest first
+func FindDocumentVersions(findOneDocumentDto *FindOneDocumentDto, credentials shared.Credentials) ([]DocumentVersion, error) {
+	document, err := findAccessibleDocument(findOneDocumentDto, credentials)
+	if err != nil {
+		return nil, err
+	}
+
+	err = ensureVersions(&document)
+	if err != nil {
+		return nil, err
+	}
+
+	versions := []DocumentVersion{}
+	DocumentVersionsModel := shared.MongoSession.C("documentVersions")
+	err = DocumentVersionsModel.Find(bson.M{"documentID": document.ID}).Sort("-version").All(&versions)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range versions {
+		versions[i].Path, err = cloudstorage.SignObjectKey(objectKey(DocumentAtVersion(document, versions[i])))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return versions, nil
+}
+
+// FindOneDocumentVersion returns the document as it was at the given version
+func FindOneDocumentVersion(findOneDocumentVersionDto *FindOneDocumentVersionDto, credentials shared.Credentials) (Document, error) {
+	document, err := findAccessibleDocument(&FindOneDocumentDto{
+		ID:           findOneDocumentVersionDto.ID,
+		ProjectID:    findOneDocumentVersionDto.ProjectID,
+		RepositoryID: findOneDocumentVersionDto.RepositoryID,
+	}, credentials)
+	if err != nil {
+		return Document{}, err
+	}
+
+	return FindDocumentAtVersion(document, findOneDocumentVersionDto.Version)
+}
+
+// FindDocumentAtVersion returns the document with the content of one of its versions, for callers which
+// already checked the access to the document
+func FindDocumentAtVersion(document Document, version int) (Document, error) {
+	if version == CurrentVersion(document) {
+		return document, nil
+	}
+
+	err := ensureVersions(&document)
+	if err != nil {
+		return Document{}, err
+	}
+
+	var documentVersion DocumentVersion
+	DocumentVersionsModel := shared.MongoSession.C("documentVersions")
+	err = DocumentVersionsModel.Find(bson.M{"documentID": document.ID, "version": version}).One(&documentVersion)
+	if err == mgo.ErrNotFound {
+		return Document{}, fmt.Errorf("document %s has no version %d", document.ID.Hex(), version)
+	}
+	if err != nil {
+		return Document{}, err
+	}
+
+	return DocumentAtVersion(document, documentVersion), nil
+}
+
+// DocumentAtVersion replaces the content of the document by the content of the version
+func DocumentAtVersion(document Document, documentVersion DocumentVersion) Document {
+	document.Version = documentVersion.Version
+	document.MimeType = documentVersion.MimeType
+	document.Size = documentVersion.Size
+	document.SHA256 = documentVersion.SHA256
+	document.BlobKey = documentVersion.BlobKey
+	document.SampleIDs = documentVersion.SampleIDs
+	document.VariantSummary = documentVersion.VariantSummary
+	document.TraitSchema = documentVersion.TraitSchema
+	document.Lineage = documentVersion.Lineage
+	document.Path = ""
+	return document
+}
+
+// CreateDocumentVersion makes the staged file the content of a new version of the document. Without a
+// coding, a file of the same format keeps the coding of the current version when it has one.
+func CreateDocumentVersion(
+	ctx context.Context, findOneDocumentDto *FindOneDocumentDto, staged cloudstorage.StagedFile, mimeType string,
+	coding genomics.GenotypeCoding, credentials shared.Credentials,
+) (DocumentVersion, error) {
+	document, err := findAccessibleDocument(findOneDocumentDto, credentials)
+	if err != nil {
+		cloudstorage.DiscardStagedFile(staged)
+		return DocumentVersion{}, err
+	}
+
+	if coding == "" && mimeType == document.MimeType && document.VariantSummary != nil {
+		coding = document.VariantSummary.Coding
+	}
+	documentVersion, err := inspectContent(ctx, staged, document.Type, mimeType, coding)
+	if err != nil {
+		cloudstorage.DiscardStagedFile(staged)
+		return DocumentVersion{}, err
+	}
+
+	key, err := cloudstorage.AcquireBlob(ctx, staged)
+	if err != nil {
+		return DocumentVersion{}, err
+	}
+
+	documentVersion.MimeType = mimeType
+	documentVersion.Size = staged.Size
+	documentVersion.SHA256 = staged.SHA256
+	documentVersion.BlobKey = key
+	err = addVersion(&document, &documentVersion, credentials)
+	if err != nil {
+		releaseBlob(staged.SHA256)
+		return DocumentVersion{}, err
+	}
+
+	return documentVersion, nil
+}
+
+// RestoreDocumentVersion adds a version with the content of an older one, the history in between is kept
+func RestoreDocumentVersion(ctx context.Context, findOneDocumentVersionDto *FindOneDocumentVersionDto, credentials shared.Credentials) (DocumentVersion, error) {
+	document, err := findAccessibleDocument(&FindOneDocumentDto{
+		ID:           findOneDocumentVersionDto.ID,
+		ProjectID:    findOneDocumentVersionDto.ProjectID,
+		RepositoryID: findOneDocumentVersionDto.RepositoryID,
+	}, credentials)
+	if err != nil {
+		return DocumentVersion{}, err
+	}
+
+	if findOneDocumentVersionDto.Version == CurrentVersion(document) {
+		return DocumentVersion{}, fmt.Errorf("version %d is the current version", findOneDocumentVersionDto.Version)
+	}
+
+	restored, err := FindDocumentAtVersion(document, findOneDocumentVersionDto.Version)
+	if err != nil {
+		return DocumentVersion{}, err
+	}
+
+	documentVersion := DocumentVersion{
+		MimeType:       restored.MimeType,
+		Size:           restored.Size,
+		SHA256:         restored.SHA256,
+		BlobKey:        restored.BlobKey,
+		SampleIDs:      restored.SampleIDs,
+		VariantSummary: restored.VariantSummary,
+		TraitSchema:    restored.TraitSchema,
+		Lineage:        restored.Lineage,
+		RestoredFrom:   restored.Version,
+	}
+
+	if restored.BlobKey != "" {
+		err = cloudstorage.AddBlobReference(restored.SHA256)
+	} else {
+		// contents uploaded before blobs were shared belong to one document, they are copied into a blob
+		// so that every version holds a reference
+		documentVersion.BlobKey, documentVersion.Size, documentVersion.SHA256, err = copyIntoBlob(ctx, restored)
+	}
+	if err != nil {
+		return DocumentVersion{}, err
+	}
+
+	err = addVersion(&document, &documentVersion, credentials)
+	if err != nil {
+		releaseBlob(documentVersion.SHA256)
+		return DocumentVersion{}, err
+	}
+
+	return documentVersion, nil
+}
+
+// BundlePLINKFileset stages the .fam, .bim and .bed files of a PLINK fileset as one archive, which
+// is how the fileset is stored. The staged files are removed.
+func BundlePLINKFileset(ctx context.Context, files map[string]cloudstorage.StagedFile) (cloudstorage.StagedFile, error) {
+	defer func() {
+		for _, staged := range files {
+			cloudstorage.DiscardStagedFile(staged)
+		}
+	}()
+
+	reader, writer := io.Pipe()
+	go func() {
+		_ = writer.CloseWithError(genomics.WritePLINKArchive(writer, func(part string) (io.ReadCloser, int64, error) {
+			staged, ok := files[part]
+			if !ok {
+				return nil, 0, fmt.Errorf("the .%s file is missing", part)
+			}
+			content, err := cloudstorage.OpenStagedFile(ctx, staged)
+			return content, staged.Size, err
+		}))
+	}()
+
+	// the files were checked against the size limit already
+	staged, err := cloudstorage.StageFile(ctx, reader, math.MaxInt64)
+	_ = reader.Close()
+	return staged, err
+}
+
+// CreateQualityControl enqueues a quality control of a version of a GENOTYPE document, the current one
+// unless given. It returns the job and the ID of the document it will create.
+func CreateQualityControl(
+	findOneDocumentDto *FindOneDocumentDto, createQualityControlDto *CreateQualityControlDto, credentials shared.Credentials,
+) (bson.ObjectId, bson.ObjectId, error) {
+	if createQualityControlDto.Parameters.IsEmpty() {
+		return "", "", errors.New("set at least one quality control filter")
+	}
+
+	document, err := findAccessibleDocument(findOneDocumentDto, credentials)
+	if err != nil {
+		return "", "", err
+	}
+	if document.Type != string(GENOTYPE) {
+		return "", "", fmt.Errorf("document %s is not a %s document", document.ID.Hex(), GENOTYPE)
+	}
+	if !genomics.IsGenotypeFormat(document.MimeType) {
+		return "", "", fmt.Errorf("%s genotypes can not be read", document.MimeType)
+	}
+
+	version := createQualityControlDto.Version
+	if version == 0 {
+		version = CurrentVersion(document)
+	}
+	_, err = FindDocumentAtVersion(document, version)
+	if err != nil {
+		return "", "", err
+	}
+
+	name := createQualityControlDto.Name
+	if name == "" {
+		name = document.Name + " (QC)"
+	}
+
+	jobID := bson.NewObjectId()
+	derivedID := bson.NewObjectId()
+	_, err = jobs.Enqueue(&jobs.CreateOneJobDto{
+		ID:        jobID,
+		ProjectID: document.ProjectID,
+		Type:      QC_JOB_TYPE,
+		Payload: map[string]interface{}{
+			"documentID":   document.ID,
+			"repositoryID": document.RepositoryID,
+			"version":      version,
+			"derivedID":    derivedID,
+			"name":         name,
+			"parameters":   createQualityControlDto.Parameters,
+		},
+	}, credentials)
+	if err != nil {
+		return "", "", err
+	}
+
+	return jobID, derivedID, nil
+}
+
+// RunQualityControl filters the genotype of the source version and stores the result as a new document
+// of the same repository. A VCF source stays VCF with only the kept sample columns and data lines, so its
+// multiallelic calls, ploidy, phasing and annotations survive. Other sources are written as VCF when
+// genomics.CanWriteVCF allows it and as a dosage matrix otherwise. A retried job finds the document it
+// created before.
+func RunQualityControl(
+	ctx context.Context, jobID bson.ObjectId, source *FindOneDocumentDto, version int, derivedID bson.ObjectId, name string,
+	parameters genomics.QCParameters, credentials shared.Credentials, progress func(percentage int),
+) error {
+	exists, err := ResumeDocumentCreation(derivedID, credentials)
+	if err != nil || exists {
+		return err
+	}
+
+	document, err := findAccessibleDocument(source, credentials)
+	if err != nil {
+		return err
+	}
+	document, err = FindDocumentAtVersion(document, version)
+	if err != nil {
+		return err
+	}
+
+	content, err := OpenDocumentContent(ctx, document)
+	if err != nil {
+		return err
+	}
+	var coding genomics.GenotypeCoding
+	if document.VariantSummary != nil {
+		coding = document.VariantSummary.Coding
+	}
+	genotype, err := genomics.ReadGenotype(content, document.MimeType, coding)
+	content.Close()
+	if err != nil {
+		return jobs.Permanent(fmt.Errorf("genotype %s: %v", document.Name, err))
+	}
+	progress(30)
+
+	var report genomics.QCReport
+	var mimeType string
+	var write func(w io.Writer) error
+	if genomics.IsVCF(document.MimeType) {
+		var samples, markers []int
+		samples, markers, report, err = genomics.SelectQC(genotype, parameters)
+		if err != nil {
+			return jobs.Permanent(err)
+		}
+		content, err = OpenDocumentContent(ctx, document)
+		if err != nil {
+			return err
+		}
+		defer content.Close()
+		mimeType, coding = "vcf", ""
+		write = func(w io.Writer) error {
+			return genomics.FilterVCF(w, content, samples, markers)
+		}
+	} else {
+		var filtered *genomics.Genotype
+		filtered, report, err = genomics.RunQC(genotype, parameters)
+		if err != nil {
+			return jobs.Permanent(err)
+		}
+		mimeType, coding = "tsv", genomics.DOSAGE_012
+		write = func(w io.Writer) error {
+			return genomics.WriteMatrix(w, filtered)
+		}
+		if genomics.CanWriteVCF(filtered) {
+			mimeType, coding = "vcf", ""
+			write = func(w io.Writer) error {
+				return genomics.WriteVCF(w, filtered)
+			}
+		}
+	}
+	progress(60)
+
+	maxUploadSize, err := projects.MaxUploadSize(document.ProjectID)
+	if err != nil {
+		return err
+	}
+	reader, writer := io.Pipe()
+	go func() {
+		_ = writer.CloseWithError(write(writer))
+	}()
+	staged, err := cloudstorage.StageFile(ctx, reader, maxUploadSize)
+	_ = reader.Close()
+	if err != nil {
+		return err
+	}
+	progress(90)
+
+	_, err = CreateOneDocumentFromStagedFile(ctx, &CreateOneDocumentDto{
+		ID:           derivedID,
+		Name:         name,
+		Description:  fmt.Sprintf("Quality control of %s version %d", document.Name, version),
+		Type:         GENOTYPE,
+		Coding:       coding,
+		MimeType:     mimeType,
+		RepositoryID: document.RepositoryID,
+		ProjectID:    document.ProjectID,
+		Lineage: &Lineage{
+			SourceID:      document.ID,
+			SourceVersion: version,
+			Operation:     QC_JOB_TYPE,
+			Parameters:    parameters,
+			Report:        report,
+			JobID:         jobID,
+		},
+	}, staged, credentials)
+	return err
+}
+
+// InvalidContentError rejects a file that does not fit its document type or format, sending the same
+// file again fails the same way
+type InvalidContentError struct {
+	Err error
+}
+
+func (e *InvalidContentError) Error() string {
+	return e.Err.Error()
+}
+
+// inspectContent validates the genotype and phenotype files the server can read and returns what it
+// learnt from them, with the coding of genotype calls and the trait schema of phenotype tables. The other
+// files are kept as they are.
+func inspectContent(
+	ctx context.Context, staged cloudstorage.StagedFile, documentType string, mimeType string, coding genomics.GenotypeCoding,
+) (DocumentVersion, error) {
+	if mimeType == genomics.PLINK_MIME_TYPE && documentType != string(GENOTYPE) {
+		return DocumentVersion{}, &InvalidContentError{Err: errors.New("PLINK filesets can only be GENOTYPE documents")}
+	}
+	isInspected := inspectedAs(documentType, mimeType)
+	if coding != "" && (!isInspected || documentType != string(GENOTYPE)) {
+		return DocumentVersion{}, &InvalidContentError{Err: errors.New("a coding can only be set for GENOTYPE documents in a format the server reads")}
+	}
+	if !isInspected {
+		return DocumentVersion{}, nil
+	}
+
+	content, err := cloudstorage.OpenStagedFile(ctx, staged)
+	if err != nil {
+		return DocumentVersion{}, err
+	}
+	defer content.Close()
+
+	var documentVersion DocumentVersion
+	if documentType == string(PHENOTYPE) {
+		documentVersion.SampleIDs, documentVersion.TraitSchema, err = genomics.InspectPhenotype(content, mimeType)
+		if err != nil {
+			return DocumentVersion{}, &InvalidContentError{Err: fmt.Errorf("invalid %s phenotype: %v", mimeType, err)}
+		}
+		return documentVersion, nil
+	}
+
+	documentVersion.SampleIDs, documentVersion.VariantSummary, err = genomics.InspectGenotype(content, mimeType, coding)
+	if err != nil {
+		return DocumentVersion{}, &InvalidContentError{Err: fmt.Errorf("invalid %s genotype: %v", mimeType, err)}
+	}
+
+	return documentVersion, nil
+}
+
+// inspectedAs tells whether files of the format are read and validated as documents of the type
+func inspectedAs(documentType string, mimeType string) bool {
+	return documentType == string(GENOTYPE) && genomics.IsGenotypeFormat(mimeType) ||
+		documentType == string(PHENOTYPE) && genomics.IsPhenotypeFormat(mimeType)
+}
+
+func findAccessibleDocument(findOneDocumentDto *FindOneDocumentDto, credentials shared.Credentials) (Document, error) {
+	var document Document
+	DocumentsModel := shared.MongoSession.C("documents")
+	err := DocumentsModel.Find(findOneDocumentDto).One(&document)
+	if err != nil {
+		return Document{}, err
+	}
+
+	_, err = shared.ValidateAccessToSingle(structs.Map(document), credentials)
+	if err != nil {
+		return Document{}, err
+	}
+
+	return document, nil
+}
+
+// addVersion stores the next version of the document and makes the document mirror it. Concurrent
+// versions are rejected, the document is only moved from the version it was read at.
+func addVersion(document *Document, documentVersion *DocumentVersion, credentials shared.Credentials) error {
+	err := ensureVersions(document)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	documentVersion.ID = bson.NewObjectId()
+	documentVersion.DocumentID = document.ID
+	documentVersion.ProjectID = document.ProjectID
+	documentVersion.Version = document.Version + 1
+	documentVersion.Diff = VersionDiff{
+		SizeDelta:       documentVersion.Size - document.Size,
+		ContentChanged:  documentVersion.SHA256 == "" || documentVersion.SHA256 != document.SHA256,
+		MimeTypeChanged: documentVersion.MimeType != document.MimeType,
+	}
+	documentVersion.CreatedBy = credentials.Id
+	documentVersion.CreatedAt = now
+
+	DocumentVersionsModel := shared.MongoSession.C("documentVersions")
+	err = DocumentVersionsModel.Insert(documentVersion)
+	if err != nil {
+		return err
+	}
+
+	DocumentsModel := shared.MongoSession.C("documents")
+	err = DocumentsModel.Update(bson.M{"_id": document.ID, "version": document.Version}, bson.M{"$set": bson.M{
+		"version":        documentVersion.Version,
+		"mimeType":       documentVersion.MimeType,
+		"size":           documentVersion.Size,
+		"sha256":         documentVersion.SHA256,
+		"blobKey":        documentVersion.BlobKey,
+		"path":           cloudstorage.OBJECT_URL_PREFIX + documentVersion.BlobKey,
+		"sampleIDs":      documentVersion.SampleIDs,
+		"variantSummary": documentVersion.VariantSummary,
+		"traitSchema":    documentVersion.TraitSchema,
+		"lineage":        documentVersion.Lineage,
+		"updatedAt":      now,
+		"updatedBy":      credentials.Id,
+	}})
+	if err != nil {
+		_ = DocumentVersionsModel.RemoveId(documentVersion.ID)
+		if err == mgo.ErrNotFound {
+			return errors.New("document was changed by another request, try again")
+		}
+		return err
+	}
+
+	updated := publishDocument(events.DOCUMENT_UPDATED, document.ID)
+	logs.Record(credentials, document.ProjectID, logs.DOCUMENT, document.ID.Hex(), logs.UPDATE, document, updated)
+	return nil
+}
+
+// ensureVersions records the first version of documents created before versions existed
+func ensureVersions(document *Document) error {
+	if document.Version != 0 {
+		return nil
+	}
+
+	first := firstVersion(*document)
+	DocumentVersionsModel := shared.MongoSession.C("documentVersions")
+	_, err := DocumentVersionsModel.Upsert(bson.M{"documentID": document.ID, "version": 1}, bson.M{"$setOnInsert": first})
+	if err != nil {
+		return err
+	}
+
+	DocumentsModel := shared.MongoSession.C("documents")
+	err = DocumentsModel.Update(bson.M{"_id": document.ID, "version": bson.M{"$exists": false}}, bson.M{"$set": bson.M{"version": 1}})
+	if err != nil && err != mgo.ErrNotFound {
+		return err
+	}
+
+	document.Version = 1
+	return nil
+}
+
+func firstVersion(document Document) DocumentVersion {
+	return DocumentVersion{
+		ID:             bson.NewObjectId(),
+		DocumentID:     document.ID,
+		ProjectID:      document.ProjectID,
+		Version:        1,
+		MimeType:       document.MimeType,
+		Size:           document.Size,
+		SHA256:         document.SHA256,
+		BlobKey:        document.BlobKey,
+		SampleIDs:      document.SampleIDs,
+		VariantSummary: document.VariantSummary,
+		TraitSchema:    document.TraitSchema,
+		Lineage:        document.Lineage,
+		Diff:           VersionDiff{SizeDelta: document.Size, ContentChanged: true},
+		CreatedBy:      document.CreatedBy,
+		CreatedAt:      document.CreatedAt,
+	}
+}
+
+// copyIntoBlob stores the content of a document uploaded before blobs were shared as a blob
+func copyIntoBlob(ctx context.Context, document Document) (string, int64, string, error) {
+	content, err := OpenDocumentContent(ctx, document)
+	if err != nil {
+		return "", 0, "", err
+	}
+	defer content.Close()
+
+	staged, err := cloudstorage.StageFile(ctx, content, math.MaxInt64)
+	if err != nil {
+		return "", 0, "", err
+	}
+
+	key, err := cloudstorage.AcquireBlob(ctx, staged)
+	if err != nil {
+		return "", 0, "", err
+	}
+
+	return key, staged.Size, staged.SHA256, nil
+}
+
+// releaseVersions drops the content of every version of a deleted document
+func releaseVersions(document Document) error {
+	if document.Version == 0 {
+		return releaseContent(document)
+	}
+
+	var versions []DocumentVersion
+	DocumentVersionsModel := shared.MongoSession.C("documentVersions")
+	err := DocumentVersionsModel.Find(bson.M{"documentID": document.ID}).All(&versions)
+	if err != nil {
+		return err
+	}
+
+	for _, documentVersion := range versions {
+		err = releaseContent(DocumentAtVersion(document, documentVersion))
+		if err != nil && err != cloudstorage.ErrNotFound {
+			return err
+		}
+	}
+
+	_, err = DocumentVersionsModel.RemoveAll(bson.M{"documentID": document.ID})
+	return err
+}
+
+func releaseBlob(sha256 string) {
+	err := cloudstorage.ReleaseBlob(context.Background(), sha256)
+	if err != nil {
+		log.Println("documents: releasing blob", sha256, "failed:", err)
+	}
+}
+
+// objectKey is where the content of the document is stored, documents uploaded before contents were
+// shared have their own object
+func objectKey(document Document) string {
+	if document.BlobKey != "" {
+		return document.BlobKey
+	}
+	return cloudstorage.ObjectKey(document.ID.Hex(), document.ProjectID.Hex(), document.MimeType)
+}
+
+func releaseContent(document Document) error {
+	if document.BlobKey != "" {
+		return cloudstorage.ReleaseBlob(context.Background(), document.SHA256)
+	}
+
+	_, err := cloudstorage.RemoveFileFromCloudStorage(context.Background(), document.ID.Hex(), document.ProjectID.Hex(), document.MimeType)
+	return err
+}
+
+// publishDocument sends a summary of the stored document to the event stream of its project and returns
+// it, nil when it can not be read back
+func publishDocument(eventType string, documentID bson.ObjectId) *Document {
+	var document Document
+	DocumentsModel := shared.MongoSession.C("documents")
+	err := DocumentsModel.FindId(documentID).One(&document)
+	if err != nil {
+		return nil
+	}
+
+	publishSummary(eventType, &document)
+	return &document
+}
+
+func publishSummary(eventType string, document *Document) {
+	events.Publish(document.ProjectID, eventType, events.DocumentSummary{
+		ID:           document.ID,
+		Name:         document.Name,
+		Type:         document.Type,
+		MimeType:     document.MimeType,
+		RepositoryID: document.RepositoryID,
+		Version:      document.Version,
+		UpdatedAt:    document.UpdatedAt,
+	})
+}
+
+// OpenDocumentRange reads length bytes of the document from offset, or up to the end when length is negative
+func OpenDocumentRange(ctx context.Context, document Document, offset, length int64) (io.ReadCloser, error) {
+	return cloudstorage.ReadRangeFromCloudStorage(ctx, objectKey(document), offset, length)
+}
+
+// DocumentETag identifies the content of the document, documents uploaded before hashes were
+// recorded fall back on the size and modification time of the stored file
+func DocumentETag(ctx context.Context, document *Document) (string, error) {
+	if document.SHA256 != "" {
+		return `"` + document.SHA256 + `"`, nil
+	}
+
+	info, err := cloudstorage.StatFileInCloudStorage(ctx, objectKey(*document))
+	if err != nil {
+		return "", err
+	}
+	document.Size = info.Size
+	return fmt.Sprintf(`W/"%x-%x"`, info.Size, info.UpdatedAt.UnixNano()), nil
+}
+
+// RecordDownload adds the download to the audit log, a download split into ranges is recorded once
+func RecordDownload(document Document, offset int64, credentials shared.Credentials) {
+	if offset == 0 {
+		logs.Record(credentials, document.ProjectID, logs.DOCUMENT, document.ID.Hex(), logs.DOWNLOAD, nil, nil)
+	}
+}
+
+func OpenDocumentContent(ctx context.Context, document Document) (io.ReadCloser, error) {
+	return cloudstorage.ReadRangeFromCloudStorage(ctx, objectKey(document), 0, -1)
+}
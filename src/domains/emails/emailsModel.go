@@ -0,0 +1,46 @@
+package emails
+
+import (
+	"time"
+
+	"gopkg.in/mgo.v2/bson"
+)
+
+type EmailStatus string
+
+const (
+	PENDING EmailStatus = "PENDING"
+	SENDING EmailStatus = "SENDING"
+	SENT    EmailStatus = "SENT"
+	FAILED  EmailStatus = "FAILED"
+)
+
+// Email is a rendered message waiting in the outbox
+type Email struct {
+	ID          bson.ObjectId `json:"_id" bson:"_id"`
+	To          string        `json:"to" bson:"to"`
+	Subject     string        `json:"subject" bson:"subject"`
+	Template    string        `json:"template" bson:"template"`
+	HTML        string        `json:"html" bson:"html"`
+	Text        string        `json:"text" bson:"text"`
+	Status      EmailStatus   `json:"status" bson:"status"`
+	Attempts    int           `json:"attempts" bson:"attempts"`
+	MaxAttempts int           `json:"maxAttempts" bson:"maxAttempts"`
+	SendAfter   time.Time     `json:"sendAfter" bson:"sendAfter"`
+	Error       string        `json:"error" bson:"error"`
+	CreatedAt   time.Time     `json:"createdAt" bson:"createdAt"`
+	UpdatedAt   time.Time     `json:"updatedAt" bson:"updatedAt"`
+	SentAt      time.Time     `json:"sentAt" bson:"sentAt,omitempty"`
+	// Owner is the server sending the email, another server takes it back once LeaseExpiresAt is past
+	Owner          string    `json:"owner,omitempty" bson:"owner,omitempty"`
+	LeaseExpiresAt time.Time `json:"leaseExpiresAt" bson:"leaseExpiresAt,omitempty"`
+}
+
+// Message is what a transport delivers
+type Message struct {
+	From    string
+	To      string
+	Subject string
+	HTML    string
+	Text    string
+}
@@ -0,0 +1,257 @@
+package emails
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/http"
+	"net/smtp"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Sender delivers a message through one transport
+type Sender interface {
+	Send(ctx context.Context, message Message) error
+}
+
+// NewSender builds the transport named by EMAIL_TRANSPORT
+func NewSender(transport string, options SenderOptions) (Sender, error) {
+	switch transport {
+	case "smtp":
+		if options.SMTPHost == "" {
+			return nil, fmt.Errorf("emails: SMTP_HOST is required by the smtp transport")
+		}
+		return &SMTPSender{Host: options.SMTPHost, Port: options.SMTPPort, User: options.SMTPUser, Pass: options.SMTPPass}, nil
+	case "sendgrid":
+		if options.SendGridAPIKey == "" {
+			return nil, fmt.Errorf("emails: SENDGRID_API_KEY is required by the sendgrid transport")
+		}
+		return &SendGridSender{APIKey: options.SendGridAPIKey}, nil
+	case "file":
+		dir := options.Dir
+		if dir == "" {
+			dir = "emails"
+		}
+		return &FileSender{Dir: dir}, nil
+	case "memory":
+		return &MemorySender{}, nil
+	}
+	return nil, fmt.Errorf("emails: unknown transport %q", transport)
+}
+
+type SenderOptions struct {
+	Dir            string
+	SMTPHost       string
+	SMTPPort       int
+	SMTPUser       string
+	SMTPPass       string
+	SendGridAPIKey string
+}
+
+// SMTPSender sends through an SMTP relay, upgrading with STARTTLS when offered
+type SMTPSender struct {
+	Host string
+	Port int
+	User string
+	Pass string
+}
+
+func (s *SMTPSender) Send(ctx context.Context, message Message) error {
+	body, err := buildMIME(message)
+	if err != nil {
+		return err
+	}
+
+	var auth smtp.Auth
+	if s.User != "" {
+		auth = smtp.PlainAuth("", s.User, s.Pass, s.Host)
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- smtp.SendMail(net.JoinHostPort(s.Host, strconv.Itoa(s.Port)), auth, message.From, []string{message.To}, body)
+	}()
+
+	select {
+	case err = <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+const sendGridURL = "https://api.sendgrid.com/v3/mail/send"
+
+// SendGridSender calls the v3 mail send API
+type SendGridSender struct {
+	APIKey string
+	Client *http.Client
+}
+
+func (s *SendGridSender) Send(ctx context.Context, message Message) error {
+	type address struct {
+		Email string `json:"email"`
+	}
+	type content struct {
+		Type  string `json:"type"`
+		Value string `json:"value"`
+	}
+	payload := map[string]interface{}{
+		"personalizations": []map[string]interface{}{{"to": []address{{Email: message.To}}}},
+		"from":             address{Email: message.From},
+		"subject":          message.Subject,
+		"content":          []content{{Type: "text/plain", Value: message.Text}, {Type: "text/html", Value: message.HTML}},
+	}
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	request, err := http.NewRequest(http.MethodPost, sendGridURL, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	request = request.WithContext(ctx)
+	request.Header.Set("Authorization", "Bearer "+s.APIKey)
+	request.Header.Set("Content-Type", "application/json")
+
+	client := s.Client
+	if client == nil {
+		client = &http.Client{Timeout: 30 * time.Second}
+	}
+	response, err := client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode >= 300 {
+		detail, _ := ioutil.ReadAll(response.Body)
+		return fmt.Errorf("sendgrid: %s: %s", response.Status, bytes.TrimSpace(detail))
+	}
+	return nil
+}
+
+// FileSender writes every message as an .eml file, for local development
+type FileSender struct {
+	Dir string
+}
+
+func (s *FileSender) Send(ctx context.Context, message Message) error {
+	body, err := buildMIME(message)
+	if err != nil {
+		return err
+	}
+
+	err = os.MkdirAll(s.Dir, 0755)
+	if err != nil {
+		return err
+	}
+
+	recipient := strings.Map(func(r rune) rune {
+		if r == '@' || r == '.' || r == '-' || r == '_' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' {
+			return r
+		}
+		return '_'
+	}, message.To)
+	name := fmt.Sprintf("%s-%s.eml", time.Now().Format("20060102T150405.000000000"), recipient)
+	return ioutil.WriteFile(filepath.Join(s.Dir, name), body, 0644)
+}
+
+// maxMemoryMessages is how many of the latest messages a MemorySender keeps
+const maxMemoryMessages = 1000
+
+// MemorySender keeps the latest messages it is given, for tests and development
+type MemorySender struct {
+	mutex    sync.Mutex
+	messages []Message
+}
+
+func (s *MemorySender) Send(ctx context.Context, message Message) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.messages = append(s.messages, message)
+	if len(s.messages) > maxMemoryMessages {
+		s.messages = append([]Message(nil), s.messages[len(s.messages)-maxMemoryMessages:]...)
+	}
+	return nil
+}
+
+func (s *MemorySender) Messages() []Message {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return append([]Message(nil), s.messages...)
+}
+
+// buildMIME renders a multipart/alternative message with the text part first
+func buildMIME(message Message) ([]byte, error) {
+	var buffer bytes.Buffer
+	writer := multipart.NewWriter(&buffer)
+
+	boundary := make([]byte, 12)
+	_, err := rand.Read(boundary)
+	if err != nil {
+		return nil, err
+	}
+	err = writer.SetBoundary(hex.EncodeToString(boundary))
+	if err != nil {
+		return nil, err
+	}
+
+	var header bytes.Buffer
+	fmt.Fprintf(&header, "From: %s\r\n", encodeHeader(message.From))
+	fmt.Fprintf(&header, "To: %s\r\n", encodeHeader(message.To))
+	fmt.Fprintf(&header, "Subject: %s\r\n", encodeHeader(message.Subject))
+	fmt.Fprintf(&header, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	fmt.Fprintf(&header, "MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&header, "Content-Type: multipart/alternative; boundary=%s\r\n\r\n", writer.Boundary())
+
+	for _, part := range []struct{ contentType, value string }{
+		{"text/plain; charset=UTF-8", message.Text},
+		{"text/html; charset=UTF-8", message.HTML},
+	} {
+		partWriter, err := writer.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		encoder := quotedprintable.NewWriter(partWriter)
+		_, err = encoder.Write([]byte(part.value))
+		if err != nil {
+			return nil, err
+		}
+		err = encoder.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err = writer.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	return append(header.Bytes(), buffer.Bytes()...), nil
+}
+
+// encodeHeader drops line breaks, which would let a value inject headers, and encodes non-ASCII text
+func encodeHeader(value string) string {
+	value = strings.NewReplacer("\r", " ", "\n", " ").Replace(value)
+	return mime.QEncoding.Encode("UTF-8", value)
+}
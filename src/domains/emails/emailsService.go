@@ -1,18 +1,213 @@
 package emails
 
-// import (
-// 	"github.com/khoa5773/go-server/src/configs"
-// 	"github.com/sendgrid/sendgrid-go"
-// 	"github.com/sendgrid/sendgrid-go/helpers/mail"
-// )
-
-// func SendEmails(to mail.Email, subject, content string) (bool, error) {
-// 	from := mail.NewEmail("Ngoc", "ngoc.nthongngoc@gmail.com")
-// 	message := mail.NewSingleEmail(from, subject, &to, "", content)
-// 	client := sendgrid.NewSendClient(configs.ConfigsService)
-// 	response, err := client.Send(message)
-// 	if err != nil || response.StatusCode != 200 {
-// 		return false, err
-// 	}
-// 	return true, nil
-// }
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/khoa5773/go-server/src/configs"
+	"github.com/khoa5773/go-server/src/shared"
+	"gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+)
+
+const (
+	defaultMaxAttempts = 5
+	// pollInterval bounds how late a retried email is sent
+	pollInterval = 15 * time.Second
+	// retryDelay doubles with every failed attempt
+	retryDelay  = time.Minute
+	sendTimeout = time.Minute
+	// leaseDuration outlasts any send, an email still SENDING after it was left by a stopped server
+	leaseDuration = 2 * sendTimeout
+)
+
+var (
+	sender Sender = &MemorySender{}
+	from          = "no-reply@localhost"
+
+	wake = make(chan struct{}, 1)
+
+	// instanceID tells apart the servers sharing the outbox
+	instanceID = newInstanceID()
+)
+
+func newInstanceID() string {
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "server"
+	}
+	return fmt.Sprintf("%s-%d-%s", hostname, os.Getpid(), bson.NewObjectId().Hex())
+}
+
+func notify() {
+	select {
+	case wake <- struct{}{}:
+	default:
+	}
+}
+
+// Queue renders a template and stores the message in the outbox. Delivery happens in the
+// background, so a failing transport never fails the caller.
+func Queue(to, templateName string, data interface{}) error {
+	message, err := Render(templateName, data)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	email := Email{
+		ID:          bson.NewObjectId(),
+		To:          to,
+		Subject:     message.Subject,
+		Template:    templateName,
+		HTML:        message.HTML,
+		Text:        message.Text,
+		Status:      PENDING,
+		MaxAttempts: defaultMaxAttempts,
+		SendAfter:   now,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+
+	EmailsModel := shared.MongoSession.C("emails")
+	err = EmailsModel.Insert(email)
+	if err != nil {
+		return err
+	}
+
+	notify()
+	return nil
+}
+
+// Start picks the configured transport and starts the outbox worker. Without a transport the outbox is
+// not worked and emails stay PENDING until a server starts with one.
+func Start() {
+	config := configs.ConfigsService
+	if config.EmailTransport == "none" {
+		log.Println("emails: no transport configured, queued emails are not sent")
+		return
+	}
+
+	configured, err := NewSender(config.EmailTransport, SenderOptions{
+		Dir:            config.EmailDir,
+		SMTPHost:       config.SMTPHost,
+		SMTPPort:       config.SMTPPort,
+		SMTPUser:       config.SMTPUser,
+		SMTPPass:       config.SMTPPass,
+		SendGridAPIKey: config.SendGridAPIKey,
+	})
+	if err != nil {
+		log.Fatalln(err)
+	}
+	sender = configured
+	if config.EmailFrom != "" {
+		from = config.EmailFrom
+	}
+
+	err = releaseExpired()
+	if err != nil {
+		log.Println("emails: recovery failed:", err)
+	}
+
+	go work()
+	notify()
+}
+
+// releaseExpired puts back the emails of servers that stopped while sending them. They may or may not
+// have gone out, sending twice is better than never. Emails claimed before leases existed have none.
+func releaseExpired() error {
+	EmailsModel := shared.MongoSession.C("emails")
+	now := time.Now()
+	_, err := EmailsModel.UpdateAll(
+		bson.M{"status": SENDING, "$or": []bson.M{
+			{"leaseExpiresAt": bson.M{"$lt": now}},
+			{"leaseExpiresAt": bson.M{"$exists": false}},
+		}},
+		bson.M{"$set": bson.M{"status": PENDING, "updatedAt": now}, "$unset": bson.M{"owner": "", "leaseExpiresAt": ""}},
+	)
+	return err
+}
+
+func work() {
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	for {
+		email, err := claim()
+		if err != nil {
+			log.Println("emails: claim failed:", err)
+		}
+		if email != nil {
+			deliver(email)
+			continue
+		}
+
+		select {
+		case <-wake:
+		case <-ticker.C:
+			err = releaseExpired()
+			if err != nil {
+				log.Println("emails: recovery failed:", err)
+			}
+		}
+	}
+}
+
+func claim() (*Email, error) {
+	var email Email
+	EmailsModel := shared.MongoSession.C("emails")
+	now := time.Now()
+	_, err := EmailsModel.Find(bson.M{"status": PENDING, "sendAfter": bson.M{"$lte": now}}).Sort("createdAt").Apply(mgo.Change{
+		Update: bson.M{
+			"$set": bson.M{"status": SENDING, "owner": instanceID, "leaseExpiresAt": now.Add(leaseDuration), "updatedAt": now},
+			"$inc": bson.M{"attempts": 1},
+		},
+		ReturnNew: true,
+	}, &email)
+	if err == mgo.ErrNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &email, nil
+}
+
+func deliver(email *Email) {
+	ctx, cancel := context.WithTimeout(context.Background(), sendTimeout)
+	defer cancel()
+
+	err := sender.Send(ctx, Message{From: from, To: email.To, Subject: email.Subject, HTML: email.HTML, Text: email.Text})
+
+	now := time.Now()
+	update := bson.M{"updatedAt": now}
+	switch {
+	case err == nil:
+		update["status"] = SENT
+		update["sentAt"] = now
+		update["error"] = ""
+	case email.Attempts < email.MaxAttempts:
+		update["status"] = PENDING
+		update["sendAfter"] = now.Add(retryDelay << uint(email.Attempts-1))
+		update["error"] = err.Error()
+	default:
+		update["status"] = FAILED
+		update["error"] = err.Error()
+	}
+	if err != nil {
+		log.Printf("emails: sending %s to %s failed (attempt %d): %v", email.ID.Hex(), email.To, email.Attempts, err)
+	}
+
+	// the email is only updated while this server holds it
+	EmailsModel := shared.MongoSession.C("emails")
+	err = EmailsModel.Update(
+		bson.M{"_id": email.ID, "status": SENDING, "owner": instanceID},
+		bson.M{"$set": update, "$unset": bson.M{"owner": "", "leaseExpiresAt": ""}},
+	)
+	if err != nil {
+		log.Println("emails: update failed:", err)
+	}
+}
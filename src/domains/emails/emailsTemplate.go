@@ -0,0 +1,73 @@
+package emails
+
+import (
+	"bytes"
+	"fmt"
+	htmltemplate "html/template"
+	"strings"
+	texttemplate "text/template"
+)
+
+// Template names
+const (
+	INVITATION = "invitation"
+)
+
+type emailTemplate struct {
+	subject *texttemplate.Template
+	text    *texttemplate.Template
+	html    *htmltemplate.Template
+}
+
+var templates = map[string]emailTemplate{
+	INVITATION: mustParse(INVITATION,
+		`{{.InviterName}} invited you to {{.ProjectName}}`,
+		`Hello,
+
+{{.InviterName}} invited you to join the project "{{.ProjectName}}" as {{.Role}}.
+
+Open the link below to accept or decline the invitation:
+{{.URL}}
+
+The invitation expires on {{.ExpiresAt.Format "January 2, 2006"}}.
+If you were not expecting it, you can ignore this email.
+`,
+		`<p>Hello,</p>
+<p>{{.InviterName}} invited you to join the project <strong>{{.ProjectName}}</strong> as {{.Role}}.</p>
+<p><a href="{{.URL}}">Accept or decline the invitation</a></p>
+<p>The invitation expires on {{.ExpiresAt.Format "January 2, 2006"}}.<br>
+If you were not expecting it, you can ignore this email.</p>
+`),
+}
+
+func mustParse(name, subject, text, html string) emailTemplate {
+	return emailTemplate{
+		subject: texttemplate.Must(texttemplate.New(name + ".subject").Option("missingkey=error").Parse(subject)),
+		text:    texttemplate.Must(texttemplate.New(name + ".text").Option("missingkey=error").Parse(text)),
+		html:    htmltemplate.Must(htmltemplate.New(name + ".html").Option("missingkey=error").Parse(html)),
+	}
+}
+
+// Render fills the subject, text and HTML bodies of a template
+func Render(name string, data interface{}) (Message, error) {
+	template, ok := templates[name]
+	if !ok {
+		return Message{}, fmt.Errorf("email template %s does not exist", name)
+	}
+
+	var subject, text, html bytes.Buffer
+	err := template.subject.Execute(&subject, data)
+	if err != nil {
+		return Message{}, err
+	}
+	err = template.text.Execute(&text, data)
+	if err != nil {
+		return Message{}, err
+	}
+	err = template.html.Execute(&html, data)
+	if err != nil {
+		return Message{}, err
+	}
+
+	return Message{Subject: strings.TrimSpace(subject.String()), Text: text.String(), HTML: html.String()}, nil
+}
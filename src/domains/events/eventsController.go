@@ -0,0 +1,91 @@
+package events
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/khoa5773/go-server/src/middleware/auth"
+	"github.com/khoa5773/go-server/src/middleware/authz"
+)
+
+// heartbeatInterval keeps proxies from closing idle streams
+const heartbeatInterval = 15 * time.Second
+
+func ApplyRoutes(r *gin.Engine) {
+	EventsControllers := r.Group("projects/:projectID/events")
+	EventsControllers.GET("", auth.JWTRequired, authz.Scopes([]string{"projects:read", "proj:projects:read"}), streamEventsController)
+}
+
+func writeEvent(w gin.ResponseWriter, event Event) error {
+	data, err := json.Marshal(event.Data)
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", event.ID, event.Type, data)
+	if err != nil {
+		return err
+	}
+
+	w.Flush()
+	return nil
+}
+
+func streamEventsController(c *gin.Context) {
+	var streamEventsDto StreamEventsDto
+	var resumeEventsDto ResumeEventsDto
+
+	err := c.ShouldBindUri(&streamEventsDto)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	err = c.ShouldBindHeader(&resumeEventsDto)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	subscription, replay := Subscribe(streamEventsDto.ProjectID, resumeEventsDto.LastEventID)
+	defer Unsubscribe(subscription)
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+	c.Writer.Flush()
+
+	for _, event := range replay {
+		if writeEvent(c.Writer, event) != nil {
+			return
+		}
+	}
+
+	heartbeat := time.NewTicker(heartbeatInterval)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case event, ok := <-subscription.Events:
+			if !ok {
+				return
+			}
+			if writeEvent(c.Writer, event) != nil {
+				return
+			}
+		case <-heartbeat.C:
+			_, err = fmt.Fprint(c.Writer, ": heartbeat\n\n")
+			if err != nil {
+				return
+			}
+			c.Writer.Flush()
+		}
+	}
+}
@@ -0,0 +1,13 @@
+package events
+
+import (
+	"gopkg.in/mgo.v2/bson"
+)
+
+type StreamEventsDto struct {
+	ProjectID bson.ObjectId `json:"projectID,omitempty" bson:"projectID,omitempty" uri:"projectID" binding:"required,mongoid"`
+}
+
+type ResumeEventsDto struct {
+	LastEventID uint64 `header:"Last-Event-ID"`
+}
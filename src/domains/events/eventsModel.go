@@ -0,0 +1,47 @@
+package events
+
+import (
+	"time"
+
+	"gopkg.in/mgo.v2/bson"
+)
+
+const (
+	JOB_PROGRESS     = "job.progress"
+	JOB_FINISHED     = "job.finished"
+	DOCUMENT_CREATED = "document.created"
+	DOCUMENT_UPDATED = "document.updated"
+	DOCUMENT_DELETED = "document.deleted"
+)
+
+// Event is kept in memory only, IDs increase for the lifetime of the process. Its data is a summary,
+// clients fetch the job or document it names when they need more.
+type Event struct {
+	ID        uint64        `json:"id"`
+	ProjectID bson.ObjectId `json:"projectID"`
+	Type      string        `json:"type"`
+	Data      interface{}   `json:"data"`
+	CreatedAt time.Time     `json:"createdAt"`
+}
+
+// JobSummary is the data of job events
+type JobSummary struct {
+	ID        bson.ObjectId `json:"_id"`
+	Type      string        `json:"type"`
+	Status    string        `json:"status"`
+	Progress  int           `json:"progress"`
+	Attempts  int           `json:"attempts"`
+	Error     string        `json:"error,omitempty"`
+	UpdatedAt time.Time     `json:"updatedAt"`
+}
+
+// DocumentSummary is the data of document events
+type DocumentSummary struct {
+	ID           bson.ObjectId `json:"_id"`
+	Name         string        `json:"name"`
+	Type         string        `json:"type"`
+	MimeType     string        `json:"mimeType"`
+	RepositoryID bson.ObjectId `json:"repositoryID"`
+	Version      int           `json:"version"`
+	UpdatedAt    time.Time     `json:"updatedAt"`
+}
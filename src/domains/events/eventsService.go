@@ -0,0 +1,164 @@
+package events
+
+import (
+	"sync"
+	"time"
+
+	"gopkg.in/mgo.v2/bson"
+)
+
+const (
+	// historySize is the number of events per project kept for Last-Event-ID resume
+	historySize = 256
+	// historyTTL is how long an event can be replayed, a topic without subscribers is dropped after it
+	historyTTL = 5 * time.Minute
+	// sweepInterval is how often expired events and idle topics are dropped
+	sweepInterval = time.Minute
+	// subscriberBuffer is the number of events a subscriber may lag behind before it is dropped
+	subscriberBuffer = 64
+)
+
+// Subscription receives the events of one project, Events is closed when the subscriber is
+// dropped for being too slow, the client then reconnects with its Last-Event-ID
+type Subscription struct {
+	ProjectID bson.ObjectId
+	Events    chan Event
+}
+
+type topic struct {
+	subscribers map[*Subscription]struct{}
+	history     []Event
+}
+
+var (
+	mutex  sync.Mutex
+	topics = map[bson.ObjectId]*topic{}
+	// lastID starts at the boot time so that IDs from a previous process are always older
+	lastID = uint64(time.Now().UnixNano())
+)
+
+// Start drops the expired history and the topics nobody listens to in the background
+func Start() {
+	go func() {
+		ticker := time.NewTicker(sweepInterval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			sweep()
+		}
+	}()
+}
+
+func sweep() {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	now := time.Now()
+	for projectID, t := range topics {
+		t.expire(now)
+		if len(t.subscribers) == 0 && len(t.history) == 0 {
+			delete(topics, projectID)
+		}
+	}
+}
+
+// expire drops the events older than historyTTL, the history is ordered by creation
+func (t *topic) expire(now time.Time) {
+	i := 0
+	for i < len(t.history) && now.Sub(t.history[i].CreatedAt) > historyTTL {
+		i++
+	}
+	if i > 0 {
+		t.history = append([]Event(nil), t.history[i:]...)
+	}
+}
+
+func findTopic(projectID bson.ObjectId) *topic {
+	t, ok := topics[projectID]
+	if !ok {
+		t = &topic{subscribers: map[*Subscription]struct{}{}}
+		topics[projectID] = t
+	}
+	return t
+}
+
+// Publish sends the event to every subscriber of the project connected to this server without ever
+// blocking. Servers do not share events, the jobs domain relays the progress of the jobs run by other
+// servers, document changes made through another server are not streamed.
+func Publish(projectID bson.ObjectId, eventType string, data interface{}) {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	lastID++
+	event := Event{ID: lastID, ProjectID: projectID, Type: eventType, Data: data, CreatedAt: time.Now()}
+
+	t := findTopic(projectID)
+	t.expire(event.CreatedAt)
+	t.history = append(t.history, event)
+	if len(t.history) > historySize {
+		t.history = t.history[len(t.history)-historySize:]
+	}
+
+	for subscription := range t.subscribers {
+		select {
+		case subscription.Events <- event:
+		default:
+			delete(t.subscribers, subscription)
+			close(subscription.Events)
+		}
+	}
+}
+
+// Subscribe registers a subscriber and returns the buffered events newer than lastEventID,
+// nothing is replayed when lastEventID is 0
+func Subscribe(projectID bson.ObjectId, lastEventID uint64) (*Subscription, []Event) {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	t := findTopic(projectID)
+	subscription := &Subscription{ProjectID: projectID, Events: make(chan Event, subscriberBuffer)}
+	t.subscribers[subscription] = struct{}{}
+
+	var replay []Event
+	if lastEventID != 0 {
+		for _, event := range t.history {
+			if event.ID > lastEventID {
+				replay = append(replay, event)
+			}
+		}
+	}
+
+	return subscription, replay
+}
+
+// SubscribedProjects returns the projects with a subscriber on this server
+func SubscribedProjects() []bson.ObjectId {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	var projectIDs []bson.ObjectId
+	for projectID, t := range topics {
+		if len(t.subscribers) > 0 {
+			projectIDs = append(projectIDs, projectID)
+		}
+	}
+	return projectIDs
+}
+
+func Unsubscribe(subscription *Subscription) {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	t, ok := topics[subscription.ProjectID]
+	if !ok {
+		return
+	}
+
+	if _, ok = t.subscribers[subscription]; ok {
+		delete(t.subscribers, subscription)
+		close(subscription.Events)
+	}
+	if len(t.subscribers) == 0 && len(t.history) == 0 {
+		delete(topics, subscription.ProjectID)
+	}
+}
@@ -0,0 +1,176 @@
+package invitations
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/khoa5773/go-server/src/middleware/auth"
+	"github.com/khoa5773/go-server/src/middleware/authz"
+	"github.com/khoa5773/go-server/src/shared"
+	"gopkg.in/mgo.v2/bson"
+)
+
+func ApplyRoutes(r *gin.Engine) {
+	ProjectInvitationsControllers := r.Group("projects/:projectID/invitations")
+	ProjectInvitationsControllers.GET("", auth.JWTRequired, authz.Scopes([]string{"invitations:read", "proj:invitations:read"}), findManyInvitationsController)
+	ProjectInvitationsControllers.POST("", auth.JWTRequired, authz.Scopes([]string{"invitations:create", "proj:invitations:create"}), createInvitationController)
+	ProjectInvitationsControllers.DELETE("/:invitationID", auth.JWTRequired, authz.Scopes([]string{"invitations:delete", "proj:invitations:delete"}), revokeInvitationController)
+
+	// the invitee is not a member of the project yet, so these routes are reached with the token only
+	InvitationsControllers := r.Group("invitations")
+	InvitationsControllers.GET("/:token", auth.JWTRequired, authz.Scopes([]string{"invitations:read"}), findInvitationByTokenController)
+	InvitationsControllers.POST("/:token/accept", auth.JWTRequired, authz.Scopes([]string{"invitations:update"}), acceptInvitationController)
+	InvitationsControllers.POST("/:token/decline", auth.JWTRequired, authz.Scopes([]string{"invitations:update"}), declineInvitationController)
+}
+
+func findManyInvitationsController(c *gin.Context) {
+	var findManyInvitationsDto FindManyInvitationsDto
+	var filterInvitationsDto FilterInvitationsDto
+
+	err := c.ShouldBindUri(&findManyInvitationsDto)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	err = c.ShouldBindQuery(&filterInvitationsDto)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	credentials := shared.Credentials{
+		Id:                c.MustGet("userID").(string),
+		HasPersonalScopes: c.MustGet("hasPersonalScopes").(bool),
+		HasProjectScopes:  c.MustGet("hasProjectScopes").(bool),
+		ProjectIDs:        c.MustGet("userProjectIDs").([]bson.ObjectId),
+		RequestID:         c.GetString("requestID"),
+	}
+
+	invitations, err := FindManyInvitations(findManyInvitationsDto, filterInvitationsDto, credentials)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"invitations": invitations})
+}
+
+func createInvitationController(c *gin.Context) {
+	var createOneInvitationDto CreateOneInvitationDto
+	err := c.ShouldBindJSON(&createOneInvitationDto)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	credentials := shared.Credentials{
+		Id:                c.MustGet("userID").(string),
+		HasPersonalScopes: c.MustGet("hasPersonalScopes").(bool),
+		HasProjectScopes:  c.MustGet("hasProjectScopes").(bool),
+		ProjectIDs:        c.MustGet("userProjectIDs").([]bson.ObjectId),
+		RequestID:         c.GetString("requestID"),
+	}
+	createOneInvitationDto.ProjectID = bson.ObjectIdHex(c.Param("projectID"))
+	createOneInvitationDto.ID = bson.NewObjectId()
+
+	isSuccess, err := CreateOneInvitation(&createOneInvitationDto, credentials)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"success": isSuccess, "_id": createOneInvitationDto.ID})
+}
+
+func revokeInvitationController(c *gin.Context) {
+	var revokeInvitationDto RevokeInvitationDto
+	err := c.ShouldBindUri(&revokeInvitationDto)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	credentials := shared.Credentials{
+		Id:                c.MustGet("userID").(string),
+		HasPersonalScopes: c.MustGet("hasPersonalScopes").(bool),
+		HasProjectScopes:  c.MustGet("hasProjectScopes").(bool),
+		ProjectIDs:        c.MustGet("userProjectIDs").([]bson.ObjectId),
+		RequestID:         c.GetString("requestID"),
+	}
+
+	isSuccess, err := RevokeInvitation(&revokeInvitationDto, credentials)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": isSuccess})
+}
+
+func findInvitationByTokenController(c *gin.Context) {
+	var respondInvitationDto RespondInvitationDto
+	err := c.ShouldBindUri(&respondInvitationDto)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	invitation, err := FindInvitationByToken(&respondInvitationDto)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"invitation": invitation})
+}
+
+func acceptInvitationController(c *gin.Context) {
+	var respondInvitationDto RespondInvitationDto
+	err := c.ShouldBindUri(&respondInvitationDto)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	credentials := shared.Credentials{
+		Id:                c.MustGet("userID").(string),
+		HasPersonalScopes: c.MustGet("hasPersonalScopes").(bool),
+		HasProjectScopes:  c.MustGet("hasProjectScopes").(bool),
+		ProjectIDs:        c.MustGet("userProjectIDs").([]bson.ObjectId),
+		RequestID:         c.GetString("requestID"),
+	}
+
+	isSuccess, err := AcceptInvitation(&respondInvitationDto, credentials)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": isSuccess})
+}
+
+func declineInvitationController(c *gin.Context) {
+	var respondInvitationDto RespondInvitationDto
+	err := c.ShouldBindUri(&respondInvitationDto)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	credentials := shared.Credentials{
+		Id:                c.MustGet("userID").(string),
+		HasPersonalScopes: c.MustGet("hasPersonalScopes").(bool),
+		HasProjectScopes:  c.MustGet("hasProjectScopes").(bool),
+		ProjectIDs:        c.MustGet("userProjectIDs").([]bson.ObjectId),
+		RequestID:         c.GetString("requestID"),
+	}
+
+	isSuccess, err := DeclineInvitation(&respondInvitationDto, credentials)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": isSuccess})
+}
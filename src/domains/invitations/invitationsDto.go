@@ -0,0 +1,43 @@
+package invitations
+
+import (
+	"time"
+
+	"gopkg.in/mgo.v2/bson"
+)
+
+type FindManyInvitationsDto struct {
+	ProjectID bson.ObjectId `json:"projectID,omitempty" bson:"projectID,omitempty" uri:"projectID" binding:"required,mongoid"`
+}
+
+type FilterInvitationsDto struct {
+	Status InvitationStatus `form:"status" binding:"omitempty,oneof=PENDING ACCEPTED DECLINED REVOKED EXPIRED"`
+}
+
+type FindOneInvitationDto struct {
+	ID        bson.ObjectId `json:"_id,omitempty" bson:"_id,omitempty" uri:"invitationID" binding:"required,mongoid"`
+	ProjectID bson.ObjectId `json:"projectID,omitempty" bson:"projectID,omitempty" uri:"projectID" binding:"required,mongoid"`
+}
+
+type CreateOneInvitationDto struct {
+	ID            bson.ObjectId    `bson:"_id"`
+	Email         string           `json:"email" bson:"email" binding:"required,email"`
+	Role          InvitationRole   `json:"role" bson:"role" binding:"required,oneof=MEMBER MANAGER"`
+	ExpiresInDays int              `json:"expiresInDays" bson:"-" binding:"omitempty,min=1,max=30"`
+	TokenHash     string           `bson:"tokenHash"`
+	Status        InvitationStatus `bson:"status"`
+	ExpiresAt     time.Time        `bson:"expiresAt"`
+	ProjectID     bson.ObjectId    `bson:"projectID"`
+	CreatedBy     string           `bson:"createdBy"`
+	CreatedAt     time.Time        `bson:"createdAt"`
+	UpdatedAt     time.Time        `bson:"updatedAt"`
+}
+
+type RespondInvitationDto struct {
+	Token string `uri:"token" binding:"required"`
+}
+
+type RevokeInvitationDto struct {
+	ID        bson.ObjectId `json:"_id,omitempty" bson:"_id,omitempty" uri:"invitationID" binding:"required,mongoid"`
+	ProjectID bson.ObjectId `json:"projectID,omitempty" bson:"projectID,omitempty" uri:"projectID" binding:"required,mongoid"`
+}
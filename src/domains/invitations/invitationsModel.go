@@ -0,0 +1,41 @@
+package invitations
+
+import (
+	"time"
+
+	"gopkg.in/mgo.v2/bson"
+)
+
+type InvitationStatus string
+
+// EXPIRED is never stored, it filters PENDING invitations past their expiry date
+const (
+	PENDING  InvitationStatus = "PENDING"
+	ACCEPTED InvitationStatus = "ACCEPTED"
+	DECLINED InvitationStatus = "DECLINED"
+	REVOKED  InvitationStatus = "REVOKED"
+	EXPIRED  InvitationStatus = "EXPIRED"
+)
+
+type InvitationRole string
+
+const (
+	MEMBER  InvitationRole = "MEMBER"
+	MANAGER InvitationRole = "MANAGER"
+)
+
+// Invitation model, only the SHA-256 hash of the token is stored
+type Invitation struct {
+	ID          bson.ObjectId    `json:"_id" bson:"_id"`
+	ProjectID   bson.ObjectId    `json:"projectID" bson:"projectID"`
+	Email       string           `json:"email" bson:"email"`
+	Role        InvitationRole   `json:"role" bson:"role"`
+	TokenHash   string           `json:"-" bson:"tokenHash"`
+	Status      InvitationStatus `json:"status" bson:"status"`
+	ExpiresAt   time.Time        `json:"expiresAt" bson:"expiresAt"`
+	RespondedBy string           `json:"respondedBy" bson:"respondedBy"`
+	RespondedAt time.Time        `json:"respondedAt" bson:"respondedAt,omitempty"`
+	CreatedBy   string           `json:"createdBy" bson:"createdBy"`
+	CreatedAt   time.Time        `json:"createdAt" bson:"createdAt"`
+	UpdatedAt   time.Time        `json:"updatedAt" bson:"updatedAt"`
+}
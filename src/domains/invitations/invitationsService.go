@@ -0,0 +1,358 @@
+package invitations
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/fatih/structs"
+	"github.com/khoa5773/go-server/src/configs"
+	"github.com/khoa5773/go-server/src/domains/emails"
+	"github.com/khoa5773/go-server/src/domains/projects"
+	"github.com/khoa5773/go-server/src/domains/roles"
+	"github.com/khoa5773/go-server/src/domains/users"
+	"github.com/khoa5773/go-server/src/shared"
+	"github.com/mitchellh/mapstructure"
+	"gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+)
+
+const defaultExpiresInDays = 7
+
+func FindManyInvitations(findManyInvitationsDto FindManyInvitationsDto, filterInvitationsDto FilterInvitationsDto, credentials shared.Credentials) (shared.Result, error) {
+	var invitations []map[string]interface{}
+	InvitationsModel := shared.MongoSession.C("invitations")
+
+	query := bson.M{"projectID": findManyInvitationsDto.ProjectID}
+	switch filterInvitationsDto.Status {
+	case "", PENDING:
+		query["status"] = PENDING
+		query["expiresAt"] = bson.M{"$gt": time.Now()}
+	case EXPIRED:
+		query["status"] = PENDING
+		query["expiresAt"] = bson.M{"$lte": time.Now()}
+	default:
+		query["status"] = filterInvitationsDto.Status
+	}
+
+	err := InvitationsModel.Find(query).Select(bson.M{"tokenHash": 0}).Sort("-createdAt").All(&invitations)
+	if err != nil {
+		return shared.Result{}, err
+	}
+
+	data, err := shared.ValidateAccessToList(invitations, credentials)
+	if err != nil {
+		return shared.Result{}, err
+	}
+
+	return *data, nil
+}
+
+// CreateOneInvitation stores a pending invitation and emails its token to the invitee, the token is
+// never stored in clear
+func CreateOneInvitation(createOneInvitationDto *CreateOneInvitationDto, credentials shared.Credentials) (bool, error) {
+	createOneInvitationDto.Email = strings.ToLower(strings.TrimSpace(createOneInvitationDto.Email))
+	createOneInvitationDto.CreatedBy = credentials.Id
+	createOneInvitationDto.CreatedAt = time.Now()
+	createOneInvitationDto.UpdatedAt = time.Now()
+
+	invitation := &Invitation{}
+	err := mapstructure.Decode(structs.Map(createOneInvitationDto), invitation)
+	if err != nil {
+		return false, err
+	}
+
+	_, err = shared.ValidateAccessToSingle(structs.Map(invitation), credentials)
+	if err != nil {
+		return false, err
+	}
+
+	user, err := users.FindUserByEmail(createOneInvitationDto.Email)
+	if err != nil && err != mgo.ErrNotFound {
+		return false, err
+	}
+	if user != nil {
+		_, err = projects.CheckValidUsers(&projects.FindOneProjectDto{ID: createOneInvitationDto.ProjectID}, []*users.User{user})
+		if err != nil {
+			return false, err
+		}
+	}
+
+	InvitationsModel := shared.MongoSession.C("invitations")
+	pending, err := InvitationsModel.Find(bson.M{
+		"projectID": createOneInvitationDto.ProjectID,
+		"email":     createOneInvitationDto.Email,
+		"status":    PENDING,
+		"expiresAt": bson.M{"$gt": time.Now()},
+	}).Count()
+	if err != nil {
+		return false, err
+	}
+	if pending > 0 {
+		return false, errors.New("email already has a pending invitation to this project")
+	}
+
+	token, tokenHash, err := newToken()
+	if err != nil {
+		return false, err
+	}
+
+	expiresInDays := createOneInvitationDto.ExpiresInDays
+	if expiresInDays == 0 {
+		expiresInDays = defaultExpiresInDays
+	}
+
+	createOneInvitationDto.TokenHash = tokenHash
+	createOneInvitationDto.Status = PENDING
+	createOneInvitationDto.ExpiresAt = createOneInvitationDto.CreatedAt.AddDate(0, 0, expiresInDays)
+
+	err = InvitationsModel.Insert(createOneInvitationDto)
+	if err != nil {
+		return false, err
+	}
+
+	err = sendInvitation(createOneInvitationDto, token, credentials)
+	if err != nil {
+		_ = InvitationsModel.RemoveId(createOneInvitationDto.ID)
+		return false, err
+	}
+
+	return true, nil
+}
+
+func RevokeInvitation(revokeInvitationDto *RevokeInvitationDto, credentials shared.Credentials) (bool, error) {
+	var invitation *Invitation
+	InvitationsModel := shared.MongoSession.C("invitations")
+	err := InvitationsModel.Find(revokeInvitationDto).One(&invitation)
+	if err != nil {
+		return false, err
+	}
+
+	_, err = shared.ValidateAccessToSingle(structs.Map(*invitation), credentials)
+	if err != nil {
+		return false, err
+	}
+
+	err = InvitationsModel.Update(
+		bson.M{"_id": invitation.ID, "status": PENDING},
+		bson.M{"$set": bson.M{"status": REVOKED, "respondedBy": credentials.Id, "respondedAt": time.Now(), "updatedAt": time.Now()}},
+	)
+	if err == mgo.ErrNotFound {
+		return false, errors.New("invitation is no longer pending")
+	}
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// FindInvitationByToken lets the invitee see the invitation before answering it
+func FindInvitationByToken(respondInvitationDto *RespondInvitationDto) (Invitation, error) {
+	var invitation Invitation
+	InvitationsModel := shared.MongoSession.C("invitations")
+	err := InvitationsModel.Find(bson.M{"tokenHash": hashToken(respondInvitationDto.Token)}).One(&invitation)
+	if err == mgo.ErrNotFound {
+		return Invitation{}, errors.New("invalid invitation")
+	}
+	if err != nil {
+		return Invitation{}, err
+	}
+
+	if invitation.Status == PENDING && !invitation.ExpiresAt.After(time.Now()) {
+		invitation.Status = EXPIRED
+	}
+
+	return invitation, nil
+}
+
+// AcceptInvitation adds the current user to the project with the invited role, the invitation is
+// claimed first so that a token can only be used once. A failed join reopens the invitation only
+// once the membership is undone, a half-joined user keeps the claimed invitation.
+func AcceptInvitation(respondInvitationDto *RespondInvitationDto, credentials shared.Credentials) (bool, error) {
+	invitation, err := claimInvitation(respondInvitationDto, ACCEPTED, credentials)
+	if err != nil {
+		return false, err
+	}
+
+	undone, err := joinProject(invitation, credentials)
+	if err == nil {
+		return true, nil
+	}
+
+	if undone {
+		InvitationsModel := shared.MongoSession.C("invitations")
+		_ = InvitationsModel.UpdateId(invitation.ID, bson.M{
+			"$set":   bson.M{"status": PENDING, "respondedBy": "", "updatedAt": time.Now()},
+			"$unset": bson.M{"respondedAt": ""},
+		})
+	}
+	return false, err
+}
+
+func DeclineInvitation(respondInvitationDto *RespondInvitationDto, credentials shared.Credentials) (bool, error) {
+	_, err := claimInvitation(respondInvitationDto, DECLINED, credentials)
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+func claimInvitation(respondInvitationDto *RespondInvitationDto, status InvitationStatus, credentials shared.Credentials) (*Invitation, error) {
+	invitation, err := FindInvitationByToken(respondInvitationDto)
+	if err != nil {
+		return nil, err
+	}
+	if invitation.Status != PENDING {
+		return nil, errors.New("invitation is " + strings.ToLower(string(invitation.Status)))
+	}
+
+	credentials.IsAdmin = true
+	user, err := users.FindOneUser(&users.FindOneUserDto{ID: credentials.Id}, credentials)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.EqualFold(strings.TrimSpace(user.Email), invitation.Email) {
+		return nil, errors.New("invitation was sent to another email address")
+	}
+
+	InvitationsModel := shared.MongoSession.C("invitations")
+	now := time.Now()
+	err = InvitationsModel.Update(
+		bson.M{"_id": invitation.ID, "status": PENDING, "expiresAt": bson.M{"$gt": now}},
+		bson.M{"$set": bson.M{"status": status, "respondedBy": credentials.Id, "respondedAt": now, "updatedAt": now}},
+	)
+	if err == mgo.ErrNotFound {
+		return nil, errors.New("invitation is no longer pending")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &invitation, nil
+}
+
+// joinProject adds the user to the project with the invited role. When a step fails the steps already
+// applied are undone and the returned bool tells whether the project and the user are as before.
+func joinProject(invitation *Invitation, credentials shared.Credentials) (bool, error) {
+	credentials.IsAdmin = true
+	findOneProjectDto := &projects.FindOneProjectDto{ID: invitation.ProjectID}
+
+	user, err := users.FindOneUser(&users.FindOneUserDto{ID: credentials.Id}, credentials)
+	if err != nil {
+		return true, err
+	}
+
+	_, err = projects.CheckValidUsers(findOneProjectDto, []*users.User{user})
+	if err != nil {
+		return true, err
+	}
+
+	err = addMembership(invitation, user.ID, credentials)
+	if err == nil {
+		return false, nil
+	}
+
+	// the user was in none of the project lists before, so every trace of the user is removed
+	undoErr := removeMembership(invitation, user.ID, credentials)
+	if undoErr != nil {
+		log.Printf("invitations: user %s is left half-joined to project %s: %v", user.ID, invitation.ProjectID.Hex(), undoErr)
+		return false, err
+	}
+	return true, err
+}
+
+func addMembership(invitation *Invitation, userID string, credentials shared.Credentials) error {
+	findOneProjectDto := &projects.FindOneProjectDto{ID: invitation.ProjectID}
+
+	_, err := projects.AddProjectMembers(findOneProjectDto, &projects.AddProjectMembersDto{MemberIDs: []string{userID}}, credentials)
+	if err != nil {
+		return err
+	}
+
+	_, err = users.AddProjectForUsers([]string{userID}, invitation.ProjectID, roles.MEMBER, credentials)
+	if err != nil {
+		return err
+	}
+
+	if invitation.Role != MANAGER {
+		return nil
+	}
+
+	_, err = projects.AddProjectManagers(findOneProjectDto, &projects.AddProjectManagersDto{ManagerIDs: []string{userID}}, credentials)
+	if err != nil {
+		return err
+	}
+
+	_, err = users.AddProjectForUsers([]string{userID}, invitation.ProjectID, roles.MANAGER, credentials)
+	return err
+}
+
+func removeMembership(invitation *Invitation, userID string, credentials shared.Credentials) error {
+	findOneProjectDto := &projects.FindOneProjectDto{ID: invitation.ProjectID}
+
+	_, err := projects.RemoveProjectMembers(findOneProjectDto, &projects.RemoveProjectMembersDto{MemberIDs: []string{userID}}, credentials)
+	if err != nil {
+		return err
+	}
+
+	if invitation.Role == MANAGER {
+		_, err = projects.RemoveProjectManagers(findOneProjectDto, &projects.RemoveProjectManagersDto{ManagerIDs: []string{userID}}, credentials)
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err = users.RemoveProjectFromUsers([]string{userID}, invitation.ProjectID, false, credentials)
+	return err
+}
+
+// sendInvitation queues the email carrying the token, the invitee opens it in the web app
+func sendInvitation(createOneInvitationDto *CreateOneInvitationDto, token string, credentials shared.Credentials) error {
+	var project projects.Project
+	ProjectsModel := shared.MongoSession.C("projects")
+	err := ProjectsModel.FindId(createOneInvitationDto.ProjectID).One(&project)
+	if err != nil {
+		return err
+	}
+
+	credentials.IsAdmin = true
+	inviter, err := users.FindOneUser(&users.FindOneUserDto{ID: credentials.Id}, credentials)
+	if err != nil {
+		return err
+	}
+	inviterName := inviter.Name
+	if inviterName == "" {
+		inviterName = inviter.Email
+	}
+
+	return emails.Queue(createOneInvitationDto.Email, emails.INVITATION, map[string]interface{}{
+		"InviterName": inviterName,
+		"ProjectName": project.Name,
+		"Role":        strings.ToLower(string(createOneInvitationDto.Role)),
+		"URL":         strings.TrimRight(configs.ConfigsService.AppURL, "/") + "/invitations/" + token,
+		"ExpiresAt":   createOneInvitationDto.ExpiresAt,
+	})
+}
+
+func newToken() (string, string, error) {
+	buffer := make([]byte, 32)
+	_, err := rand.Read(buffer)
+	if err != nil {
+		return "", "", err
+	}
+
+	token := base64.RawURLEncoding.EncodeToString(buffer)
+	return token, hashToken(token), nil
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,130 @@
+package jobs
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/khoa5773/go-server/src/middleware/auth"
+	"github.com/khoa5773/go-server/src/middleware/authz"
+	"github.com/khoa5773/go-server/src/shared"
+	"gopkg.in/mgo.v2/bson"
+)
+
+// Jobs run prediction work, so they share the predictions scopes. Their creator can cancel them with the
+// read scopes, the jobs of others need the update scopes.
+func ApplyRoutes(r *gin.Engine) {
+	JobsControllers := r.Group("projects/:projectID/jobs")
+	JobsControllers.GET("", auth.JWTRequired, authz.Scopes([]string{"predictions:read", "proj:predictions:read"}), findManyJobsController)
+	JobsControllers.GET("/:jobID", auth.JWTRequired, authz.Scopes([]string{"predictions:read", "proj:predictions:read"}), findOneJobController)
+	JobsControllers.POST("/:jobID/cancel", auth.JWTRequired, authz.Scopes([]string{"predictions:read", "proj:predictions:read"}), cancelJobController)
+	JobsControllers.DELETE("/:jobID", auth.JWTRequired, authz.Scopes([]string{"predictions:delete", "proj:predictions:delete"}), deleteJobController)
+}
+
+func findManyJobsController(c *gin.Context) {
+	var findManyJobsDto FindManyJobsDto
+	var filterJobsDto FilterJobsDto
+
+	err := c.ShouldBindUri(&findManyJobsDto)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	err = c.ShouldBindQuery(&filterJobsDto)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	credentials := shared.Credentials{
+		Id:                c.MustGet("userID").(string),
+		HasPersonalScopes: c.MustGet("hasPersonalScopes").(bool),
+		HasProjectScopes:  c.MustGet("hasProjectScopes").(bool),
+		ProjectIDs:        c.MustGet("userProjectIDs").([]bson.ObjectId),
+		RequestID:         c.GetString("requestID"),
+	}
+
+	jobs, err := FindManyJobs(findManyJobsDto, filterJobsDto, credentials)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"jobs": jobs})
+}
+
+func findOneJobController(c *gin.Context) {
+	var findOneJobDto FindOneJobDto
+	err := c.ShouldBindUri(&findOneJobDto)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	credentials := shared.Credentials{
+		Id:                c.MustGet("userID").(string),
+		HasPersonalScopes: c.MustGet("hasPersonalScopes").(bool),
+		HasProjectScopes:  c.MustGet("hasProjectScopes").(bool),
+		ProjectIDs:        c.MustGet("userProjectIDs").([]bson.ObjectId),
+		RequestID:         c.GetString("requestID"),
+	}
+
+	job, err := FindOneJob(&findOneJobDto, credentials)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"job": job})
+}
+
+func cancelJobController(c *gin.Context) {
+	var findOneJobDto FindOneJobDto
+	err := c.ShouldBindUri(&findOneJobDto)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	credentials := shared.Credentials{
+		Id:                c.MustGet("userID").(string),
+		HasPersonalScopes: c.MustGet("hasPersonalScopes").(bool),
+		HasProjectScopes:  c.MustGet("hasProjectScopes").(bool),
+		ProjectIDs:        c.MustGet("userProjectIDs").([]bson.ObjectId),
+		RequestID:         c.GetString("requestID"),
+	}
+
+	canCancelAny := authz.HasProjectScope(c, "proj:predictions:update")
+	isSuccess, err := CancelJob(&findOneJobDto, canCancelAny, credentials)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": isSuccess})
+}
+
+func deleteJobController(c *gin.Context) {
+	var deleteJobDto DeleteJobDto
+	err := c.ShouldBindUri(&deleteJobDto)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	credentials := shared.Credentials{
+		Id:                c.MustGet("userID").(string),
+		HasPersonalScopes: c.MustGet("hasPersonalScopes").(bool),
+		HasProjectScopes:  c.MustGet("hasProjectScopes").(bool),
+		ProjectIDs:        c.MustGet("userProjectIDs").([]bson.ObjectId),
+		RequestID:         c.GetString("requestID"),
+	}
+
+	isSuccess, err := DeleteJob(&deleteJobDto, credentials)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": isSuccess})
+}
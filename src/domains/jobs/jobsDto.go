@@ -0,0 +1,41 @@
+package jobs
+
+import (
+	"time"
+
+	"gopkg.in/mgo.v2/bson"
+)
+
+type FindManyJobsDto struct {
+	ProjectID bson.ObjectId `json:"projectID,omitempty" bson:"projectID,omitempty" uri:"projectID" binding:"required,mongoid"`
+}
+
+type FilterJobsDto struct {
+	Type   string    `json:"type,omitempty" bson:"type,omitempty" form:"type"`
+	Status JobStatus `json:"status,omitempty" bson:"status,omitempty" form:"status" binding:"omitempty,oneof=QUEUED RUNNING SUCCEEDED FAILED CANCELLED"`
+}
+
+type FindOneJobDto struct {
+	ID        bson.ObjectId `json:"_id,omitempty" bson:"_id,omitempty" uri:"jobID" binding:"required,mongoid"`
+	ProjectID bson.ObjectId `json:"projectID,omitempty" bson:"projectID,omitempty" uri:"projectID" binding:"required,mongoid"`
+}
+
+type CreateOneJobDto struct {
+	ID          bson.ObjectId          `bson:"_id"`
+	ProjectID   bson.ObjectId          `bson:"projectID"`
+	Type        string                 `bson:"type"`
+	Payload     map[string]interface{} `bson:"payload"`
+	Status      JobStatus              `bson:"status"`
+	Progress    int                    `bson:"progress"`
+	Attempts    int                    `bson:"attempts"`
+	MaxAttempts int                    `bson:"maxAttempts"`
+	RunAfter    time.Time              `bson:"runAfter"`
+	CreatedBy   string                 `bson:"createdBy"`
+	CreatedAt   time.Time              `bson:"createdAt"`
+	UpdatedAt   time.Time              `bson:"updatedAt"`
+}
+
+type DeleteJobDto struct {
+	ID        bson.ObjectId `json:"_id,omitempty" bson:"_id,omitempty" uri:"jobID" binding:"required,mongoid"`
+	ProjectID bson.ObjectId `json:"projectID,omitempty" bson:"projectID,omitempty" uri:"projectID" binding:"required,mongoid"`
+}
@@ -0,0 +1,45 @@
+package jobs
+
+import (
+	"time"
+
+	"gopkg.in/mgo.v2/bson"
+)
+
+type JobStatus string
+
+const (
+	QUEUED    JobStatus = "QUEUED"
+	RUNNING   JobStatus = "RUNNING"
+	SUCCEEDED JobStatus = "SUCCEEDED"
+	FAILED    JobStatus = "FAILED"
+	CANCELLED JobStatus = "CANCELLED"
+)
+
+// Job model
+type Job struct {
+	ID          bson.ObjectId          `json:"_id" bson:"_id"`
+	ProjectID   bson.ObjectId          `json:"projectID" bson:"projectID"`
+	Type        string                 `json:"type" bson:"type"`
+	Payload     map[string]interface{} `json:"payload" bson:"payload"`
+	Status      JobStatus              `json:"status" bson:"status"`
+	Progress    int                    `json:"progress" bson:"progress"`
+	Attempts    int                    `json:"attempts" bson:"attempts"`
+	MaxAttempts int                    `json:"maxAttempts" bson:"maxAttempts"`
+	RunAfter    time.Time              `json:"runAfter" bson:"runAfter"`
+	Error       string                 `json:"error" bson:"error"`
+	Result      map[string]interface{} `json:"result" bson:"result"`
+	CreatedBy   string                 `json:"createdBy" bson:"createdBy"`
+	CreatedAt   time.Time              `json:"createdAt" bson:"createdAt"`
+	UpdatedAt   time.Time              `json:"updatedAt" bson:"updatedAt"`
+	StartedAt   time.Time              `json:"startedAt" bson:"startedAt,omitempty"`
+	FinishedAt  time.Time              `json:"finishedAt" bson:"finishedAt,omitempty"`
+	// Owner is the server running the job, it keeps the job while it renews LeaseExpiresAt
+	Owner           string    `json:"owner,omitempty" bson:"owner,omitempty"`
+	LeaseExpiresAt  time.Time `json:"leaseExpiresAt" bson:"leaseExpiresAt,omitempty"`
+	CancelRequested bool      `json:"cancelRequested" bson:"cancelRequested"`
+}
+
+func (j *Job) IsFinished() bool {
+	return j.Status == SUCCEEDED || j.Status == FAILED || j.Status == CANCELLED
+}
@@ -0,0 +1,477 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/khoa5773/go-server/src/domains/events"
+	"github.com/khoa5773/go-server/src/shared"
+	"gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+)
+
+const (
+	// pollInterval bounds how late a retried job is picked up
+	pollInterval = 5 * time.Second
+	// retryDelay is multiplied by the number of attempts already made
+	retryDelay = 30 * time.Second
+	// heartbeatInterval is how often leases are renewed and cancellations picked up
+	heartbeatInterval = 10 * time.Second
+	// leaseDuration is how long a RUNNING job stays with a server that stopped renewing its lease
+	leaseDuration = time.Minute
+)
+
+// Handler runs the jobs of one type. Run reports progress as a percentage and its result is stored
+// on the job. Finish is called once with the final job, whatever the way it finished, so that the
+// handler can mirror the status on its own documents.
+type Handler interface {
+	Run(ctx context.Context, job *Job, progress func(percentage int)) (map[string]interface{}, error)
+	Finish(job *Job) error
+}
+
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string {
+	return e.err.Error()
+}
+
+// Permanent marks an error that would happen again on retry, e.g. an invalid input file
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &permanentError{err: err}
+}
+
+var (
+	handlersMutex sync.RWMutex
+	handlers      = map[string]Handler{}
+
+	runningMutex sync.Mutex
+	running      = map[bson.ObjectId]context.CancelFunc{}
+
+	wake = make(chan struct{}, 1)
+
+	// instanceID tells apart the servers sharing the jobs collection
+	instanceID = newInstanceID()
+)
+
+func newInstanceID() string {
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "server"
+	}
+	return fmt.Sprintf("%s-%d-%s", hostname, os.Getpid(), bson.NewObjectId().Hex())
+}
+
+// RegisterHandler is called from the init function of the domain owning the job type
+func RegisterHandler(jobType string, handler Handler) {
+	handlersMutex.Lock()
+	defer handlersMutex.Unlock()
+	handlers[jobType] = handler
+}
+
+func findHandler(jobType string) (Handler, bool) {
+	handlersMutex.RLock()
+	defer handlersMutex.RUnlock()
+	handler, ok := handlers[jobType]
+	return handler, ok
+}
+
+func notify() {
+	select {
+	case wake <- struct{}{}:
+	default:
+	}
+}
+
+// Start requeues the jobs whose server stopped renewing their lease, then starts the worker pool and
+// the heartbeat of this server
+func Start(workers int) {
+	if workers < 1 {
+		workers = 1
+	}
+
+	err := requeueExpired()
+	if err != nil {
+		log.Println("jobs: recovery failed:", err)
+	}
+
+	for i := 0; i < workers; i++ {
+		go work()
+	}
+	go heartbeat()
+	notify()
+}
+
+// expiredLease matches RUNNING jobs whose lease ran out, jobs started before leases existed have none
+func expiredLease(now time.Time) bson.M {
+	return bson.M{"status": RUNNING, "$or": []bson.M{
+		{"leaseExpiresAt": bson.M{"$lt": now}},
+		{"leaseExpiresAt": bson.M{"$exists": false}},
+	}}
+}
+
+// requeueExpired puts the jobs of stopped servers back in the queue. A job is failed when no attempt is
+// left and cancelled when a cancellation was requested. Each job is updated only if it still has the
+// expired lease it was found with, so servers recovering at the same time do not both take it.
+func requeueExpired() error {
+	var jobs []Job
+	now := time.Now()
+	JobsModel := shared.MongoSession.C("jobs")
+	err := JobsModel.Find(expiredLease(now)).All(&jobs)
+	if err != nil {
+		return err
+	}
+
+	for i := range jobs {
+		job := &jobs[i]
+		selector := expiredLease(now)
+		selector["_id"] = job.ID
+		selector["attempts"] = job.Attempts
+
+		update := bson.M{"progress": 0, "updatedAt": now}
+		switch {
+		case job.CancelRequested:
+			job.Status = CANCELLED
+			job.Error = ""
+		case job.Attempts < job.MaxAttempts:
+			job.Status = QUEUED
+			job.RunAfter = now
+			update["runAfter"] = now
+		default:
+			job.Status = FAILED
+			job.Error = "interrupted by a server restart"
+		}
+		update["status"] = job.Status
+		update["error"] = job.Error
+		if job.IsFinished() {
+			job.FinishedAt = now
+			update["finishedAt"] = now
+		}
+
+		err = JobsModel.Update(selector, bson.M{"$set": update})
+		if err == mgo.ErrNotFound {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		if job.IsFinished() {
+			finish(job)
+		}
+	}
+
+	return nil
+}
+
+// heartbeat renews the leases of the jobs running on this server, stops the ones cancelled from any
+// server, requeues the jobs of servers that stopped and relays the progress of the other servers
+func heartbeat() {
+	ticker := time.NewTicker(heartbeatInterval)
+	defer ticker.Stop()
+
+	relayed := time.Now()
+	for now := range ticker.C {
+		err := relayJobs(relayed, now)
+		if err != nil {
+			log.Println("jobs: relay failed:", err)
+		} else {
+			relayed = now
+		}
+
+		err = renewLeases()
+		if err != nil {
+			log.Println("jobs: lease renewal failed:", err)
+		}
+
+		err = requeueExpired()
+		if err != nil {
+			log.Println("jobs: recovery failed:", err)
+		}
+		notify()
+	}
+}
+
+// relayJobs publishes the jobs changed by other servers between since and until to the subscribers of
+// this server, so that a stream sees the progress of every job of its project once per heartbeat.
+// Subscribers may get the same state twice.
+func relayJobs(since, until time.Time) error {
+	projectIDs := events.SubscribedProjects()
+	if len(projectIDs) == 0 {
+		return nil
+	}
+
+	var jobs []Job
+	JobsModel := shared.MongoSession.C("jobs")
+	err := JobsModel.Find(bson.M{
+		"projectID": bson.M{"$in": projectIDs},
+		"owner":     bson.M{"$ne": instanceID},
+		"updatedAt": bson.M{"$gt": since, "$lte": until},
+	}).Sort("updatedAt").All(&jobs)
+	if err != nil {
+		return err
+	}
+
+	for i := range jobs {
+		eventType := events.JOB_PROGRESS
+		if jobs[i].IsFinished() {
+			eventType = events.JOB_FINISHED
+		}
+		publish(eventType, &jobs[i])
+	}
+	return nil
+}
+
+func renewLeases() error {
+	jobIDs := runningJobIDs()
+	if len(jobIDs) == 0 {
+		return nil
+	}
+
+	JobsModel := shared.MongoSession.C("jobs")
+	_, err := JobsModel.UpdateAll(
+		bson.M{"_id": bson.M{"$in": jobIDs}, "status": RUNNING, "owner": instanceID},
+		bson.M{"$set": bson.M{"leaseExpiresAt": time.Now().Add(leaseDuration)}},
+	)
+	if err != nil {
+		return err
+	}
+
+	// a job whose lease was lost is stopped as well, its outcome could not be stored anyway
+	var stopped []Job
+	err = JobsModel.Find(bson.M{"_id": bson.M{"$in": jobIDs}, "$or": []bson.M{
+		{"cancelRequested": true},
+		{"status": bson.M{"$ne": RUNNING}},
+		{"owner": bson.M{"$ne": instanceID}},
+	}}).Select(bson.M{"_id": 1}).All(&stopped)
+	if err != nil {
+		return err
+	}
+	for _, job := range stopped {
+		cancelRunning(job.ID)
+	}
+
+	return nil
+}
+
+func work() {
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	for {
+		job, err := claim()
+		if err != nil {
+			log.Println("jobs: claim failed:", err)
+		}
+		if job != nil {
+			// another job may be waiting behind this one
+			notify()
+			publish(events.JOB_PROGRESS, job)
+			run(job)
+			continue
+		}
+
+		select {
+		case <-wake:
+		case <-ticker.C:
+		}
+	}
+}
+
+// claim atomically moves the oldest runnable job to RUNNING
+func claim() (*Job, error) {
+	var job Job
+	now := time.Now()
+	JobsModel := shared.MongoSession.C("jobs")
+	_, err := JobsModel.Find(bson.M{"status": QUEUED, "runAfter": bson.M{"$lte": now}}).Sort("createdAt").Apply(mgo.Change{
+		Update: bson.M{
+			"$set": bson.M{
+				"status":          RUNNING,
+				"progress":        0,
+				"owner":           instanceID,
+				"leaseExpiresAt":  now.Add(leaseDuration),
+				"cancelRequested": false,
+				"startedAt":       now,
+				"updatedAt":       now,
+			},
+			"$inc": bson.M{"attempts": 1},
+		},
+		ReturnNew: true,
+	}, &job)
+	if err == mgo.ErrNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &job, nil
+}
+
+func run(job *Job) {
+	JobsModel := shared.MongoSession.C("jobs")
+
+	handler, ok := findHandler(job.Type)
+	if !ok {
+		complete(job, nil, Permanent(fmt.Errorf("no handler for job type %s", job.Type)), false)
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	runningMutex.Lock()
+	running[job.ID] = cancel
+	runningMutex.Unlock()
+	defer func() {
+		runningMutex.Lock()
+		delete(running, job.ID)
+		runningMutex.Unlock()
+		cancel()
+	}()
+
+	progress := func(percentage int) {
+		if percentage < 0 {
+			percentage = 0
+		}
+		if percentage > 100 {
+			percentage = 100
+		}
+		job.Progress = percentage
+		job.UpdatedAt = time.Now()
+		err := JobsModel.Update(
+			bson.M{"_id": job.ID, "status": RUNNING, "owner": instanceID},
+			bson.M{"$set": bson.M{"progress": percentage, "updatedAt": job.UpdatedAt}},
+		)
+		if err != nil && err != mgo.ErrNotFound {
+			log.Println("jobs: progress update failed:", err)
+		}
+		publish(events.JOB_PROGRESS, job)
+	}
+
+	result, err := safeRun(ctx, handler, job, progress)
+	complete(job, result, err, ctx.Err() == context.Canceled)
+}
+
+func safeRun(ctx context.Context, handler Handler, job *Job, progress func(int)) (result map[string]interface{}, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("job panicked: %v", r)
+		}
+	}()
+
+	return handler.Run(ctx, job, progress)
+}
+
+// complete stores the outcome of an attempt, a failed attempt is queued again after a delay
+// unless it was cancelled, the error is permanent or no attempt is left. Nothing is stored when
+// the lease of the job was lost to another server.
+func complete(job *Job, result map[string]interface{}, err error, cancelled bool) {
+	now := time.Now()
+	job.UpdatedAt = now
+	update := bson.M{"updatedAt": now}
+
+	var permanent *permanentError
+	switch {
+	case err == nil:
+		job.Status = SUCCEEDED
+		job.Progress = 100
+		job.Error = ""
+		job.Result = result
+		update["result"] = result
+	case cancelled:
+		job.Status = CANCELLED
+		job.Error = ""
+	case job.Attempts < job.MaxAttempts && !errors.As(err, &permanent):
+		job.Status = QUEUED
+		job.Progress = 0
+		job.Error = err.Error()
+		job.RunAfter = now.Add(retryDelay * time.Duration(job.Attempts))
+		update["runAfter"] = job.RunAfter
+	default:
+		job.Status = FAILED
+		job.Error = err.Error()
+	}
+
+	update["status"] = job.Status
+	update["progress"] = job.Progress
+	update["error"] = job.Error
+	if job.IsFinished() {
+		job.FinishedAt = now
+		update["finishedAt"] = now
+	}
+
+	JobsModel := shared.MongoSession.C("jobs")
+	updateErr := JobsModel.Update(bson.M{"_id": job.ID, "status": RUNNING, "owner": instanceID}, bson.M{"$set": update})
+	if updateErr == mgo.ErrNotFound {
+		log.Printf("jobs: job %s is no longer held by this server, its outcome is dropped", job.ID.Hex())
+		return
+	}
+	if updateErr != nil {
+		log.Println("jobs: status update failed:", updateErr)
+		return
+	}
+
+	if job.IsFinished() {
+		finish(job)
+		return
+	}
+	publish(events.JOB_PROGRESS, job)
+}
+
+func runningJobIDs() []bson.ObjectId {
+	runningMutex.Lock()
+	defer runningMutex.Unlock()
+
+	jobIDs := make([]bson.ObjectId, 0, len(running))
+	for jobID := range running {
+		jobIDs = append(jobIDs, jobID)
+	}
+	return jobIDs
+}
+
+func cancelRunning(jobID bson.ObjectId) bool {
+	runningMutex.Lock()
+	defer runningMutex.Unlock()
+
+	cancel, ok := running[jobID]
+	if ok {
+		cancel()
+	}
+	return ok
+}
+
+// publish sends a summary of the job to the event stream of its project
+func publish(eventType string, job *Job) {
+	events.Publish(job.ProjectID, eventType, events.JobSummary{
+		ID:        job.ID,
+		Type:      job.Type,
+		Status:    string(job.Status),
+		Progress:  job.Progress,
+		Attempts:  job.Attempts,
+		Error:     job.Error,
+		UpdatedAt: job.UpdatedAt,
+	})
+}
+
+// finish is called once per job whatever the way it reached a final status
+func finish(job *Job) {
+	publish(events.JOB_FINISHED, job)
+
+	handler, ok := findHandler(job.Type)
+	if !ok {
+		return
+	}
+
+	err := handler.Finish(job)
+	if err != nil {
+		log.Println("jobs: finish failed:", err)
+	}
+}
@@ -0,0 +1,175 @@
+package jobs
+
+import (
+	"errors"
+	"time"
+
+	"github.com/fatih/structs"
+	"github.com/khoa5773/go-server/src/domains/events"
+	"github.com/khoa5773/go-server/src/shared"
+	"github.com/mitchellh/mapstructure"
+	"gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+)
+
+// DefaultMaxAttempts is used when a job is enqueued without MaxAttempts
+const DefaultMaxAttempts = 3
+
+func FindManyJobs(findManyJobsDto FindManyJobsDto, filterJobsDto FilterJobsDto, credentials shared.Credentials) (shared.Result, error) {
+	var jobs []map[string]interface{}
+	JobsModel := shared.MongoSession.C("jobs")
+
+	query := bson.M{"projectID": findManyJobsDto.ProjectID}
+	if filterJobsDto.Type != "" {
+		query["type"] = filterJobsDto.Type
+	}
+	if filterJobsDto.Status != "" {
+		query["status"] = filterJobsDto.Status
+	}
+
+	err := JobsModel.Find(query).Sort("-createdAt").All(&jobs)
+	if err != nil {
+		return shared.Result{}, err
+	}
+
+	data, err := shared.ValidateAccessToList(jobs, credentials)
+	if err != nil {
+		return shared.Result{}, err
+	}
+
+	return *data, nil
+}
+
+func FindOneJob(findOneJobDto *FindOneJobDto, credentials shared.Credentials) (Job, error) {
+	var job Job
+	JobsModel := shared.MongoSession.C("jobs")
+	err := JobsModel.Find(findOneJobDto).One(&job)
+	if err != nil {
+		return Job{}, err
+	}
+
+	data, err := shared.ValidateAccessToSingle(structs.Map(job), credentials)
+	if err != nil {
+		return Job{}, err
+	}
+
+	err = mapstructure.Decode(data, &job)
+	if err != nil {
+		return Job{}, err
+	}
+
+	return job, nil
+}
+
+// Enqueue stores the job as QUEUED and wakes up an idle worker, the caller sets ID, ProjectID, Type and Payload
+func Enqueue(createOneJobDto *CreateOneJobDto, credentials shared.Credentials) (bool, error) {
+	if _, ok := findHandler(createOneJobDto.Type); !ok {
+		return false, errors.New("unknown job type " + createOneJobDto.Type)
+	}
+
+	createOneJobDto.Status = QUEUED
+	createOneJobDto.Progress = 0
+	createOneJobDto.Attempts = 0
+	if createOneJobDto.MaxAttempts <= 0 {
+		createOneJobDto.MaxAttempts = DefaultMaxAttempts
+	}
+	createOneJobDto.CreatedBy = credentials.Id
+	createOneJobDto.CreatedAt = time.Now()
+	createOneJobDto.UpdatedAt = time.Now()
+	createOneJobDto.RunAfter = createOneJobDto.CreatedAt
+
+	job := &Job{}
+	err := mapstructure.Decode(structs.Map(createOneJobDto), job)
+	if err != nil {
+		return false, err
+	}
+
+	_, err = shared.ValidateAccessToSingle(structs.Map(job), credentials)
+	if err != nil {
+		return false, err
+	}
+
+	JobsModel := shared.MongoSession.C("jobs")
+	err = JobsModel.Insert(createOneJobDto)
+	if err != nil {
+		return false, err
+	}
+
+	publish(events.JOB_PROGRESS, job)
+	notify()
+	return true, nil
+}
+
+// CancelJob cancels a queued job right away. A running job is flagged, the server running it cancels
+// its context at its next heartbeat and the job reaches CANCELLED once its handler returns. Only the
+// creator of a job can cancel it unless canCancelAny is set.
+func CancelJob(findOneJobDto *FindOneJobDto, canCancelAny bool, credentials shared.Credentials) (bool, error) {
+	job, err := FindOneJob(findOneJobDto, credentials)
+	if err != nil {
+		return false, err
+	}
+	if job.CreatedBy != credentials.Id && !canCancelAny && !credentials.IsAdmin {
+		return false, errors.New("no permission")
+	}
+
+	switch job.Status {
+	case QUEUED:
+		JobsModel := shared.MongoSession.C("jobs")
+		now := time.Now()
+		err = JobsModel.Update(
+			bson.M{"_id": job.ID, "status": QUEUED},
+			bson.M{"$set": bson.M{"status": CANCELLED, "finishedAt": now, "updatedAt": now}},
+		)
+		if err != nil {
+			return false, err
+		}
+
+		job.Status = CANCELLED
+		job.FinishedAt = now
+		finish(&job)
+		return true, nil
+	case RUNNING:
+		JobsModel := shared.MongoSession.C("jobs")
+		err = JobsModel.Update(
+			bson.M{"_id": job.ID, "status": RUNNING},
+			bson.M{"$set": bson.M{"cancelRequested": true, "updatedAt": time.Now()}},
+		)
+		if err == mgo.ErrNotFound {
+			return false, errors.New("job is no longer running")
+		}
+		if err != nil {
+			return false, err
+		}
+
+		// no need to wait for the heartbeat when the job runs here
+		cancelRunning(job.ID)
+		return true, nil
+	}
+
+	return false, errors.New("job is already finished")
+}
+
+func DeleteJob(deleteJobDto *DeleteJobDto, credentials shared.Credentials) (bool, error) {
+	var job *Job
+	JobsModel := shared.MongoSession.C("jobs")
+	err := JobsModel.Find(deleteJobDto).One(&job)
+	if err != nil {
+		return false, err
+	}
+
+	_, err = shared.ValidateAccessToSingle(structs.Map(*job), credentials)
+	if err != nil {
+		return false, err
+	}
+
+	if !job.IsFinished() {
+		return false, errors.New("only finished jobs can be deleted")
+	}
+
+	err = JobsModel.Remove(deleteJobDto)
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
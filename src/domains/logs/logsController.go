@@ -0,0 +1,94 @@
+package logs
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/khoa5773/go-server/src/middleware/auth"
+	"github.com/khoa5773/go-server/src/middleware/authz"
+	"github.com/khoa5773/go-server/src/shared"
+	"gopkg.in/mgo.v2/bson"
+)
+
+func ApplyRoutes(r *gin.Engine) {
+	LogsControllers := r.Group("projects/:projectID/logs")
+	LogsControllers.GET("", auth.JWTRequired, authz.Scopes([]string{"logs:read", "proj:logs:read"}), findManyLogsController)
+
+	r.GET("/me/logs", auth.JWTRequired, authz.Scopes([]string{"userLogs:read"}), findMyLogsController)
+	r.GET("/users/:_id/logs", auth.JWTRequired, authz.Scopes([]string{"userLogs:read"}), authz.Admin, findManyUserLogsController)
+}
+
+func findManyLogsController(c *gin.Context) {
+	var findManyLogsDto FindManyLogsDto
+	var filterLogsDto FilterLogsDto
+
+	err := c.ShouldBindUri(&findManyLogsDto)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	err = c.ShouldBindQuery(&filterLogsDto)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	credentials := shared.Credentials{
+		Id:                c.MustGet("userID").(string),
+		HasPersonalScopes: c.MustGet("hasPersonalScopes").(bool),
+		HasProjectScopes:  c.MustGet("hasProjectScopes").(bool),
+		ProjectIDs:        c.MustGet("userProjectIDs").([]bson.ObjectId),
+		RequestID:         c.GetString("requestID"),
+	}
+
+	logs, total, err := FindManyLogs(findManyLogsDto, filterLogsDto, credentials)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"logs": logs, "total": total})
+}
+
+func findMyLogsController(c *gin.Context) {
+	var filterLogsDto FilterLogsDto
+	err := c.ShouldBindQuery(&filterLogsDto)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	logs, total, err := FindManyUserLogs(c.MustGet("userID").(string), filterLogsDto)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"logs": logs, "total": total})
+}
+
+func findManyUserLogsController(c *gin.Context) {
+	var findManyUserLogsDto FindManyUserLogsDto
+	var filterLogsDto FilterLogsDto
+
+	err := c.ShouldBindUri(&findManyUserLogsDto)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	err = c.ShouldBindQuery(&filterLogsDto)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	logs, total, err := FindManyUserLogs(findManyUserLogsDto.UserID, filterLogsDto)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"logs": logs, "total": total})
+}
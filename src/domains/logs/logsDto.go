@@ -0,0 +1,24 @@
+package logs
+
+import (
+	"time"
+
+	"gopkg.in/mgo.v2/bson"
+)
+
+type FindManyLogsDto struct {
+	ProjectID bson.ObjectId `json:"projectID" bson:"projectID" uri:"projectID" binding:"required,mongoid"`
+}
+
+type FindManyUserLogsDto struct {
+	UserID string `uri:"_id" binding:"required"`
+}
+
+type FilterLogsDto struct {
+	ActorID  string    `form:"actorID"`
+	Resource Resource  `form:"resource" binding:"omitempty,oneof=PROJECT REPOSITORY DOCUMENT USER PREDICTION"`
+	From     time.Time `form:"from" time_format:"2006-01-02T15:04:05Z07:00"`
+	To       time.Time `form:"to" time_format:"2006-01-02T15:04:05Z07:00"`
+	Limit    int       `form:"limit" binding:"omitempty,min=1,max=500"`
+	Skip     int       `form:"skip" binding:"omitempty,min=0"`
+}
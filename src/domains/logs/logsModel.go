@@ -0,0 +1,51 @@
+package logs
+
+import (
+	"time"
+
+	"gopkg.in/mgo.v2/bson"
+)
+
+type Action string
+
+const (
+	CREATE          Action = "CREATE"
+	UPDATE          Action = "UPDATE"
+	DELETE          Action = "DELETE"
+	ADD_MEMBERS     Action = "ADD_MEMBERS"
+	REMOVE_MEMBERS  Action = "REMOVE_MEMBERS"
+	ADD_MANAGERS    Action = "ADD_MANAGERS"
+	REMOVE_MANAGERS Action = "REMOVE_MANAGERS"
+	LOGIN           Action = "LOGIN"
+	DOWNLOAD        Action = "DOWNLOAD"
+)
+
+type Resource string
+
+const (
+	PROJECT    Resource = "PROJECT"
+	REPOSITORY Resource = "REPOSITORY"
+	DOCUMENT   Resource = "DOCUMENT"
+	USER       Resource = "USER"
+	PREDICTION Resource = "PREDICTION"
+)
+
+// Change is one field of the resource, Before is nil on creation and After on deletion
+type Change struct {
+	Field  string      `json:"field" bson:"field"`
+	Before interface{} `json:"before" bson:"before"`
+	After  interface{} `json:"after" bson:"after"`
+}
+
+// Log model, entries are only ever inserted
+type Log struct {
+	ID         bson.ObjectId `json:"_id" bson:"_id"`
+	ProjectID  bson.ObjectId `json:"projectID,omitempty" bson:"projectID,omitempty"`
+	ActorID    string        `json:"actorID" bson:"actorID"`
+	Resource   Resource      `json:"resource" bson:"resource"`
+	ResourceID string        `json:"resourceID" bson:"resourceID"`
+	Action     Action        `json:"action" bson:"action"`
+	Changes    []Change      `json:"changes" bson:"changes"`
+	RequestID  string        `json:"requestID" bson:"requestID"`
+	CreatedAt  time.Time     `json:"createdAt" bson:"createdAt"`
+}
@@ -0,0 +1,159 @@
+package logs
+
+import (
+	"log"
+	"reflect"
+	"sort"
+	"time"
+
+	"github.com/khoa5773/go-server/src/shared"
+	"gopkg.in/mgo.v2/bson"
+)
+
+const defaultLimit = 50
+
+// ignoredFields change on every write and would only add noise to the diff
+var ignoredFields = map[string]bool{"updatedAt": true, "updatedBy": true, "lastAccess": true}
+
+// Record appends an audit entry. before and after are the resource as stored, nil when it does not
+// exist. The mutation has already happened, so a failure is only logged.
+func Record(credentials shared.Credentials, projectID bson.ObjectId, resource Resource, resourceID string, action Action, before, after interface{}) {
+	changes, err := diff(before, after)
+	if err != nil {
+		log.Println("logs: diff failed:", err)
+		return
+	}
+	if action == UPDATE && len(changes) == 0 {
+		return
+	}
+
+	entry := Log{
+		ID:         bson.NewObjectId(),
+		ProjectID:  projectID,
+		ActorID:    credentials.Id,
+		Resource:   resource,
+		ResourceID: resourceID,
+		Action:     action,
+		Changes:    changes,
+		RequestID:  credentials.RequestID,
+		CreatedAt:  time.Now(),
+	}
+
+	LogsModel := shared.MongoSession.C("logs")
+	err = LogsModel.Insert(entry)
+	if err != nil {
+		log.Println("logs: insert failed:", err)
+	}
+}
+
+func FindManyLogs(findManyLogsDto FindManyLogsDto, filterLogsDto FilterLogsDto, credentials shared.Credentials) (shared.Result, int, error) {
+	query := filterQuery(filterLogsDto)
+	query["projectID"] = findManyLogsDto.ProjectID
+
+	return findLogs(query, filterLogsDto, credentials)
+}
+
+// FindManyUserLogs returns what a user did across all projects, newest first. The query only
+// matches the user's own entries, some of which belong to no project, so the list is not
+// filtered by project membership.
+func FindManyUserLogs(userID string, filterLogsDto FilterLogsDto) (shared.Result, int, error) {
+	query := filterQuery(filterLogsDto)
+	query["actorID"] = userID
+
+	return findLogs(query, filterLogsDto, shared.Credentials{Id: userID, IsAdmin: true})
+}
+
+func filterQuery(filterLogsDto FilterLogsDto) bson.M {
+	query := bson.M{}
+	if filterLogsDto.ActorID != "" {
+		query["actorID"] = filterLogsDto.ActorID
+	}
+	if filterLogsDto.Resource != "" {
+		query["resource"] = filterLogsDto.Resource
+	}
+
+	createdAt := bson.M{}
+	if !filterLogsDto.From.IsZero() {
+		createdAt["$gte"] = filterLogsDto.From
+	}
+	if !filterLogsDto.To.IsZero() {
+		createdAt["$lt"] = filterLogsDto.To
+	}
+	if len(createdAt) > 0 {
+		query["createdAt"] = createdAt
+	}
+
+	return query
+}
+
+func findLogs(query bson.M, filterLogsDto FilterLogsDto, credentials shared.Credentials) (shared.Result, int, error) {
+	var logs []map[string]interface{}
+	LogsModel := shared.MongoSession.C("logs")
+
+	total, err := LogsModel.Find(query).Count()
+	if err != nil {
+		return shared.Result{}, 0, err
+	}
+
+	limit := filterLogsDto.Limit
+	if limit == 0 {
+		limit = defaultLimit
+	}
+
+	err = LogsModel.Find(query).Sort("-createdAt", "-_id").Skip(filterLogsDto.Skip).Limit(limit).All(&logs)
+	if err != nil {
+		return shared.Result{}, 0, err
+	}
+
+	data, err := shared.ValidateAccessToList(logs, credentials)
+	if err != nil {
+		return shared.Result{}, 0, err
+	}
+
+	return *data, total, nil
+}
+
+// diff compares the stored representation of both versions field by field
+func diff(before, after interface{}) ([]Change, error) {
+	beforeFields, err := toFields(before)
+	if err != nil {
+		return nil, err
+	}
+	afterFields, err := toFields(after)
+	if err != nil {
+		return nil, err
+	}
+
+	fields := map[string]bool{}
+	for field := range beforeFields {
+		fields[field] = true
+	}
+	for field := range afterFields {
+		fields[field] = true
+	}
+
+	changes := []Change{}
+	for field := range fields {
+		if ignoredFields[field] || reflect.DeepEqual(beforeFields[field], afterFields[field]) {
+			continue
+		}
+		changes = append(changes, Change{Field: field, Before: beforeFields[field], After: afterFields[field]})
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Field < changes[j].Field })
+
+	return changes, nil
+}
+
+func toFields(value interface{}) (bson.M, error) {
+	fields := bson.M{}
+	if value == nil || reflect.ValueOf(value).Kind() == reflect.Ptr && reflect.ValueOf(value).IsNil() {
+		return fields, nil
+	}
+
+	data, err := bson.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	err = bson.Unmarshal(data, &fields)
+	return fields, err
+}
@@ -0,0 +1,198 @@
+package models
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/khoa5773/go-server/src/middleware/auth"
+	"github.com/khoa5773/go-server/src/middleware/authz"
+	"github.com/khoa5773/go-server/src/shared"
+	"gopkg.in/mgo.v2/bson"
+)
+
+func ApplyRoutes(r *gin.Engine) {
+	ModelsControllers := r.Group("projects/:projectID/models")
+	ModelsControllers.GET("", auth.JWTRequired, authz.Scopes([]string{"models:read", "proj:models:read"}), findManyModelsController)
+	ModelsControllers.POST("", auth.JWTRequired, authz.Scopes([]string{"models:create", "proj:models:create"}), createModelController)
+	ModelsControllers.GET("/:modelID", auth.JWTRequired, authz.Scopes([]string{"models:read", "proj:models:read"}), findOneModelController)
+	ModelsControllers.PUT("/:modelID", auth.JWTRequired, authz.Scopes([]string{"models:update", "proj:models:update"}), updateModelController)
+	ModelsControllers.DELETE("/:modelID", auth.JWTRequired, authz.Scopes([]string{"models:delete", "proj:models:delete"}), deleteModelController)
+	ModelsControllers.POST("/:modelID/apply", auth.JWTRequired, authz.Scopes([]string{"predictions:create", "proj:predictions:create"}), applyModelController)
+}
+
+func findManyModelsController(c *gin.Context) {
+	var findManyModelsDto FindManyModelsDto
+	var filterModelsDto FilterModelsDto
+
+	err := c.ShouldBindUri(&findManyModelsDto)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	err = c.ShouldBindQuery(&filterModelsDto)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	credentials := shared.Credentials{
+		Id:                c.MustGet("userID").(string),
+		HasPersonalScopes: c.MustGet("hasPersonalScopes").(bool),
+		HasProjectScopes:  c.MustGet("hasProjectScopes").(bool),
+		ProjectIDs:        c.MustGet("userProjectIDs").([]bson.ObjectId),
+		RequestID:         c.GetString("requestID"),
+	}
+
+	models, err := FindManyModels(findManyModelsDto, filterModelsDto, credentials)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"models": models})
+}
+
+func findOneModelController(c *gin.Context) {
+	var findOneModelDto FindOneModelDto
+	err := c.ShouldBindUri(&findOneModelDto)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	credentials := shared.Credentials{
+		Id:                c.MustGet("userID").(string),
+		HasPersonalScopes: c.MustGet("hasPersonalScopes").(bool),
+		HasProjectScopes:  c.MustGet("hasProjectScopes").(bool),
+		ProjectIDs:        c.MustGet("userProjectIDs").([]bson.ObjectId),
+		RequestID:         c.GetString("requestID"),
+	}
+
+	model, err := FindOneModel(&findOneModelDto, credentials)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"model": model})
+}
+
+func createModelController(c *gin.Context) {
+	var createOneModelDto CreateOneModelDto
+	err := c.ShouldBindJSON(&createOneModelDto)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	credentials := shared.Credentials{
+		Id:                c.MustGet("userID").(string),
+		HasPersonalScopes: c.MustGet("hasPersonalScopes").(bool),
+		HasProjectScopes:  c.MustGet("hasProjectScopes").(bool),
+		ProjectIDs:        c.MustGet("userProjectIDs").([]bson.ObjectId),
+		RequestID:         c.GetString("requestID"),
+	}
+	createOneModelDto.ProjectID = bson.ObjectIdHex(c.Param("projectID"))
+	createOneModelDto.ID = bson.NewObjectId()
+
+	isSuccess, err := CreateOneModel(&createOneModelDto, credentials)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{"success": isSuccess, "_id": createOneModelDto.ID, "version": createOneModelDto.Version, "jobID": createOneModelDto.JobID})
+}
+
+func updateModelController(c *gin.Context) {
+	var findOneModelDto FindOneModelDto
+	var updateModelDto UpdateModelDto
+
+	err := c.ShouldBindUri(&findOneModelDto)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	err = c.ShouldBindJSON(&updateModelDto)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	credentials := shared.Credentials{
+		Id:                c.MustGet("userID").(string),
+		HasPersonalScopes: c.MustGet("hasPersonalScopes").(bool),
+		HasProjectScopes:  c.MustGet("hasProjectScopes").(bool),
+		ProjectIDs:        c.MustGet("userProjectIDs").([]bson.ObjectId),
+		RequestID:         c.GetString("requestID"),
+	}
+
+	isSuccess, err := UpdateModel(&findOneModelDto, &updateModelDto, credentials)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": isSuccess})
+}
+
+func deleteModelController(c *gin.Context) {
+	var deleteModelDto DeleteModelDto
+
+	err := c.ShouldBindUri(&deleteModelDto)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	credentials := shared.Credentials{
+		Id:                c.MustGet("userID").(string),
+		HasPersonalScopes: c.MustGet("hasPersonalScopes").(bool),
+		HasProjectScopes:  c.MustGet("hasProjectScopes").(bool),
+		ProjectIDs:        c.MustGet("userProjectIDs").([]bson.ObjectId),
+		RequestID:         c.GetString("requestID"),
+	}
+
+	isSuccess, err := DeleteModel(&deleteModelDto, credentials)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": isSuccess})
+}
+
+func applyModelController(c *gin.Context) {
+	var findOneModelDto FindOneModelDto
+	var applyModelDto ApplyModelDto
+
+	err := c.ShouldBindUri(&findOneModelDto)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	err = c.ShouldBindJSON(&applyModelDto)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	credentials := shared.Credentials{
+		Id:                c.MustGet("userID").(string),
+		HasPersonalScopes: c.MustGet("hasPersonalScopes").(bool),
+		HasProjectScopes:  c.MustGet("hasProjectScopes").(bool),
+		ProjectIDs:        c.MustGet("userProjectIDs").([]bson.ObjectId),
+		RequestID:         c.GetString("requestID"),
+	}
+
+	predictionID, isSuccess, err := ApplyModel(&findOneModelDto, &applyModelDto, credentials)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{"success": isSuccess, "predictionID": predictionID})
+}
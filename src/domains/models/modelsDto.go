@@ -0,0 +1,63 @@
+package models
+
+import (
+	"time"
+
+	"gopkg.in/mgo.v2/bson"
+)
+
+type FindManyModelsDto struct {
+	ProjectID bson.ObjectId `json:"projectID,omitempty" bson:"projectID,omitempty" uri:"projectID" binding:"required,mongoid"`
+}
+
+type FilterModelsDto struct {
+	Name   string      `json:"name,omitempty" bson:"name,omitempty" form:"name"`
+	Method string      `json:"method,omitempty" bson:"method,omitempty" form:"method"`
+	Trait  string      `json:"trait,omitempty" bson:"trait,omitempty" form:"trait"`
+	Status ModelStatus `json:"status,omitempty" bson:"status,omitempty" form:"status"`
+}
+
+type FindOneModelDto struct {
+	ID        bson.ObjectId `json:"_id,omitempty" bson:"_id,omitempty" uri:"modelID" binding:"required,mongoid"`
+	ProjectID bson.ObjectId `json:"projectID,omitempty" bson:"projectID,omitempty" uri:"projectID" binding:"required,mongoid"`
+}
+
+// CreateOneModelDto either copies the inputs of PredictionID or takes them from the body
+type CreateOneModelDto struct {
+	ID               bson.ObjectId          `bson:"_id"`
+	Name             string                 `json:"name" bson:"name" binding:"required"`
+	Description      string                 `json:"description" bson:"description"`
+	Version          int                    `bson:"version"`
+	PredictionID     bson.ObjectId          `json:"predictionID" bson:"predictionID,omitempty"`
+	GenotypeID       bson.ObjectId          `json:"genotypeID" bson:"genotypeID"`
+	PhenotypeID      bson.ObjectId          `json:"phenotypeID" bson:"phenotypeID"`
+	GenotypeVersion  int                    `json:"genotypeVersion" bson:"genotypeVersion,omitempty" binding:"omitempty,min=1"`
+	PhenotypeVersion int                    `json:"phenotypeVersion" bson:"phenotypeVersion,omitempty" binding:"omitempty,min=1"`
+	Method           string                 `json:"method" bson:"method" binding:"omitempty,oneof=RBF_BLUF ELASTIC_NET LASSO G_BLUP"`
+	Parameters       map[string]interface{} `json:"parameters" bson:"parameters"`
+	Trait            string                 `json:"trait" bson:"trait"`
+	JobID            bson.ObjectId          `bson:"jobID"`
+	Status           ModelStatus            `bson:"status"`
+	ProjectID        bson.ObjectId          `bson:"projectID"`
+	CreatedBy        string                 `bson:"createdBy"`
+	UpdatedBy        string                 `bson:"updatedBy"`
+	CreatedAt        time.Time              `bson:"createdAt"`
+	UpdatedAt        time.Time              `bson:"updatedAt"`
+}
+
+type UpdateModelDto struct {
+	Description string    `json:"description" bson:"description,omitempty"`
+	UpdatedBy   string    `bson:"updatedBy,omitempty"`
+	UpdatedAt   time.Time `bson:"updatedAt,omitempty"`
+}
+
+type ApplyModelDto struct {
+	Name        string        `json:"name" binding:"required"`
+	Description string        `json:"description"`
+	GenotypeID  bson.ObjectId `json:"genotypeID" binding:"required"`
+}
+
+type DeleteModelDto struct {
+	ID        bson.ObjectId `json:"_id,omitempty" bson:"_id,omitempty" uri:"modelID" binding:"required,mongoid"`
+	ProjectID bson.ObjectId `json:"projectID,omitempty" bson:"projectID,omitempty" uri:"projectID" binding:"required,mongoid"`
+}
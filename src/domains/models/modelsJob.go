@@ -0,0 +1,211 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"math"
+	"time"
+
+	"github.com/khoa5773/go-server/src/domains/documents"
+	"github.com/khoa5773/go-server/src/domains/jobs"
+	"github.com/khoa5773/go-server/src/domains/predictions"
+	"github.com/khoa5773/go-server/src/domains/validations"
+	"github.com/khoa5773/go-server/src/genomics"
+	"github.com/khoa5773/go-server/src/genomics/crossvalidation"
+	"github.com/khoa5773/go-server/src/shared"
+	"gopkg.in/mgo.v2/bson"
+)
+
+const (
+	TRAINING_JOB_TYPE = "MODEL_TRAINING"
+	SCORING_JOB_TYPE  = "MODEL_SCORING"
+)
+
+type trainingJobHandler struct{}
+
+type scoringJobHandler struct{}
+
+func init() {
+	jobs.RegisterHandler(TRAINING_JOB_TYPE, trainingJobHandler{})
+	jobs.RegisterHandler(SCORING_JOB_TYPE, scoringJobHandler{})
+}
+
+// Run cross-validates the method with the N_FOLD settings of the creator, then fits the model on every
+// phenotyped sample and saves its state
+func (trainingJobHandler) Run(ctx context.Context, job *jobs.Job, progress func(percentage int)) (map[string]interface{}, error) {
+	modelID, ok := job.Payload["modelID"].(bson.ObjectId)
+	if !ok {
+		return nil, jobs.Permanent(errors.New("job payload has no modelID"))
+	}
+
+	credentials := shared.Credentials{Id: job.CreatedBy, IsAdmin: true}
+	model, err := FindOneModel(&FindOneModelDto{ID: modelID, ProjectID: job.ProjectID}, credentials)
+	if err != nil {
+		return nil, err
+	}
+
+	ModelsModel := shared.MongoSession.C("models")
+	err = ModelsModel.UpdateId(modelID, bson.M{"$set": bson.M{"status": RUNNING, "error": "", "updatedAt": time.Now()}})
+	if err != nil {
+		return nil, err
+	}
+
+	method, err := genomics.GetMethod(model.Method)
+	if err != nil {
+		return nil, jobs.Permanent(err)
+	}
+
+	genotypeDocument, err := predictions.FindDocumentVersionOfType(model.GenotypeID, model.ProjectID, documents.GENOTYPE, model.GenotypeVersion, credentials)
+	if err != nil {
+		return nil, err
+	}
+
+	phenotypeDocument, err := predictions.FindDocumentVersionOfType(model.PhenotypeID, model.ProjectID, documents.PHENOTYPE, model.PhenotypeVersion, credentials)
+	if err != nil {
+		return nil, err
+	}
+
+	dataset, err := predictions.LoadDataset(ctx, genotypeDocument, phenotypeDocument, model.Trait)
+	if err != nil {
+		return nil, err
+	}
+	progress(20)
+
+	// the metrics are measured out of fold, the fit on its own training samples would flatter the model
+	rows := dataset.TrainingRows()
+	folds, repetitions, err := validations.FindDefaultSettings(credentials)
+	if err != nil {
+		return nil, err
+	}
+	if folds > len(rows) {
+		folds = len(rows)
+	}
+	validation, err := crossvalidation.Run(ctx, method, dataset, model.Parameters, crossvalidation.Settings{
+		Folds:       folds,
+		Repetitions: repetitions,
+		Seed:        model.CreatedAt.UnixNano(),
+		Progress: func(completed, total int) {
+			progress(20 + 50*completed/total)
+		},
+	})
+	if err != nil {
+		return nil, jobs.Permanent(err)
+	}
+
+	fitted, err := genomics.Fit(ctx, method, dataset, rows, model.Parameters)
+	if err != nil {
+		return nil, jobs.Permanent(err)
+	}
+	progress(80)
+
+	stateSize, err := SaveModelState(ctx, model, fitted)
+	if err != nil {
+		return nil, err
+	}
+	// recorded at once so that DeleteModel finds the state even if the job fails from here on
+	err = ModelsModel.UpdateId(modelID, bson.M{"$set": bson.M{"stateSize": stateSize}})
+	if err != nil {
+		return nil, err
+	}
+	progress(90)
+
+	err = ModelsModel.UpdateId(modelID, bson.M{"$set": bson.M{
+		"summary":         fitted.Summary(),
+		"metrics":         validation.Mean,
+		"crossValidation": validation,
+		"samples":         len(rows),
+		"markers":         dataset.Genotype.NumMarkers(),
+		"updatedAt":       time.Now(),
+	}})
+	if err != nil {
+		return nil, err
+	}
+
+	return map[string]interface{}{"modelID": modelID}, nil
+}
+
+// Finish copies the final job status and error on the model
+func (trainingJobHandler) Finish(job *jobs.Job) error {
+	modelID, ok := job.Payload["modelID"].(bson.ObjectId)
+	if !ok {
+		return nil
+	}
+
+	ModelsModel := shared.MongoSession.C("models")
+	return ModelsModel.UpdateId(modelID, bson.M{"$set": bson.M{
+		"status":    ModelStatus(job.Status),
+		"error":     job.Error,
+		"updatedAt": time.Now(),
+	}})
+}
+
+// Run restores the model state and predicts every sample of the genotype document without refitting
+func (scoringJobHandler) Run(ctx context.Context, job *jobs.Job, progress func(percentage int)) (map[string]interface{}, error) {
+	predictionID, ok := job.Payload["predictionID"].(bson.ObjectId)
+	if !ok {
+		return nil, jobs.Permanent(errors.New("job payload has no predictionID"))
+	}
+	modelID, ok := job.Payload["modelID"].(bson.ObjectId)
+	if !ok {
+		return nil, jobs.Permanent(errors.New("job payload has no modelID"))
+	}
+
+	credentials := shared.Credentials{Id: job.CreatedBy, IsAdmin: true}
+	prediction, err := predictions.FindOnePrediction(&predictions.FindOnePredictionDto{ID: predictionID, ProjectID: job.ProjectID}, credentials)
+	if err != nil {
+		return nil, err
+	}
+
+	err = predictions.SetPredictionStatus(predictionID, predictions.RUNNING, "")
+	if err != nil {
+		return nil, err
+	}
+
+	model, err := FindOneModel(&FindOneModelDto{ID: modelID, ProjectID: job.ProjectID}, credentials)
+	if err != nil {
+		return nil, err
+	}
+
+	state, err := LoadModelState(ctx, model)
+	if err != nil {
+		return nil, err
+	}
+	progress(20)
+
+	genotypeDocument, err := predictions.FindDocumentVersionOfType(prediction.GenotypeID, prediction.ProjectID, documents.GENOTYPE, prediction.GenotypeVersion, credentials)
+	if err != nil {
+		return nil, err
+	}
+
+	genotype, err := predictions.LoadGenotype(ctx, genotypeDocument)
+	if err != nil {
+		return nil, err
+	}
+	progress(60)
+
+	predicted, err := state.Predict(genotype)
+	if err != nil {
+		return nil, jobs.Permanent(err)
+	}
+
+	dataset := &genomics.Dataset{Genotype: genotype, Observed: make([]float64, genotype.NumSamples())}
+	for i := range dataset.Observed {
+		dataset.Observed[i] = math.NaN()
+	}
+
+	results, err := predictions.BuildPredictionResults(dataset, predicted)
+	if err != nil {
+		return nil, jobs.Permanent(err)
+	}
+
+	err = predictions.SetPredictionResults(predictionID, results, state.Summary())
+	if err != nil {
+		return nil, err
+	}
+
+	return map[string]interface{}{"predictionID": predictionID, "modelID": modelID}, nil
+}
+
+func (scoringJobHandler) Finish(job *jobs.Job) error {
+	return predictions.FinishPredictionJob(job)
+}
@@ -0,0 +1,55 @@
+package models
+
+import (
+	"time"
+
+	"github.com/khoa5773/go-server/src/genomics/crossvalidation"
+	"gopkg.in/mgo.v2/bson"
+)
+
+type ModelStatus string
+
+// Statuses mirror the status of the training job
+const (
+	QUEUED    ModelStatus = "QUEUED"
+	RUNNING   ModelStatus = "RUNNING"
+	SUCCEEDED ModelStatus = "SUCCEEDED"
+	FAILED    ModelStatus = "FAILED"
+	CANCELLED ModelStatus = "CANCELLED"
+)
+
+// STATE_MIME_TYPE is the extension of the fitted state object kept in cloud storage
+const STATE_MIME_TYPE = "json"
+
+// Model is one version of a trained prediction model, versions are numbered per name within a project.
+// The fitted state (marker effects or kernel training data) is stored in cloud storage. The document
+// versions it was trained on are recorded, later versions do not change it. Metrics are the mean
+// out-of-fold metrics of CrossValidation.
+type Model struct {
+	ID               bson.ObjectId           `json:"_id" bson:"_id"`
+	Name             string                  `json:"name" bson:"name"`
+	Description      string                  `json:"description" bson:"description"`
+	Version          int                     `json:"version" bson:"version"`
+	ProjectID        bson.ObjectId           `json:"projectID" bson:"projectID"`
+	GenotypeID       bson.ObjectId           `json:"genotypeID" bson:"genotypeID"`
+	PhenotypeID      bson.ObjectId           `json:"phenotypeID" bson:"phenotypeID"`
+	GenotypeVersion  int                     `json:"genotypeVersion" bson:"genotypeVersion,omitempty"`
+	PhenotypeVersion int                     `json:"phenotypeVersion" bson:"phenotypeVersion,omitempty"`
+	PredictionID     bson.ObjectId           `json:"predictionID" bson:"predictionID,omitempty"`
+	Method           string                  `json:"method" bson:"method"`
+	Parameters       map[string]interface{}  `json:"parameters" bson:"parameters"`
+	Trait            string                  `json:"trait" bson:"trait"`
+	JobID            bson.ObjectId           `json:"jobID" bson:"jobID"`
+	Status           ModelStatus             `json:"status" bson:"status"`
+	Error            string                  `json:"error" bson:"error"`
+	Summary          map[string]float64      `json:"summary" bson:"summary"`
+	Metrics          crossvalidation.Metrics `json:"metrics" bson:"metrics"`
+	CrossValidation  *crossvalidation.Result `json:"crossValidation,omitempty" bson:"crossValidation,omitempty"`
+	Samples          int                     `json:"samples" bson:"samples"`
+	Markers          int                     `json:"markers" bson:"markers"`
+	StateSize        int64                   `json:"stateSize" bson:"stateSize"`
+	CreatedBy        string                  `json:"createdBy" bson:"createdBy"`
+	CreatedAt        time.Time               `json:"createdAt" bson:"createdAt"`
+	UpdatedAt        time.Time               `json:"updatedAt" bson:"updatedAt"`
+	UpdatedBy        string                  `json:"updatedBy" bson:"updatedBy"`
+}
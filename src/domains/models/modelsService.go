@@ -0,0 +1,323 @@
+package models
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"time"
+
+	"github.com/fatih/structs"
+	cloudstorage "github.com/khoa5773/go-server/src/domains/cloudStorage"
+	"github.com/khoa5773/go-server/src/domains/documents"
+	"github.com/khoa5773/go-server/src/domains/jobs"
+	"github.com/khoa5773/go-server/src/domains/predictions"
+	"github.com/khoa5773/go-server/src/genomics"
+	"github.com/khoa5773/go-server/src/shared"
+	"github.com/mitchellh/mapstructure"
+	"gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+)
+
+// maxVersionAttempts bounds the retries of a save racing other saves of the same model name
+const maxVersionAttempts = 5
+
+// Setup creates the unique index that keeps two saves of a model name from taking the same version
+func Setup() error {
+	ModelsModel := shared.MongoSession.C("models")
+	return ModelsModel.EnsureIndex(mgo.Index{
+		Key:    []string{"projectID", "name", "version"},
+		Unique: true,
+	})
+}
+
+func FindManyModels(findManyModelsDto FindManyModelsDto, filterModelsDto FilterModelsDto, credentials shared.Credentials) (shared.Result, error) {
+	var models []map[string]interface{}
+	ModelsModel := shared.MongoSession.C("models")
+
+	query := bson.M{"projectID": findManyModelsDto.ProjectID}
+	if filterModelsDto.Name != "" {
+		query["name"] = filterModelsDto.Name
+	}
+	if filterModelsDto.Method != "" {
+		query["method"] = filterModelsDto.Method
+	}
+	if filterModelsDto.Trait != "" {
+		query["trait"] = filterModelsDto.Trait
+	}
+	if filterModelsDto.Status != "" {
+		query["status"] = filterModelsDto.Status
+	}
+
+	err := ModelsModel.Find(query).Sort("name", "-version").All(&models)
+	if err != nil {
+		return shared.Result{}, err
+	}
+
+	data, err := shared.ValidateAccessToList(models, credentials)
+	if err != nil {
+		return shared.Result{}, err
+	}
+
+	return *data, nil
+}
+
+func FindOneModel(findOneModelDto *FindOneModelDto, credentials shared.Credentials) (Model, error) {
+	var model Model
+	ModelsModel := shared.MongoSession.C("models")
+	err := ModelsModel.Find(findOneModelDto).One(&model)
+	if err != nil {
+		return Model{}, err
+	}
+
+	data, err := shared.ValidateAccessToSingle(structs.Map(model), credentials)
+	if err != nil {
+		return Model{}, err
+	}
+
+	err = mapstructure.Decode(data, &model)
+	if err != nil {
+		return Model{}, err
+	}
+
+	return model, nil
+}
+
+// CreateOneModel stores the next version of the named model as QUEUED and enqueues its training job
+func CreateOneModel(createOneModelDto *CreateOneModelDto, credentials shared.Credentials) (bool, error) {
+	createOneModelDto.CreatedBy = credentials.Id
+	createOneModelDto.UpdatedBy = credentials.Id
+	createOneModelDto.CreatedAt = time.Now()
+	createOneModelDto.UpdatedAt = time.Now()
+
+	model := &Model{}
+	err := mapstructure.Decode(structs.Map(createOneModelDto), model)
+	if err != nil {
+		return false, err
+	}
+
+	_, err = shared.ValidateAccessToSingle(structs.Map(model), credentials)
+	if err != nil {
+		return false, err
+	}
+
+	if createOneModelDto.PredictionID != "" {
+		prediction, err := predictions.FindOnePrediction(&predictions.FindOnePredictionDto{ID: createOneModelDto.PredictionID, ProjectID: createOneModelDto.ProjectID}, credentials)
+		if err != nil {
+			return false, err
+		}
+		if prediction.ModelID != "" {
+			return false, errors.New("prediction was computed by a saved model")
+		}
+
+		createOneModelDto.GenotypeID = prediction.GenotypeID
+		createOneModelDto.PhenotypeID = prediction.PhenotypeID
+		createOneModelDto.GenotypeVersion = prediction.GenotypeVersion
+		createOneModelDto.PhenotypeVersion = prediction.PhenotypeVersion
+		createOneModelDto.Method = prediction.Method
+		createOneModelDto.Parameters = prediction.Parameters
+		createOneModelDto.Trait = prediction.Trait
+	}
+
+	if createOneModelDto.GenotypeID == "" || createOneModelDto.PhenotypeID == "" || createOneModelDto.Method == "" {
+		return false, errors.New("genotypeID, phenotypeID and method are required without predictionID")
+	}
+
+	genotypeDocument, err := predictions.FindDocumentVersionOfType(createOneModelDto.GenotypeID, createOneModelDto.ProjectID, documents.GENOTYPE, createOneModelDto.GenotypeVersion, credentials)
+	if err != nil {
+		return false, err
+	}
+
+	phenotypeDocument, err := predictions.FindDocumentVersionOfType(createOneModelDto.PhenotypeID, createOneModelDto.ProjectID, documents.PHENOTYPE, createOneModelDto.PhenotypeVersion, credentials)
+	if err != nil {
+		return false, err
+	}
+
+	createOneModelDto.Trait, err = documents.ResolveTrait(phenotypeDocument, createOneModelDto.Trait)
+	if err != nil {
+		return false, err
+	}
+
+	// the versions are recorded so that the training uses the data the model was saved with
+	createOneModelDto.GenotypeVersion = documents.CurrentVersion(genotypeDocument)
+	createOneModelDto.PhenotypeVersion = documents.CurrentVersion(phenotypeDocument)
+
+	if len(createOneModelDto.Parameters) == 0 {
+		createOneModelDto.Parameters, err = predictions.FindDefaultParameters(createOneModelDto.Method, credentials)
+		if err != nil {
+			return false, err
+		}
+	}
+
+	createOneModelDto.JobID = bson.NewObjectId()
+	createOneModelDto.Status = QUEUED
+
+	ModelsModel := shared.MongoSession.C("models")
+	for attempt := 1; ; attempt++ {
+		createOneModelDto.Version, err = nextVersion(createOneModelDto.ProjectID, createOneModelDto.Name)
+		if err != nil {
+			return false, err
+		}
+
+		err = ModelsModel.Insert(createOneModelDto)
+		if err == nil {
+			break
+		}
+		// another save took this version first
+		if !mgo.IsDup(err) || attempt == maxVersionAttempts {
+			return false, err
+		}
+	}
+
+	_, err = jobs.Enqueue(&jobs.CreateOneJobDto{
+		ID:        createOneModelDto.JobID,
+		ProjectID: createOneModelDto.ProjectID,
+		Type:      TRAINING_JOB_TYPE,
+		Payload:   map[string]interface{}{"modelID": createOneModelDto.ID},
+	}, credentials)
+	if err != nil {
+		_ = ModelsModel.RemoveId(createOneModelDto.ID)
+		return false, err
+	}
+
+	return true, nil
+}
+
+func nextVersion(projectID bson.ObjectId, name string) (int, error) {
+	var latest Model
+	ModelsModel := shared.MongoSession.C("models")
+	err := ModelsModel.Find(bson.M{"projectID": projectID, "name": name}).Sort("-version").One(&latest)
+	if err == mgo.ErrNotFound {
+		return 1, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	return latest.Version + 1, nil
+}
+
+func UpdateModel(findOneModelDto *FindOneModelDto, updateModelDto *UpdateModelDto, credentials shared.Credentials) (bool, error) {
+	var model *Model
+	ModelsModel := shared.MongoSession.C("models")
+
+	err := ModelsModel.Find(findOneModelDto).One(&model)
+	if err != nil {
+		return false, err
+	}
+
+	_, err = shared.ValidateAccessToSingle(structs.Map(*model), credentials)
+	if err != nil {
+		return false, err
+	}
+
+	updateModelDto.UpdatedAt = time.Now()
+	updateModelDto.UpdatedBy = credentials.Id
+
+	err = ModelsModel.Update(findOneModelDto, bson.M{"$set": updateModelDto})
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+func DeleteModel(deleteModelDto *DeleteModelDto, credentials shared.Credentials) (bool, error) {
+	var model *Model
+	ModelsModel := shared.MongoSession.C("models")
+	err := ModelsModel.Find(deleteModelDto).One(&model)
+	if err != nil {
+		return false, err
+	}
+
+	_, err = shared.ValidateAccessToSingle(structs.Map(*model), credentials)
+	if err != nil {
+		return false, err
+	}
+
+	if model.Status == QUEUED || model.Status == RUNNING {
+		return false, errors.New("model is still training, cancel its job first")
+	}
+
+	err = ModelsModel.Remove(deleteModelDto)
+	if err != nil {
+		return false, err
+	}
+
+	// a job may have saved the state before failing or being cancelled
+	if model.StateSize > 0 {
+		_, err = cloudstorage.RemoveFileFromCloudStorage(context.Background(), model.ID.Hex(), model.ProjectID.Hex(), STATE_MIME_TYPE)
+		if err != nil && err != cloudstorage.ErrNotFound {
+			return false, err
+		}
+	}
+
+	return true, nil
+}
+
+// ApplyModel scores the samples of a GENOTYPE document with a trained model, the scores are
+// stored as a prediction referencing the model
+func ApplyModel(findOneModelDto *FindOneModelDto, applyModelDto *ApplyModelDto, credentials shared.Credentials) (bson.ObjectId, bool, error) {
+	model, err := FindOneModel(findOneModelDto, credentials)
+	if err != nil {
+		return "", false, err
+	}
+
+	if model.Status != SUCCEEDED {
+		return "", false, errors.New("model has not been trained")
+	}
+
+	genotypeDocument, err := predictions.FindDocumentOfType(applyModelDto.GenotypeID, model.ProjectID, documents.GENOTYPE, credentials)
+	if err != nil {
+		return "", false, err
+	}
+
+	createOnePredictionDto := &predictions.CreateOnePredictionDto{
+		ID:              bson.NewObjectId(),
+		Name:            applyModelDto.Name,
+		Description:     applyModelDto.Description,
+		GenotypeID:      applyModelDto.GenotypeID,
+		GenotypeVersion: documents.CurrentVersion(genotypeDocument),
+		ModelID:         model.ID,
+		Method:          model.Method,
+		Parameters:      model.Parameters,
+		Trait:           model.Trait,
+		ProjectID:       model.ProjectID,
+		CreatedBy:       credentials.Id,
+		UpdatedBy:       credentials.Id,
+		CreatedAt:       time.Now(),
+		UpdatedAt:       time.Now(),
+	}
+
+	isSuccess, err := predictions.EnqueuePrediction(createOnePredictionDto, SCORING_JOB_TYPE, map[string]interface{}{"modelID": model.ID}, credentials)
+	if err != nil {
+		return "", false, err
+	}
+
+	return createOnePredictionDto.ID, isSuccess, nil
+}
+
+func SaveModelState(ctx context.Context, model Model, state genomics.Model) (int64, error) {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return 0, err
+	}
+
+	return cloudstorage.WriteFileToCloudStorage(ctx, bytes.NewReader(data), model.ID.Hex(), model.ProjectID.Hex(), STATE_MIME_TYPE)
+}
+
+func LoadModelState(ctx context.Context, model Model) (genomics.Model, error) {
+	content, err := cloudstorage.ReadFileFromCloudStorage(ctx, model.ID.Hex(), model.ProjectID.Hex(), STATE_MIME_TYPE)
+	if err != nil {
+		return nil, err
+	}
+	defer content.Close()
+
+	data, err := ioutil.ReadAll(content)
+	if err != nil {
+		return nil, err
+	}
+
+	return genomics.DecodeModel(model.Method, data)
+}
@@ -0,0 +1,197 @@
+package predictions
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/khoa5773/go-server/src/middleware/auth"
+	"github.com/khoa5773/go-server/src/middleware/authz"
+	"github.com/khoa5773/go-server/src/shared"
+	"gopkg.in/mgo.v2/bson"
+)
+
+func ApplyRoutes(r *gin.Engine) {
+	PredictionsControllers := r.Group("projects/:projectID/predictions")
+	PredictionsControllers.GET("", auth.JWTRequired, authz.Scopes([]string{"predictions:read", "proj:predictions:read"}), findManyPredictionsController)
+	PredictionsControllers.POST("", auth.JWTRequired, authz.Scopes([]string{"predictions:create", "proj:predictions:create"}), createPredictionController)
+	PredictionsControllers.POST("/sampleMatch", auth.JWTRequired, authz.Scopes([]string{"predictions:read", "proj:predictions:read"}), matchSamplesController)
+	PredictionsControllers.GET("/:predictionID", auth.JWTRequired, authz.Scopes([]string{"predictions:read", "proj:predictions:read"}), findOnePredictionController)
+	PredictionsControllers.PUT("/:predictionID", auth.JWTRequired, authz.Scopes([]string{"predictions:update", "proj:predictions:update"}), updatePredictionController)
+	PredictionsControllers.DELETE("/:predictionID", auth.JWTRequired, authz.Scopes([]string{"predictions:delete", "proj:predictions:delete"}), deletePredictionController)
+}
+
+func findManyPredictionsController(c *gin.Context) {
+	var findManyPredictionsDto FindManyPredictionsDto
+	var filterPredictionsDto FilterPredictionsDto
+
+	err := c.ShouldBindUri(&findManyPredictionsDto)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	err = c.ShouldBindQuery(&filterPredictionsDto)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	credentials := shared.Credentials{
+		Id:                c.MustGet("userID").(string),
+		HasPersonalScopes: c.MustGet("hasPersonalScopes").(bool),
+		HasProjectScopes:  c.MustGet("hasProjectScopes").(bool),
+		ProjectIDs:        c.MustGet("userProjectIDs").([]bson.ObjectId),
+		RequestID:         c.GetString("requestID"),
+	}
+
+	predictions, err := FindManyPredictions(findManyPredictionsDto, filterPredictionsDto, credentials)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"predictions": predictions})
+}
+
+func findOnePredictionController(c *gin.Context) {
+	var findOnePredictionDto FindOnePredictionDto
+	err := c.ShouldBindUri(&findOnePredictionDto)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	credentials := shared.Credentials{
+		Id:                c.MustGet("userID").(string),
+		HasPersonalScopes: c.MustGet("hasPersonalScopes").(bool),
+		HasProjectScopes:  c.MustGet("hasProjectScopes").(bool),
+		ProjectIDs:        c.MustGet("userProjectIDs").([]bson.ObjectId),
+		RequestID:         c.GetString("requestID"),
+	}
+
+	prediction, err := FindOnePrediction(&findOnePredictionDto, credentials)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"prediction": prediction})
+}
+
+func matchSamplesController(c *gin.Context) {
+	var findManyPredictionsDto FindManyPredictionsDto
+	err := c.ShouldBindUri(&findManyPredictionsDto)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	var matchSamplesDto MatchSamplesDto
+	err = c.ShouldBindJSON(&matchSamplesDto)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	credentials := shared.Credentials{
+		Id:                c.MustGet("userID").(string),
+		HasPersonalScopes: c.MustGet("hasPersonalScopes").(bool),
+		HasProjectScopes:  c.MustGet("hasProjectScopes").(bool),
+		ProjectIDs:        c.MustGet("userProjectIDs").([]bson.ObjectId),
+		RequestID:         c.GetString("requestID"),
+	}
+
+	match, normalization, err := MatchSamples(c, findManyPredictionsDto.ProjectID, &matchSamplesDto, credentials)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"sampleMatch": match, "sampleNormalization": normalization})
+}
+
+func createPredictionController(c *gin.Context) {
+	var createOnePredictionDto CreateOnePredictionDto
+	err := c.ShouldBindJSON(&createOnePredictionDto)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	credentials := shared.Credentials{
+		Id:                c.MustGet("userID").(string),
+		HasPersonalScopes: c.MustGet("hasPersonalScopes").(bool),
+		HasProjectScopes:  c.MustGet("hasProjectScopes").(bool),
+		ProjectIDs:        c.MustGet("userProjectIDs").([]bson.ObjectId),
+		RequestID:         c.GetString("requestID"),
+	}
+	createOnePredictionDto.ProjectID = bson.ObjectIdHex(c.Param("projectID"))
+	createOnePredictionDto.ID = bson.NewObjectId()
+
+	isSuccess, err := CreateOnePrediction(&createOnePredictionDto, credentials)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{"success": isSuccess, "_id": createOnePredictionDto.ID, "jobID": createOnePredictionDto.JobID})
+}
+
+func updatePredictionController(c *gin.Context) {
+	var findOnePredictionDto FindOnePredictionDto
+	var updatePredictionDto UpdatePredictionDto
+
+	err := c.ShouldBindUri(&findOnePredictionDto)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	err = c.ShouldBindJSON(&updatePredictionDto)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	credentials := shared.Credentials{
+		Id:                c.MustGet("userID").(string),
+		HasPersonalScopes: c.MustGet("hasPersonalScopes").(bool),
+		HasProjectScopes:  c.MustGet("hasProjectScopes").(bool),
+		ProjectIDs:        c.MustGet("userProjectIDs").([]bson.ObjectId),
+		RequestID:         c.GetString("requestID"),
+	}
+
+	isSuccess, err := UpdatePrediction(&findOnePredictionDto, &updatePredictionDto, credentials)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": isSuccess})
+}
+
+func deletePredictionController(c *gin.Context) {
+	var deletePredictionDto DeletePredictionDto
+
+	err := c.ShouldBindUri(&deletePredictionDto)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	credentials := shared.Credentials{
+		Id:                c.MustGet("userID").(string),
+		HasPersonalScopes: c.MustGet("hasPersonalScopes").(bool),
+		HasProjectScopes:  c.MustGet("hasProjectScopes").(bool),
+		ProjectIDs:        c.MustGet("userProjectIDs").([]bson.ObjectId),
+		RequestID:         c.GetString("requestID"),
+	}
+
+	isSuccess, err := DeletePrediction(&deletePredictionDto, credentials)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": isSuccess})
+}
@@ -0,0 +1,72 @@
+package predictions
+
+import (
+	"time"
+
+	"github.com/khoa5773/go-server/src/genomics"
+	"gopkg.in/mgo.v2/bson"
+)
+
+type FindManyPredictionsDto struct {
+	ProjectID bson.ObjectId `json:"projectID,omitempty" bson:"projectID,omitempty" uri:"projectID" binding:"required,mongoid"`
+}
+
+type FilterPredictionsDto struct {
+	GenotypeID  bson.ObjectId    `json:"genotypeID,omitempty" bson:"genotypeID,omitempty" form:"genotypeID" binding:"mongoid"`
+	PhenotypeID bson.ObjectId    `json:"phenotypeID,omitempty" bson:"phenotypeID,omitempty" form:"phenotypeID" binding:"mongoid"`
+	ModelID     bson.ObjectId    `json:"modelID,omitempty" bson:"modelID,omitempty" form:"modelID" binding:"mongoid"`
+	Method      string           `json:"method,omitempty" bson:"method,omitempty" form:"method"`
+	Trait       string           `json:"trait,omitempty" bson:"trait,omitempty" form:"trait"`
+	Status      PredictionStatus `json:"status,omitempty" bson:"status,omitempty" form:"status"`
+}
+
+type FindOnePredictionDto struct {
+	ID        bson.ObjectId `json:"_id,omitempty" bson:"_id,omitempty" uri:"predictionID" binding:"required,mongoid"`
+	ProjectID bson.ObjectId `json:"projectID,omitempty" bson:"projectID,omitempty" uri:"projectID" binding:"required,mongoid"`
+}
+
+// CreateOnePredictionDto takes the settingSelection of the method when Parameters is empty. The "time"
+// parameter of LASSO and ELASTIC_NET is the λ search budget in minutes.
+type CreateOnePredictionDto struct {
+	ID               bson.ObjectId          `bson:"_id"`
+	Name             string                 `json:"name" bson:"name" binding:"required"`
+	Description      string                 `json:"description" bson:"description"`
+	GenotypeID       bson.ObjectId          `json:"genotypeID" bson:"genotypeID" binding:"required"`
+	PhenotypeID      bson.ObjectId          `json:"phenotypeID" bson:"phenotypeID" binding:"required"`
+	GenotypeVersion  int                    `json:"genotypeVersion" bson:"genotypeVersion,omitempty" binding:"omitempty,min=1"`
+	PhenotypeVersion int                    `json:"phenotypeVersion" bson:"phenotypeVersion,omitempty" binding:"omitempty,min=1"`
+	ModelID          bson.ObjectId          `bson:"modelID,omitempty"`
+	Method           string                 `json:"method" bson:"method" binding:"required,oneof=RBF_BLUF ELASTIC_NET LASSO G_BLUP"`
+	Parameters       map[string]interface{} `json:"parameters" bson:"parameters"`
+	Trait            string                 `json:"trait" bson:"trait"`
+	JobID            bson.ObjectId          `bson:"jobID"`
+	Status           PredictionStatus       `bson:"status"`
+	Error            string                 `bson:"error"`
+	Summary          map[string]float64     `bson:"summary"`
+	Results          []PredictionResult     `bson:"results"`
+	ProjectID        bson.ObjectId          `bson:"projectID"`
+	CreatedBy        string                 `bson:"createdBy"`
+	UpdatedBy        string                 `bson:"updatedBy"`
+	CreatedAt        time.Time              `bson:"createdAt"`
+	UpdatedAt        time.Time              `bson:"updatedAt"`
+}
+
+type MatchSamplesDto struct {
+	GenotypeID          bson.ObjectId                 `json:"genotypeID" binding:"required"`
+	PhenotypeID         bson.ObjectId                 `json:"phenotypeID" binding:"required"`
+	GenotypeVersion     int                           `json:"genotypeVersion" binding:"omitempty,min=1"`
+	PhenotypeVersion    int                           `json:"phenotypeVersion" binding:"omitempty,min=1"`
+	SampleNormalization *genomics.SampleNormalization `json:"sampleNormalization"`
+}
+
+type UpdatePredictionDto struct {
+	Name        string    `json:"name" bson:"name,omitempty"`
+	Description string    `json:"description" bson:"description,omitempty"`
+	UpdatedBy   string    `bson:"updatedBy,omitempty"`
+	UpdatedAt   time.Time `bson:"updatedAt,omitempty"`
+}
+
+type DeletePredictionDto struct {
+	ID        bson.ObjectId `json:"_id,omitempty" bson:"_id,omitempty" uri:"predictionID" binding:"required,mongoid"`
+	ProjectID bson.ObjectId `json:"projectID,omitempty" bson:"projectID,omitempty" uri:"projectID" binding:"required,mongoid"`
+}
@@ -0,0 +1,73 @@
+package predictions
+
+import (
+	"context"
+	"errors"
+
+	"github.com/khoa5773/go-server/src/domains/documents"
+	"github.com/khoa5773/go-server/src/domains/jobs"
+	"github.com/khoa5773/go-server/src/shared"
+	"gopkg.in/mgo.v2/bson"
+)
+
+const JOB_TYPE = "PREDICTION"
+
+type predictionJobHandler struct{}
+
+func init() {
+	jobs.RegisterHandler(JOB_TYPE, predictionJobHandler{})
+}
+
+func (predictionJobHandler) Run(ctx context.Context, job *jobs.Job, progress func(percentage int)) (map[string]interface{}, error) {
+	predictionID, ok := job.Payload["predictionID"].(bson.ObjectId)
+	if !ok {
+		return nil, jobs.Permanent(errors.New("job payload has no predictionID"))
+	}
+
+	credentials := shared.Credentials{Id: job.CreatedBy, IsAdmin: true}
+	prediction, err := FindOnePrediction(&FindOnePredictionDto{ID: predictionID, ProjectID: job.ProjectID}, credentials)
+	if err != nil {
+		return nil, err
+	}
+
+	err = SetPredictionStatus(predictionID, RUNNING, "")
+	if err != nil {
+		return nil, err
+	}
+
+	genotypeDocument, err := FindDocumentVersionOfType(prediction.GenotypeID, prediction.ProjectID, documents.GENOTYPE, prediction.GenotypeVersion, credentials)
+	if err != nil {
+		return nil, err
+	}
+
+	phenotypeDocument, err := FindDocumentVersionOfType(prediction.PhenotypeID, prediction.ProjectID, documents.PHENOTYPE, prediction.PhenotypeVersion, credentials)
+	if err != nil {
+		return nil, err
+	}
+
+	results, summary, err := RunPrediction(ctx, genotypeDocument, phenotypeDocument, prediction.Method, prediction.Parameters, prediction.Trait, progress)
+	if err != nil {
+		return nil, err
+	}
+
+	err = SetPredictionResults(predictionID, results, summary)
+	if err != nil {
+		return nil, err
+	}
+
+	return map[string]interface{}{"predictionID": predictionID}, nil
+}
+
+func (predictionJobHandler) Finish(job *jobs.Job) error {
+	return FinishPredictionJob(job)
+}
+
+// FinishPredictionJob copies the final job status and error on the prediction of the payload
+func FinishPredictionJob(job *jobs.Job) error {
+	predictionID, ok := job.Payload["predictionID"].(bson.ObjectId)
+	if !ok {
+		return nil
+	}
+
+	return SetPredictionStatus(predictionID, PredictionStatus(job.Status), job.Error)
+}
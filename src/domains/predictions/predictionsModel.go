@@ -0,0 +1,50 @@
+package predictions
+
+import (
+	"time"
+
+	"gopkg.in/mgo.v2/bson"
+)
+
+type PredictionStatus string
+
+// Statuses mirror the status of the prediction job
+const (
+	QUEUED    PredictionStatus = "QUEUED"
+	RUNNING   PredictionStatus = "RUNNING"
+	SUCCEEDED PredictionStatus = "SUCCEEDED"
+	FAILED    PredictionStatus = "FAILED"
+	CANCELLED PredictionStatus = "CANCELLED"
+)
+
+type PredictionResult struct {
+	SampleID  string   `json:"sampleID" bson:"sampleID"`
+	Predicted float64  `json:"predicted" bson:"predicted"`
+	Observed  *float64 `json:"observed" bson:"observed"`
+	Training  bool     `json:"training" bson:"training"`
+}
+
+// Prediction model
+type Prediction struct {
+	ID               bson.ObjectId          `json:"_id" bson:"_id"`
+	Name             string                 `json:"name" bson:"name"`
+	Description      string                 `json:"description" bson:"description"`
+	ProjectID        bson.ObjectId          `json:"projectID" bson:"projectID"`
+	GenotypeID       bson.ObjectId          `json:"genotypeID" bson:"genotypeID"`
+	PhenotypeID      bson.ObjectId          `json:"phenotypeID" bson:"phenotypeID"`
+	GenotypeVersion  int                    `json:"genotypeVersion" bson:"genotypeVersion,omitempty"`
+	PhenotypeVersion int                    `json:"phenotypeVersion" bson:"phenotypeVersion,omitempty"`
+	ModelID          bson.ObjectId          `json:"modelID" bson:"modelID,omitempty"`
+	Method           string                 `json:"method" bson:"method"`
+	Parameters       map[string]interface{} `json:"parameters" bson:"parameters"`
+	Trait            string                 `json:"trait" bson:"trait"`
+	JobID            bson.ObjectId          `json:"jobID" bson:"jobID"`
+	Status           PredictionStatus       `json:"status" bson:"status"`
+	Error            string                 `json:"error" bson:"error"`
+	Summary          map[string]float64     `json:"summary" bson:"summary"`
+	Results          []PredictionResult     `json:"results" bson:"results"`
+	CreatedBy        string                 `json:"createdBy" bson:"createdBy"`
+	CreatedAt        time.Time              `json:"createdAt" bson:"createdAt"`
+	UpdatedAt        time.Time              `json:"updatedAt" bson:"updatedAt"`
+	UpdatedBy        string                 `json:"updatedBy" bson:"updatedBy"`
+}
@@ -0,0 +1,421 @@
+package predictions
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/fatih/structs"
+	"github.com/khoa5773/go-server/src/domains/documents"
+	"github.com/khoa5773/go-server/src/domains/jobs"
+	"github.com/khoa5773/go-server/src/domains/logs"
+	"github.com/khoa5773/go-server/src/domains/projects"
+	"github.com/khoa5773/go-server/src/domains/users"
+	"github.com/khoa5773/go-server/src/genomics"
+	_ "github.com/khoa5773/go-server/src/genomics/gblup"
+	_ "github.com/khoa5773/go-server/src/genomics/penalized"
+	_ "github.com/khoa5773/go-server/src/genomics/rkhs"
+	"github.com/khoa5773/go-server/src/shared"
+	"github.com/mitchellh/mapstructure"
+	"gopkg.in/mgo.v2/bson"
+)
+
+func FindManyPredictions(findManyPredictionsDto FindManyPredictionsDto, filterPredictionsDto FilterPredictionsDto, credentials shared.Credentials) (shared.Result, error) {
+	var predictions []map[string]interface{}
+	PredictionsModel := shared.MongoSession.C("predictions")
+
+	query := bson.M{"projectID": findManyPredictionsDto.ProjectID}
+	if filterPredictionsDto.GenotypeID != "" {
+		query["genotypeID"] = filterPredictionsDto.GenotypeID
+	}
+	if filterPredictionsDto.PhenotypeID != "" {
+		query["phenotypeID"] = filterPredictionsDto.PhenotypeID
+	}
+	if filterPredictionsDto.ModelID != "" {
+		query["modelID"] = filterPredictionsDto.ModelID
+	}
+	if filterPredictionsDto.Method != "" {
+		query["method"] = filterPredictionsDto.Method
+	}
+	if filterPredictionsDto.Trait != "" {
+		query["trait"] = filterPredictionsDto.Trait
+	}
+	if filterPredictionsDto.Status != "" {
+		query["status"] = filterPredictionsDto.Status
+	}
+
+	err := PredictionsModel.Find(query).Select(bson.M{"results": 0}).Sort("-createdAt").All(&predictions)
+	if err != nil {
+		return shared.Result{}, err
+	}
+
+	data, err := shared.ValidateAccessToList(predictions, credentials)
+	if err != nil {
+		return shared.Result{}, err
+	}
+
+	return *data, nil
+}
+
+func FindOnePrediction(findOnePredictionDto *FindOnePredictionDto, credentials shared.Credentials) (Prediction, error) {
+	var prediction Prediction
+	PredictionsModel := shared.MongoSession.C("predictions")
+	err := PredictionsModel.Find(findOnePredictionDto).One(&prediction)
+	if err != nil {
+		return Prediction{}, err
+	}
+
+	data, err := shared.ValidateAccessToSingle(structs.Map(prediction), credentials)
+	if err != nil {
+		return Prediction{}, err
+	}
+
+	err = mapstructure.Decode(data, &prediction)
+	if err != nil {
+		return Prediction{}, err
+	}
+
+	return prediction, nil
+}
+
+// CreateOnePrediction stores the prediction as QUEUED and enqueues the job that fits it
+func CreateOnePrediction(createOnePredictionDto *CreateOnePredictionDto, credentials shared.Credentials) (bool, error) {
+	createOnePredictionDto.CreatedBy = credentials.Id
+	createOnePredictionDto.UpdatedBy = credentials.Id
+	createOnePredictionDto.CreatedAt = time.Now()
+	createOnePredictionDto.UpdatedAt = time.Now()
+
+	prediction := &Prediction{}
+	err := mapstructure.Decode(structs.Map(createOnePredictionDto), prediction)
+	if err != nil {
+		return false, err
+	}
+
+	_, err = shared.ValidateAccessToSingle(structs.Map(prediction), credentials)
+	if err != nil {
+		return false, err
+	}
+
+	genotypeDocument, err := FindDocumentVersionOfType(createOnePredictionDto.GenotypeID, createOnePredictionDto.ProjectID, documents.GENOTYPE, createOnePredictionDto.GenotypeVersion, credentials)
+	if err != nil {
+		return false, err
+	}
+
+	phenotypeDocument, err := FindDocumentVersionOfType(createOnePredictionDto.PhenotypeID, createOnePredictionDto.ProjectID, documents.PHENOTYPE, createOnePredictionDto.PhenotypeVersion, credentials)
+	if err != nil {
+		return false, err
+	}
+
+	createOnePredictionDto.Trait, err = documents.ResolveTrait(phenotypeDocument, createOnePredictionDto.Trait)
+	if err != nil {
+		return false, err
+	}
+
+	// the versions are recorded so that the prediction keeps using the data it was made with
+	createOnePredictionDto.GenotypeVersion = documents.CurrentVersion(genotypeDocument)
+	createOnePredictionDto.PhenotypeVersion = documents.CurrentVersion(phenotypeDocument)
+
+	if len(createOnePredictionDto.Parameters) == 0 {
+		createOnePredictionDto.Parameters, err = FindDefaultParameters(createOnePredictionDto.Method, credentials)
+		if err != nil {
+			return false, err
+		}
+	}
+
+	return EnqueuePrediction(createOnePredictionDto, JOB_TYPE, map[string]interface{}{}, credentials)
+}
+
+// EnqueuePrediction inserts the prediction as QUEUED and enqueues a job of the given type, the
+// predictionID is added to the payload. It is also used for predictions computed by other domains.
+func EnqueuePrediction(createOnePredictionDto *CreateOnePredictionDto, jobType string, payload map[string]interface{}, credentials shared.Credentials) (bool, error) {
+	createOnePredictionDto.JobID = bson.NewObjectId()
+	createOnePredictionDto.Status = QUEUED
+
+	PredictionsModel := shared.MongoSession.C("predictions")
+	err := PredictionsModel.Insert(createOnePredictionDto)
+	if err != nil {
+		return false, err
+	}
+
+	payload["predictionID"] = createOnePredictionDto.ID
+	_, err = jobs.Enqueue(&jobs.CreateOneJobDto{
+		ID:        createOnePredictionDto.JobID,
+		ProjectID: createOnePredictionDto.ProjectID,
+		Type:      jobType,
+		Payload:   payload,
+	}, credentials)
+	if err != nil {
+		_ = PredictionsModel.RemoveId(createOnePredictionDto.ID)
+		return false, err
+	}
+
+	logs.Record(credentials, createOnePredictionDto.ProjectID, logs.PREDICTION, createOnePredictionDto.ID.Hex(), logs.CREATE, nil, createOnePredictionDto)
+	return true, nil
+}
+
+func SetPredictionStatus(predictionID bson.ObjectId, status PredictionStatus, message string) error {
+	PredictionsModel := shared.MongoSession.C("predictions")
+	return PredictionsModel.UpdateId(predictionID, bson.M{"$set": bson.M{"status": status, "error": message, "updatedAt": time.Now()}})
+}
+
+func SetPredictionResults(predictionID bson.ObjectId, results []PredictionResult, summary map[string]float64) error {
+	PredictionsModel := shared.MongoSession.C("predictions")
+	return PredictionsModel.UpdateId(predictionID, bson.M{"$set": bson.M{"results": results, "summary": summary, "updatedAt": time.Now()}})
+}
+
+func UpdatePrediction(findOnePredictionDto *FindOnePredictionDto, updatePredictionDto *UpdatePredictionDto, credentials shared.Credentials) (bool, error) {
+	var prediction *Prediction
+	PredictionsModel := shared.MongoSession.C("predictions")
+
+	err := PredictionsModel.Find(findOnePredictionDto).One(&prediction)
+	if err != nil {
+		return false, err
+	}
+
+	_, err = shared.ValidateAccessToSingle(structs.Map(*prediction), credentials)
+	if err != nil {
+		return false, err
+	}
+
+	updatePredictionDto.UpdatedAt = time.Now()
+	updatePredictionDto.UpdatedBy = credentials.Id
+
+	err = PredictionsModel.Update(findOnePredictionDto, bson.M{"$set": updatePredictionDto})
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+func DeletePrediction(deletePredictionDto *DeletePredictionDto, credentials shared.Credentials) (bool, error) {
+	var prediction *Prediction
+	PredictionsModel := shared.MongoSession.C("predictions")
+	err := PredictionsModel.Find(deletePredictionDto).One(&prediction)
+	if err != nil {
+		return false, err
+	}
+
+	_, err = shared.ValidateAccessToSingle(structs.Map(*prediction), credentials)
+	if err != nil {
+		return false, err
+	}
+
+	if prediction.Status == QUEUED || prediction.Status == RUNNING {
+		return false, errors.New("prediction is still running, cancel its job first")
+	}
+
+	err = PredictionsModel.Remove(deletePredictionDto)
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+func FindDocumentOfType(documentID, projectID bson.ObjectId, documentType documents.DocumentType, credentials shared.Credentials) (documents.Document, error) {
+	document, err := documents.FindOneDocument(&documents.FindOneDocumentDto{ID: documentID, ProjectID: projectID}, credentials)
+	if err != nil {
+		return documents.Document{}, err
+	}
+
+	if document.Type != string(documentType) {
+		return documents.Document{}, fmt.Errorf("document %s is not a %s document", documentID.Hex(), documentType)
+	}
+
+	return document, nil
+}
+
+// FindDocumentVersionOfType is FindDocumentOfType with the content of the given version, the current
+// one when version is 0
+func FindDocumentVersionOfType(
+	documentID, projectID bson.ObjectId, documentType documents.DocumentType, version int, credentials shared.Credentials,
+) (documents.Document, error) {
+	document, err := FindDocumentOfType(documentID, projectID, documentType, credentials)
+	if err != nil || version == 0 {
+		return document, err
+	}
+
+	return documents.FindDocumentAtVersion(document, version)
+}
+
+// MatchSamples compares the sample IDs of a genotype and a phenotype of the project with the given
+// normalization, or the one saved on the project, and returns the normalization it applied
+func MatchSamples(
+	ctx context.Context, projectID bson.ObjectId, matchSamplesDto *MatchSamplesDto, credentials shared.Credentials,
+) (genomics.SampleMatch, *genomics.SampleNormalization, error) {
+	genotypeDocument, err := FindDocumentVersionOfType(matchSamplesDto.GenotypeID, projectID, documents.GENOTYPE, matchSamplesDto.GenotypeVersion, credentials)
+	if err != nil {
+		return genomics.SampleMatch{}, nil, err
+	}
+
+	phenotypeDocument, err := FindDocumentVersionOfType(matchSamplesDto.PhenotypeID, projectID, documents.PHENOTYPE, matchSamplesDto.PhenotypeVersion, credentials)
+	if err != nil {
+		return genomics.SampleMatch{}, nil, err
+	}
+
+	normalization := matchSamplesDto.SampleNormalization
+	if normalization == nil {
+		normalization, err = projects.SampleNormalization(projectID)
+		if err != nil {
+			return genomics.SampleMatch{}, nil, err
+		}
+	}
+
+	// documents uploaded before their samples were recorded are read
+	genotypeIDs := genotypeDocument.SampleIDs
+	if genotypeIDs == nil {
+		genotype, err := LoadGenotype(ctx, genotypeDocument)
+		if err != nil {
+			return genomics.SampleMatch{}, nil, err
+		}
+		genotypeIDs = genotype.SampleIDs
+	}
+
+	phenotypeIDs := phenotypeDocument.SampleIDs
+	if phenotypeIDs == nil {
+		phenotype, err := LoadPhenotype(ctx, phenotypeDocument)
+		if err != nil {
+			return genomics.SampleMatch{}, nil, err
+		}
+		phenotypeIDs = phenotype.SampleIDs
+	}
+
+	return genomics.MatchSamples(genotypeIDs, phenotypeIDs, normalization), normalization, nil
+}
+
+func FindDefaultParameters(method string, credentials shared.Credentials) (map[string]interface{}, error) {
+	credentials.IsAdmin = true
+	user, err := users.FindOneUser(&users.FindOneUserDto{ID: credentials.Id}, credentials)
+	if err != nil {
+		return nil, err
+	}
+
+	switch v := user.SettingSelection[method].(type) {
+	case bson.M:
+		return v, nil
+	case map[string]interface{}:
+		return v, nil
+	}
+
+	return map[string]interface{}{}, nil
+}
+
+func LoadGenotype(ctx context.Context, genotypeDocument documents.Document) (*genomics.Genotype, error) {
+	genotypeContent, err := documents.OpenDocumentContent(ctx, genotypeDocument)
+	if err != nil {
+		return nil, err
+	}
+	defer genotypeContent.Close()
+
+	var coding genomics.GenotypeCoding
+	if genotypeDocument.VariantSummary != nil {
+		coding = genotypeDocument.VariantSummary.Coding
+	}
+	genotype, err := genomics.ReadGenotype(genotypeContent, genotypeDocument.MimeType, coding)
+	if err != nil {
+		return nil, jobs.Permanent(fmt.Errorf("genotype %s: %v", genotypeDocument.Name, err))
+	}
+
+	return genotype, nil
+}
+
+func LoadPhenotype(ctx context.Context, phenotypeDocument documents.Document) (*genomics.Phenotype, error) {
+	phenotypeContent, err := documents.OpenDocumentContent(ctx, phenotypeDocument)
+	if err != nil {
+		return nil, err
+	}
+	defer phenotypeContent.Close()
+
+	phenotype, err := genomics.ReadPhenotype(phenotypeContent, phenotypeDocument.MimeType)
+	if err != nil {
+		return nil, jobs.Permanent(fmt.Errorf("phenotype %s: %v", phenotypeDocument.Name, err))
+	}
+
+	return phenotype, nil
+}
+
+// LoadDataset reads the genotype and the trait of the phenotype, whose samples are paired under the
+// sample normalization of the project
+func LoadDataset(ctx context.Context, genotypeDocument, phenotypeDocument documents.Document, trait string) (*genomics.Dataset, error) {
+	genotype, err := LoadGenotype(ctx, genotypeDocument)
+	if err != nil {
+		return nil, err
+	}
+
+	phenotype, err := LoadPhenotype(ctx, phenotypeDocument)
+	if err != nil {
+		return nil, err
+	}
+
+	normalization, err := projects.SampleNormalization(genotypeDocument.ProjectID)
+	if err != nil {
+		return nil, err
+	}
+
+	dataset, err := genomics.NewDataset(genotype, phenotype, trait, normalization)
+	if err != nil {
+		return nil, jobs.Permanent(err)
+	}
+
+	return dataset, nil
+}
+
+// RunPrediction fits the method on the phenotyped samples and predicts every genotyped sample,
+// progress is reported once the data is loaded and once the model is fitted
+func RunPrediction(
+	ctx context.Context, genotypeDocument, phenotypeDocument documents.Document, method string, parameters map[string]interface{}, trait string,
+	progress func(percentage int),
+) ([]PredictionResult, map[string]float64, error) {
+	predictionMethod, err := genomics.GetMethod(method)
+	if err != nil {
+		return nil, nil, jobs.Permanent(err)
+	}
+
+	dataset, err := LoadDataset(ctx, genotypeDocument, phenotypeDocument, trait)
+	if err != nil {
+		return nil, nil, err
+	}
+	progress(20)
+
+	model, err := genomics.Fit(ctx, predictionMethod, dataset, dataset.TrainingRows(), parameters)
+	if err != nil {
+		return nil, nil, jobs.Permanent(err)
+	}
+	progress(80)
+
+	predicted, err := model.Predict(dataset.Genotype)
+	if err != nil {
+		return nil, nil, jobs.Permanent(err)
+	}
+
+	results, err := BuildPredictionResults(dataset, predicted)
+	if err != nil {
+		return nil, nil, jobs.Permanent(err)
+	}
+
+	return results, model.Summary(), nil
+}
+
+func BuildPredictionResults(dataset *genomics.Dataset, predicted []float64) ([]PredictionResult, error) {
+	if len(predicted) != dataset.Genotype.NumSamples() {
+		return nil, errors.New("prediction count does not match genotype samples")
+	}
+
+	results := make([]PredictionResult, len(predicted))
+	for i, v := range predicted {
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return nil, fmt.Errorf("invalid prediction for sample %s", dataset.Genotype.SampleIDs[i])
+		}
+
+		results[i] = PredictionResult{SampleID: dataset.Genotype.SampleIDs[i], Predicted: v}
+		if observed := dataset.Observed[i]; !math.IsNaN(observed) {
+			results[i].Observed = &observed
+			results[i].Training = true
+		}
+	}
+
+	return results, nil
+}
@@ -34,6 +34,7 @@ func findManyProjectsController(c *gin.Context) {
 		HasPersonalScopes: c.MustGet("hasPersonalScopes").(bool),
 		HasProjectScopes:  c.MustGet("hasProjectScopes").(bool),
 		ProjectIDs:        c.MustGet("userProjectIDs").([]bson.ObjectId),
+		RequestID:         c.GetString("requestID"),
 	}
 
 	projects, err := FindManyProjects(projectIDs, credentials)
@@ -58,6 +59,7 @@ func findOneProjectController(c *gin.Context) {
 		HasPersonalScopes: c.MustGet("hasPersonalScopes").(bool),
 		HasProjectScopes:  c.MustGet("hasProjectScopes").(bool),
 		ProjectIDs:        c.MustGet("userProjectIDs").([]bson.ObjectId),
+		RequestID:         c.GetString("requestID"),
 	}
 
 	project, err := FindOneProject(&findOneProjectDto, credentials)
@@ -82,6 +84,7 @@ func createProjectController(c *gin.Context) {
 		HasPersonalScopes: c.MustGet("hasPersonalScopes").(bool),
 		HasProjectScopes:  c.MustGet("hasProjectScopes").(bool),
 		ProjectIDs:        c.MustGet("userProjectIDs").([]bson.ObjectId),
+		RequestID:         c.GetString("requestID"),
 	}
 
 	projectID := bson.NewObjectId()
@@ -134,6 +137,7 @@ func updateProjectController(c *gin.Context) {
 		HasPersonalScopes: c.MustGet("hasPersonalScopes").(bool),
 		HasProjectScopes:  c.MustGet("hasProjectScopes").(bool),
 		ProjectIDs:        c.MustGet("userProjectIDs").([]bson.ObjectId),
+		RequestID:         c.GetString("requestID"),
 	}
 
 	isSuccess, err := UpdateProject(&findOneProjectDto, &updateProjectDto, credentials)
@@ -159,6 +163,7 @@ func deleteProjectController(c *gin.Context) {
 		HasPersonalScopes: c.MustGet("hasPersonalScopes").(bool),
 		HasProjectScopes:  c.MustGet("hasProjectScopes").(bool),
 		ProjectIDs:        c.MustGet("userProjectIDs").([]bson.ObjectId),
+		RequestID:         c.GetString("requestID"),
 	}
 
 	isSuccess, userIDs, err := DeleteProject(&deleteProjectDto, credentials)
@@ -205,6 +210,7 @@ func addProjectMembersController(c *gin.Context) {
 		HasPersonalScopes: c.MustGet("hasPersonalScopes").(bool),
 		HasProjectScopes:  c.MustGet("hasProjectScopes").(bool),
 		ProjectIDs:        c.MustGet("userProjectIDs").([]bson.ObjectId),
+		RequestID:         c.GetString("requestID"),
 	}
 
 	usersData, err := users.FindMany(&users.FindManyDto{UserIDs: addProjectMembersDto.MemberIDs})
@@ -263,6 +269,7 @@ func removeProjectMembersController(c *gin.Context) {
 		HasPersonalScopes: c.MustGet("hasPersonalScopes").(bool),
 		HasProjectScopes:  c.MustGet("hasProjectScopes").(bool),
 		ProjectIDs:        c.MustGet("userProjectIDs").([]bson.ObjectId),
+		RequestID:         c.GetString("requestID"),
 	}
 
 	isSuccess, err := RemoveProjectMembers(&findOneProjectDto, &removeProjectMembersDto, credentials)
@@ -303,6 +310,7 @@ func addProjectManagersController(c *gin.Context) {
 		HasPersonalScopes: c.MustGet("hasPersonalScopes").(bool),
 		HasProjectScopes:  c.MustGet("hasProjectScopes").(bool),
 		ProjectIDs:        c.MustGet("userProjectIDs").([]bson.ObjectId),
+		RequestID:         c.GetString("requestID"),
 	}
 
 	isSuccess, err := AddProjectManagers(&findOneProjectDto, &addProjectManagersDto, credentials)
@@ -344,6 +352,7 @@ func removeProjectManagersController(c *gin.Context) {
 		HasPersonalScopes: c.MustGet("hasPersonalScopes").(bool),
 		HasProjectScopes:  c.MustGet("hasProjectScopes").(bool),
 		ProjectIDs:        c.MustGet("userProjectIDs").([]bson.ObjectId),
+		RequestID:         c.GetString("requestID"),
 	}
 
 	isSuccess, err := RemoveProjectManagers(&findOneProjectDto, &removeProjectManagersDto, credentials)
@@ -3,6 +3,7 @@ package projects
 import (
 	"time"
 
+	"github.com/khoa5773/go-server/src/genomics"
 	"gopkg.in/mgo.v2/bson"
 )
 
@@ -11,22 +12,28 @@ type FindOneProjectDto struct {
 }
 
 type CreateOneProjectDto struct {
-	ID          bson.ObjectId `bson:"_id"`
-	Name        string        `json:"name" bson:"name" binding:"required"`
-	Description string        `json:"description" bson:"description"`
-	CreatedBy   string        `json:"createdBy" bson:"createdBy"`
-	CreatedAt   time.Time     `bson:"createdAt"`
-	UpdatedAt   time.Time     `bson:"updatedAt"`
-	MemberIDs   []string      `bson:"memberIDs" binding:"unique"`
-	ManagerIDs  []string      `bson:"managerIDs" binding:"unique"`
+	ID            bson.ObjectId `bson:"_id"`
+	Name          string        `json:"name" bson:"name" binding:"required"`
+	Description   string        `json:"description" bson:"description"`
+	CreatedBy     string        `json:"createdBy" bson:"createdBy"`
+	CreatedAt     time.Time     `bson:"createdAt"`
+	UpdatedAt     time.Time     `bson:"updatedAt"`
+	MemberIDs     []string      `bson:"memberIDs" binding:"unique"`
+	ManagerIDs    []string      `bson:"managerIDs" binding:"unique"`
+	MaxUploadSize int64         `json:"maxUploadSize" bson:"maxUploadSize,omitempty" binding:"omitempty,min=1"`
 }
 
+// UpdateProjectDto only sets the fields it is sent, ClearSampleNormalization removes the sample
+// normalization of the project
 type UpdateProjectDto struct {
-	Name        string    `json:"name" bson:"name,omitempty"`
-	Description string    `json:"description" bson:"description,omitempty"`
-	CreatedBy   string    `bson:"createdBy,omitempty"`
-	CreatedAt   time.Time `bson:"createdAt,omitempty"`
-	UpdatedAt   time.Time `bson:"updatedAt,omitempty"`
+	Name                     string                        `json:"name" bson:"name,omitempty"`
+	Description              string                        `json:"description" bson:"description,omitempty"`
+	MaxUploadSize            int64                         `json:"maxUploadSize" bson:"maxUploadSize,omitempty" binding:"omitempty,min=1"`
+	SampleNormalization      *genomics.SampleNormalization `json:"sampleNormalization" bson:"sampleNormalization,omitempty"`
+	ClearSampleNormalization bool                          `json:"clearSampleNormalization" bson:"-"`
+	CreatedBy                string                        `bson:"createdBy,omitempty"`
+	CreatedAt                time.Time                     `bson:"createdAt,omitempty"`
+	UpdatedAt                time.Time                     `bson:"updatedAt,omitempty"`
 }
 
 type DeleteProjectDto struct {
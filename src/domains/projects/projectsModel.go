@@ -3,6 +3,7 @@ package projects
 import (
 	"time"
 
+	"github.com/khoa5773/go-server/src/genomics"
 	"gopkg.in/mgo.v2/bson"
 )
 
@@ -16,4 +17,8 @@ type Project struct {
 	UpdatedAt   time.Time     `json:"updatedAt" bson:"updatedAt"`
 	MemberIDs   []string      `json:"memberIDs" bson:"memberIDs"`
 	ManagerIDs  []string      `json:"managerIDs" bson:"managerIDs"`
+	// MaxUploadSize in bytes, the server limit applies when it is 0 or larger
+	MaxUploadSize int64 `json:"maxUploadSize" bson:"maxUploadSize,omitempty"`
+	// SampleNormalization is applied when the samples of genotypes and phenotypes are matched
+	SampleNormalization *genomics.SampleNormalization `json:"sampleNormalization,omitempty" bson:"sampleNormalization,omitempty"`
 }
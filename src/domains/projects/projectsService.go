@@ -6,8 +6,11 @@ import (
 	"time"
 
 	"github.com/fatih/structs"
+	"github.com/khoa5773/go-server/src/configs"
+	"github.com/khoa5773/go-server/src/domains/logs"
 	"github.com/khoa5773/go-server/src/domains/repositories"
 	"github.com/khoa5773/go-server/src/domains/users"
+	"github.com/khoa5773/go-server/src/genomics"
 	"github.com/khoa5773/go-server/src/helpers"
 	"github.com/khoa5773/go-server/src/shared"
 	"github.com/mitchellh/mapstructure"
@@ -116,12 +119,17 @@ func FindOneProject(findOneProjectDto *FindOneProjectDto, credentials shared.Cre
 func CreateOneProject(createOneProjectDto *CreateOneProjectDto, credentials shared.Credentials) (bool, error) {
 	createOneProjectDto.CreatedBy = credentials.Id
 
+	err := checkMaxUploadSize(createOneProjectDto.MaxUploadSize)
+	if err != nil {
+		return false, err
+	}
+
 	createOneProjectDto.CreatedAt = time.Now()
 	createOneProjectDto.UpdatedAt = time.Now()
 
 	project := &Project{}
 	test := (structs.Map(createOneProjectDto))
-	err := mapstructure.Decode(test, project)
+	err = mapstructure.Decode(test, project)
 	if err != nil {
 		return false, err
 	}
@@ -137,6 +145,7 @@ func CreateOneProject(createOneProjectDto *CreateOneProjectDto, credentials shar
 		return false, err
 	}
 
+	logs.Record(credentials, createOneProjectDto.ID, logs.PROJECT, createOneProjectDto.ID.Hex(), logs.CREATE, nil, findProject(createOneProjectDto.ID))
 	return true, nil
 }
 
@@ -156,13 +165,27 @@ func UpdateProject(findOneProjectDto *FindOneProjectDto, updateProjectDto *Updat
 
 	_ = mapstructure.Decode(data, project)
 
+	err = checkMaxUploadSize(updateProjectDto.MaxUploadSize)
+	if err != nil {
+		return false, err
+	}
+
+	update := bson.M{"$set": updateProjectDto}
+	if updateProjectDto.ClearSampleNormalization {
+		if updateProjectDto.SampleNormalization != nil {
+			return false, errors.New("sampleNormalization can not be set and cleared at once")
+		}
+		update["$unset"] = bson.M{"sampleNormalization": ""}
+	}
+
 	updateProjectDto.UpdatedAt = time.Now()
 
-	err = ProjectsModel.Update(findOneProjectDto, bson.M{"$set": updateProjectDto})
+	err = ProjectsModel.Update(findOneProjectDto, update)
 	if err != nil {
 		return false, err
 	}
 
+	logs.Record(credentials, project.ID, logs.PROJECT, project.ID.Hex(), logs.UPDATE, project, findProject(project.ID))
 	return true, nil
 }
 
@@ -188,6 +211,7 @@ func DeleteProject(deleteProjectDto *DeleteProjectDto, credentials shared.Creden
 		return false, []string{}, err
 	}
 
+	logs.Record(credentials, project.ID, logs.PROJECT, project.ID.Hex(), logs.DELETE, project, nil)
 	return true, userIDs, nil
 
 }
@@ -213,6 +237,7 @@ func AddProjectMembers(findOneProjectDto *FindOneProjectDto, addProjectMembersDt
 		}
 	}
 
+	logs.Record(credentials, project.ID, logs.PROJECT, project.ID.Hex(), logs.ADD_MEMBERS, project, findProject(project.ID))
 	return true, nil
 }
 
@@ -237,6 +262,7 @@ func RemoveProjectMembers(findOneProjectDto *FindOneProjectDto, removeProjectMem
 		}
 	}
 
+	logs.Record(credentials, project.ID, logs.PROJECT, project.ID.Hex(), logs.REMOVE_MEMBERS, project, findProject(project.ID))
 	return true, nil
 }
 
@@ -270,6 +296,7 @@ func AddProjectManagers(findOneProjectDto *FindOneProjectDto, addProjectManagers
 		return false, err
 	}
 
+	logs.Record(credentials, project.ID, logs.PROJECT, project.ID.Hex(), logs.ADD_MANAGERS, project, findProject(project.ID))
 	return true, nil
 }
 
@@ -294,9 +321,56 @@ func RemoveProjectManagers(findOneProjectDto *FindOneProjectDto, removeProjectMa
 		}
 	}
 
+	logs.Record(credentials, project.ID, logs.PROJECT, project.ID.Hex(), logs.REMOVE_MANAGERS, project, findProject(project.ID))
 	return true, nil
 }
 
+// MaxUploadSize is the largest file in bytes that can be uploaded to the project, a project limit
+// can only lower the MAX_UPLOAD_SIZE of the server
+func MaxUploadSize(projectID bson.ObjectId) (int64, error) {
+	var project Project
+	ProjectsModel := shared.MongoSession.C("projects")
+	err := ProjectsModel.FindId(projectID).Select(bson.M{"maxUploadSize": 1}).One(&project)
+	if err != nil {
+		return 0, err
+	}
+
+	if project.MaxUploadSize > 0 && project.MaxUploadSize < configs.ConfigsService.MaxUploadSize {
+		return project.MaxUploadSize, nil
+	}
+	return configs.ConfigsService.MaxUploadSize, nil
+}
+
+func checkMaxUploadSize(maxUploadSize int64) error {
+	if maxUploadSize > configs.ConfigsService.MaxUploadSize {
+		return fmt.Errorf("maxUploadSize can not exceed the server limit of %d bytes", configs.ConfigsService.MaxUploadSize)
+	}
+	return nil
+}
+
+// SampleNormalization returns the rules the project applies to sample IDs, nil when it has none
+func SampleNormalization(projectID bson.ObjectId) (*genomics.SampleNormalization, error) {
+	var project Project
+	ProjectsModel := shared.MongoSession.C("projects")
+	err := ProjectsModel.FindId(projectID).Select(bson.M{"sampleNormalization": 1}).One(&project)
+	if err != nil {
+		return nil, err
+	}
+
+	return project.SampleNormalization, nil
+}
+
+// findProject reads a project back for the audit log, nil when it can not be read
+func findProject(projectID bson.ObjectId) *Project {
+	var project Project
+	ProjectsModel := shared.MongoSession.C("projects")
+	err := ProjectsModel.FindId(projectID).One(&project)
+	if err != nil {
+		return nil
+	}
+	return &project
+}
+
 func CheckValidUsers(findOneProjectDto *FindOneProjectDto, users []*users.User) (bool, error) {
 	var project *Project
 	ProjectsModel := shared.MongoSession.C("projects")
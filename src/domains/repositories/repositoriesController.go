@@ -32,6 +32,7 @@ func findManyRepositoriesController(c *gin.Context) {
 		HasPersonalScopes: c.MustGet("hasPersonalScopes").(bool),
 		HasProjectScopes:  c.MustGet("hasProjectScopes").(bool),
 		ProjectIDs:        c.MustGet("userProjectIDs").([]bson.ObjectId),
+		RequestID:         c.GetString("requestID"),
 	}
 
 	repositories, err := FindManyRepositories(findManyRepositoriesDto, credentials)
@@ -56,6 +57,7 @@ func findOneRepositoryController(c *gin.Context) {
 		HasPersonalScopes: c.MustGet("hasPersonalScopes").(bool),
 		HasProjectScopes:  c.MustGet("hasProjectScopes").(bool),
 		ProjectIDs:        c.MustGet("userProjectIDs").([]bson.ObjectId),
+		RequestID:         c.GetString("requestID"),
 	}
 
 	repository, err := FindOneRepository(&findOneRepositoryDto, credentials)
@@ -80,6 +82,7 @@ func createRepositoryController(c *gin.Context) {
 		HasPersonalScopes: c.MustGet("hasPersonalScopes").(bool),
 		HasProjectScopes:  c.MustGet("hasProjectScopes").(bool),
 		ProjectIDs:        c.MustGet("userProjectIDs").([]bson.ObjectId),
+		RequestID:         c.GetString("requestID"),
 	}
 	createOneRepositoryDto.ProjectID = bson.ObjectIdHex(c.Param("projectID"))
 
@@ -113,6 +116,7 @@ func updateRepositoryController(c *gin.Context) {
 		HasPersonalScopes: c.MustGet("hasPersonalScopes").(bool),
 		HasProjectScopes:  c.MustGet("hasProjectScopes").(bool),
 		ProjectIDs:        c.MustGet("userProjectIDs").([]bson.ObjectId),
+		RequestID:         c.GetString("requestID"),
 	}
 
 	isSuccess, err := UpdateRepository(&findOneRepositoryDto, &updateRepositoryDto, credentials)
@@ -138,6 +142,7 @@ func deleteRepositoryController(c *gin.Context) {
 		HasPersonalScopes: c.MustGet("hasPersonalScopes").(bool),
 		HasProjectScopes:  c.MustGet("hasProjectScopes").(bool),
 		ProjectIDs:        c.MustGet("userProjectIDs").([]bson.ObjectId),
+		RequestID:         c.GetString("requestID"),
 	}
 
 	isSuccess, err := DeleteRepository(&deleteRepositoryDto, credentials)
@@ -1,10 +1,14 @@
 package repositories
 
 import (
+	"context"
 	"errors"
+	"log"
 	"time"
 
 	"github.com/fatih/structs"
+	cloudstorage "github.com/khoa5773/go-server/src/domains/cloudStorage"
+	"github.com/khoa5773/go-server/src/domains/logs"
 	"github.com/khoa5773/go-server/src/shared"
 	"github.com/mitchellh/mapstructure"
 	"gopkg.in/mgo.v2/bson"
@@ -90,6 +94,7 @@ func CreateOneRepository(createOneRepositoryDto *CreateOneRepositoryDto, credent
 		}
 	}
 
+	logs.Record(credentials, repository.ProjectID, logs.REPOSITORY, createOneRepositoryDto.ID.Hex(), logs.CREATE, nil, findRepository(createOneRepositoryDto.ID))
 	return true, nil
 }
 
@@ -127,6 +132,7 @@ func UpdateRepository(findOneRepositoryDto *FindOneRepositoryDto, updateReposito
 	}
 
 	if updateRepositoryDto.ParentRepositoryID == "" {
+		logs.Record(credentials, repository.ProjectID, logs.REPOSITORY, repository.ID.Hex(), logs.UPDATE, repository, findRepository(repository.ID))
 		return true, nil
 	}
 
@@ -147,6 +153,7 @@ func UpdateRepository(findOneRepositoryDto *FindOneRepositoryDto, updateReposito
 		return false, err
 	}
 
+	logs.Record(credentials, repository.ProjectID, logs.REPOSITORY, repository.ID.Hex(), logs.UPDATE, repository, findRepository(repository.ID))
 	return true, nil
 }
 
@@ -187,9 +194,37 @@ func DeleteRepository(deleteRepositoryDto *DeleteRepositoryDto, credentials shar
 		return false, err
 	}
 
+	logs.Record(credentials, repository.ProjectID, logs.REPOSITORY, repository.ID.Hex(), logs.DELETE, repository, nil)
 	return true, nil
 }
 
+// findRepository reads a repository back for the audit log, nil when it can not be read
+func findRepository(repositoryID bson.ObjectId) *Repository {
+	var repository Repository
+	RepositoriesModel := shared.MongoSession.C("repositories")
+	err := RepositoriesModel.FindId(repositoryID).One(&repository)
+	if err != nil {
+		return nil
+	}
+	return &repository
+}
+
+// FindRepositoryNames maps the given repositories to their names
+func FindRepositoryNames(repositoryIDs []bson.ObjectId) (map[bson.ObjectId]string, error) {
+	var repositories []Repository
+	RepositoriesModel := shared.MongoSession.C("repositories")
+	err := RepositoriesModel.Find(bson.M{"_id": bson.M{"$in": repositoryIDs}}).Select(bson.M{"name": 1}).All(&repositories)
+	if err != nil {
+		return nil, err
+	}
+
+	names := map[bson.ObjectId]string{}
+	for _, repository := range repositories {
+		names[repository.ID] = repository.Name
+	}
+	return names, nil
+}
+
 func CheckRepositoriesExists(repositoryIDs []bson.ObjectId, projectID bson.ObjectId) (bool, error) {
 	if repositoryIDs == nil || len(repositoryIDs) == 0 || repositoryIDs[0] == "" {
 		return true, nil
@@ -400,18 +435,69 @@ func RemoveDocumentIDs(repositoryID, projectID bson.ObjectId, documentIDs []bson
 }
 
 func DeleteManyDocuments(documentIDs []bson.ObjectId, repositoryID, projectID bson.ObjectId, credentials shared.Credentials) (bool, error) {
-	DocumentsModel := shared.MongoSession.C("documents")
-	_, err := DocumentsModel.RemoveAll(bson.M{
+	query := bson.M{
 		"_id": bson.M{
 			"$in": documentIDs,
 		},
 		"repositoryID": repositoryID,
 		"projectID":    projectID,
-	})
+	}
 
+	var contents []struct {
+		ID      bson.ObjectId `bson:"_id"`
+		SHA256  string        `bson:"sha256"`
+		BlobKey string        `bson:"blobKey"`
+		Version int           `bson:"version"`
+	}
+	DocumentsModel := shared.MongoSession.C("documents")
+	err := DocumentsModel.Find(query).Select(bson.M{"sha256": 1, "blobKey": 1, "version": 1}).All(&contents)
 	if err != nil {
 		return false, err
 	}
 
+	// documents with versions hold a blob reference per version, the others one for their content
+	versionedIDs := []bson.ObjectId{}
+	blobs := []string{}
+	for _, content := range contents {
+		if content.Version > 0 {
+			versionedIDs = append(versionedIDs, content.ID)
+		} else if content.BlobKey != "" {
+			blobs = append(blobs, content.SHA256)
+		}
+	}
+
+	var versions []struct {
+		SHA256  string `bson:"sha256"`
+		BlobKey string `bson:"blobKey"`
+	}
+	DocumentVersionsModel := shared.MongoSession.C("documentVersions")
+	err = DocumentVersionsModel.Find(bson.M{"documentID": bson.M{"$in": versionedIDs}}).Select(bson.M{"sha256": 1, "blobKey": 1}).All(&versions)
+	if err != nil {
+		return false, err
+	}
+	for _, version := range versions {
+		if version.BlobKey != "" {
+			blobs = append(blobs, version.SHA256)
+		}
+	}
+
+	_, err = DocumentsModel.RemoveAll(query)
+	if err != nil {
+		return false, err
+	}
+
+	_, err = DocumentVersionsModel.RemoveAll(bson.M{"documentID": bson.M{"$in": versionedIDs}})
+	if err != nil {
+		return false, err
+	}
+
+	// the documents are gone already, a blob failing to be released only costs storage
+	for _, sha256 := range blobs {
+		err = cloudstorage.ReleaseBlob(context.Background(), sha256)
+		if err != nil {
+			log.Println("repositories: releasing blob", sha256, "failed:", err)
+		}
+	}
+
 	return true, nil
 }
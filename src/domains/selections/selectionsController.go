@@ -0,0 +1,195 @@
+package selections
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+
+	"github.com/gin-gonic/gin"
+	"github.com/khoa5773/go-server/src/middleware/auth"
+	"github.com/khoa5773/go-server/src/middleware/authz"
+	"github.com/khoa5773/go-server/src/shared"
+	"gopkg.in/mgo.v2/bson"
+)
+
+var unsafeFileNameCharacters = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+func ApplyRoutes(r *gin.Engine) {
+	SelectionsControllers := r.Group("projects/:projectID/selections")
+	SelectionsControllers.GET("", auth.JWTRequired, authz.Scopes([]string{"selections:read", "proj:selections:read"}), findManySelectionsController)
+	SelectionsControllers.POST("", auth.JWTRequired, authz.Scopes([]string{"selections:create", "proj:selections:create"}), createSelectionController)
+	SelectionsControllers.GET("/:selectionID", auth.JWTRequired, authz.Scopes([]string{"selections:read", "proj:selections:read"}), findOneSelectionController)
+	SelectionsControllers.GET("/:selectionID/export", auth.JWTRequired, authz.Scopes([]string{"selections:read", "proj:selections:read"}), exportSelectionController)
+	SelectionsControllers.PUT("/:selectionID", auth.JWTRequired, authz.Scopes([]string{"selections:update", "proj:selections:update"}), updateSelectionController)
+	SelectionsControllers.DELETE("/:selectionID", auth.JWTRequired, authz.Scopes([]string{"selections:delete", "proj:selections:delete"}), deleteSelectionController)
+}
+
+func findManySelectionsController(c *gin.Context) {
+	var findManySelectionsDto FindManySelectionsDto
+	err := c.ShouldBindUri(&findManySelectionsDto)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	credentials := shared.Credentials{
+		Id:                c.MustGet("userID").(string),
+		HasPersonalScopes: c.MustGet("hasPersonalScopes").(bool),
+		HasProjectScopes:  c.MustGet("hasProjectScopes").(bool),
+		ProjectIDs:        c.MustGet("userProjectIDs").([]bson.ObjectId),
+		RequestID:         c.GetString("requestID"),
+	}
+
+	selections, err := FindManySelections(findManySelectionsDto, credentials)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"selections": selections})
+}
+
+func findOneSelectionController(c *gin.Context) {
+	var findOneSelectionDto FindOneSelectionDto
+	err := c.ShouldBindUri(&findOneSelectionDto)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	credentials := shared.Credentials{
+		Id:                c.MustGet("userID").(string),
+		HasPersonalScopes: c.MustGet("hasPersonalScopes").(bool),
+		HasProjectScopes:  c.MustGet("hasProjectScopes").(bool),
+		ProjectIDs:        c.MustGet("userProjectIDs").([]bson.ObjectId),
+		RequestID:         c.GetString("requestID"),
+	}
+
+	selection, err := FindOneSelection(&findOneSelectionDto, credentials)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"selection": selection})
+}
+
+func exportSelectionController(c *gin.Context) {
+	var findOneSelectionDto FindOneSelectionDto
+	err := c.ShouldBindUri(&findOneSelectionDto)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	credentials := shared.Credentials{
+		Id:                c.MustGet("userID").(string),
+		HasPersonalScopes: c.MustGet("hasPersonalScopes").(bool),
+		HasProjectScopes:  c.MustGet("hasProjectScopes").(bool),
+		ProjectIDs:        c.MustGet("userProjectIDs").([]bson.ObjectId),
+		RequestID:         c.GetString("requestID"),
+	}
+
+	selection, err := FindOneSelection(&findOneSelectionDto, credentials)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	fileName := unsafeFileNameCharacters.ReplaceAllString(selection.Name, "_")
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.csv"`, fileName))
+	c.Status(http.StatusOK)
+
+	err = ExportSelection(c.Writer, selection)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+}
+
+func createSelectionController(c *gin.Context) {
+	var createOneSelectionDto CreateOneSelectionDto
+	err := c.ShouldBindJSON(&createOneSelectionDto)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	credentials := shared.Credentials{
+		Id:                c.MustGet("userID").(string),
+		HasPersonalScopes: c.MustGet("hasPersonalScopes").(bool),
+		HasProjectScopes:  c.MustGet("hasProjectScopes").(bool),
+		ProjectIDs:        c.MustGet("userProjectIDs").([]bson.ObjectId),
+		RequestID:         c.GetString("requestID"),
+	}
+	createOneSelectionDto.ProjectID = bson.ObjectIdHex(c.Param("projectID"))
+	createOneSelectionDto.ID = bson.NewObjectId()
+
+	isSuccess, err := CreateOneSelection(&createOneSelectionDto, credentials)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"success": isSuccess, "_id": createOneSelectionDto.ID})
+}
+
+func updateSelectionController(c *gin.Context) {
+	var findOneSelectionDto FindOneSelectionDto
+	var updateSelectionDto UpdateSelectionDto
+
+	err := c.ShouldBindUri(&findOneSelectionDto)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	err = c.ShouldBindJSON(&updateSelectionDto)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	credentials := shared.Credentials{
+		Id:                c.MustGet("userID").(string),
+		HasPersonalScopes: c.MustGet("hasPersonalScopes").(bool),
+		HasProjectScopes:  c.MustGet("hasProjectScopes").(bool),
+		ProjectIDs:        c.MustGet("userProjectIDs").([]bson.ObjectId),
+		RequestID:         c.GetString("requestID"),
+	}
+
+	isSuccess, err := UpdateSelection(&findOneSelectionDto, &updateSelectionDto, credentials)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": isSuccess})
+}
+
+func deleteSelectionController(c *gin.Context) {
+	var deleteSelectionDto DeleteSelectionDto
+
+	err := c.ShouldBindUri(&deleteSelectionDto)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	credentials := shared.Credentials{
+		Id:                c.MustGet("userID").(string),
+		HasPersonalScopes: c.MustGet("hasPersonalScopes").(bool),
+		HasProjectScopes:  c.MustGet("hasProjectScopes").(bool),
+		ProjectIDs:        c.MustGet("userProjectIDs").([]bson.ObjectId),
+		RequestID:         c.GetString("requestID"),
+	}
+
+	isSuccess, err := DeleteSelection(&deleteSelectionDto, credentials)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": isSuccess})
+}
@@ -0,0 +1,54 @@
+package selections
+
+import (
+	"time"
+
+	"github.com/khoa5773/go-server/src/genomics/selection"
+	"gopkg.in/mgo.v2/bson"
+)
+
+type FindManySelectionsDto struct {
+	ProjectID bson.ObjectId `json:"projectID,omitempty" bson:"projectID,omitempty" uri:"projectID" binding:"required,mongoid"`
+}
+
+type FindOneSelectionDto struct {
+	ID        bson.ObjectId `json:"_id,omitempty" bson:"_id,omitempty" uri:"selectionID" binding:"required,mongoid"`
+	ProjectID bson.ObjectId `json:"projectID,omitempty" bson:"projectID,omitempty" uri:"projectID" binding:"required,mongoid"`
+}
+
+// CreateOneSelectionDto takes Count for TOP_K and Percent for TOP_PERCENT. Families assigns samples to
+// their family for MaxPerFamily, the assignment of the ranked samples is saved with the selection.
+type CreateOneSelectionDto struct {
+	ID              bson.ObjectId         `bson:"_id"`
+	Name            string                `json:"name" bson:"name" binding:"required"`
+	Description     string                `json:"description" bson:"description"`
+	Rationale       string                `json:"rationale" bson:"rationale" binding:"required"`
+	Criteria        []Criterion           `json:"criteria" bson:"criteria" binding:"required,min=1,dive"`
+	Standardize     bool                  `json:"standardize" bson:"standardize"`
+	Mode            SelectionMode         `json:"mode" bson:"mode" binding:"required,oneof=TOP_K TOP_PERCENT"`
+	Count           int                   `json:"count" bson:"count" binding:"omitempty,min=1"`
+	Percent         float64               `json:"percent" bson:"percent" binding:"omitempty,gt=0,lte=100"`
+	MaxPerFamily    int                   `json:"maxPerFamily" bson:"maxPerFamily" binding:"omitempty,min=1"`
+	Families        []FamilyMember        `json:"families" bson:"families,omitempty" binding:"omitempty,dive"`
+	ExcludeTraining bool                  `json:"excludeTraining" bson:"excludeTraining"`
+	Ranked          int                   `bson:"ranked"`
+	Candidates      []selection.Candidate `bson:"candidates"`
+	ProjectID       bson.ObjectId         `bson:"projectID"`
+	CreatedBy       string                `bson:"createdBy"`
+	UpdatedBy       string                `bson:"updatedBy"`
+	CreatedAt       time.Time             `bson:"createdAt"`
+	UpdatedAt       time.Time             `bson:"updatedAt"`
+}
+
+type UpdateSelectionDto struct {
+	Name        string    `json:"name" bson:"name,omitempty"`
+	Description string    `json:"description" bson:"description,omitempty"`
+	Rationale   string    `json:"rationale" bson:"rationale,omitempty"`
+	UpdatedBy   string    `bson:"updatedBy,omitempty"`
+	UpdatedAt   time.Time `bson:"updatedAt,omitempty"`
+}
+
+type DeleteSelectionDto struct {
+	ID        bson.ObjectId `json:"_id,omitempty" bson:"_id,omitempty" uri:"selectionID" binding:"required,mongoid"`
+	ProjectID bson.ObjectId `json:"projectID,omitempty" bson:"projectID,omitempty" uri:"projectID" binding:"required,mongoid"`
+}
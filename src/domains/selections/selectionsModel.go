@@ -0,0 +1,54 @@
+package selections
+
+import (
+	"time"
+
+	"github.com/khoa5773/go-server/src/genomics/selection"
+	"gopkg.in/mgo.v2/bson"
+)
+
+type SelectionMode string
+
+const (
+	TOP_K       SelectionMode = "TOP_K"
+	TOP_PERCENT SelectionMode = "TOP_PERCENT"
+)
+
+// Criterion weights the predicted values of one prediction in the selection index, a missing weight
+// counts as 1 and a weight of 0 leaves the trait out of the index
+type Criterion struct {
+	PredictionID bson.ObjectId `json:"predictionID" bson:"predictionID" binding:"required"`
+	Name         string        `json:"name" bson:"name"`
+	Trait        string        `json:"trait" bson:"trait"`
+	Weight       *float64      `json:"weight" bson:"weight"`
+}
+
+// FamilyMember assigns a sample to its family for MaxPerFamily, a list since sample IDs may not be
+// valid Mongo keys
+type FamilyMember struct {
+	SampleID string `json:"sampleID" bson:"sampleID" binding:"required"`
+	Family   string `json:"family" bson:"family" binding:"required"`
+}
+
+// Selection model, Families keeps the assignment of the ranked samples that MaxPerFamily was applied with
+type Selection struct {
+	ID              bson.ObjectId         `json:"_id" bson:"_id"`
+	Name            string                `json:"name" bson:"name"`
+	Description     string                `json:"description" bson:"description"`
+	Rationale       string                `json:"rationale" bson:"rationale"`
+	ProjectID       bson.ObjectId         `json:"projectID" bson:"projectID"`
+	Criteria        []Criterion           `json:"criteria" bson:"criteria"`
+	Standardize     bool                  `json:"standardize" bson:"standardize"`
+	Mode            SelectionMode         `json:"mode" bson:"mode"`
+	Count           int                   `json:"count" bson:"count"`
+	Percent         float64               `json:"percent" bson:"percent"`
+	MaxPerFamily    int                   `json:"maxPerFamily" bson:"maxPerFamily"`
+	Families        []FamilyMember        `json:"families" bson:"families,omitempty"`
+	ExcludeTraining bool                  `json:"excludeTraining" bson:"excludeTraining"`
+	Ranked          int                   `json:"ranked" bson:"ranked"`
+	Candidates      []selection.Candidate `json:"candidates" bson:"candidates"`
+	CreatedBy       string                `json:"createdBy" bson:"createdBy"`
+	CreatedAt       time.Time             `json:"createdAt" bson:"createdAt"`
+	UpdatedAt       time.Time             `json:"updatedAt" bson:"updatedAt"`
+	UpdatedBy       string                `json:"updatedBy" bson:"updatedBy"`
+}
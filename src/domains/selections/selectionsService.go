@@ -0,0 +1,248 @@
+package selections
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/fatih/structs"
+	"github.com/khoa5773/go-server/src/domains/predictions"
+	"github.com/khoa5773/go-server/src/genomics/selection"
+	"github.com/khoa5773/go-server/src/shared"
+	"github.com/mitchellh/mapstructure"
+	"gopkg.in/mgo.v2/bson"
+)
+
+func FindManySelections(findManySelectionsDto FindManySelectionsDto, credentials shared.Credentials) (shared.Result, error) {
+	var selections []map[string]interface{}
+	SelectionsModel := shared.MongoSession.C("selections")
+
+	err := SelectionsModel.Find(findManySelectionsDto).Select(bson.M{"candidates": 0, "families": 0}).Sort("-createdAt").All(&selections)
+	if err != nil {
+		return shared.Result{}, err
+	}
+
+	data, err := shared.ValidateAccessToList(selections, credentials)
+	if err != nil {
+		return shared.Result{}, err
+	}
+
+	return *data, nil
+}
+
+func FindOneSelection(findOneSelectionDto *FindOneSelectionDto, credentials shared.Credentials) (Selection, error) {
+	var selection Selection
+	SelectionsModel := shared.MongoSession.C("selections")
+	err := SelectionsModel.Find(findOneSelectionDto).One(&selection)
+	if err != nil {
+		return Selection{}, err
+	}
+
+	data, err := shared.ValidateAccessToSingle(structs.Map(selection), credentials)
+	if err != nil {
+		return Selection{}, err
+	}
+
+	err = mapstructure.Decode(data, &selection)
+	if err != nil {
+		return Selection{}, err
+	}
+
+	return selection, nil
+}
+
+func CreateOneSelection(createOneSelectionDto *CreateOneSelectionDto, credentials shared.Credentials) (bool, error) {
+	createOneSelectionDto.CreatedBy = credentials.Id
+	createOneSelectionDto.UpdatedBy = credentials.Id
+	createOneSelectionDto.CreatedAt = time.Now()
+	createOneSelectionDto.UpdatedAt = time.Now()
+
+	newSelection := &Selection{}
+	err := mapstructure.Decode(structs.Map(createOneSelectionDto), newSelection)
+	if err != nil {
+		return false, err
+	}
+
+	_, err = shared.ValidateAccessToSingle(structs.Map(newSelection), credentials)
+	if err != nil {
+		return false, err
+	}
+
+	if createOneSelectionDto.Mode == TOP_K && createOneSelectionDto.Count == 0 {
+		return false, errors.New("count is required for TOP_K selection")
+	}
+	if createOneSelectionDto.Mode == TOP_PERCENT && createOneSelectionDto.Percent == 0 {
+		return false, errors.New("percent is required for TOP_PERCENT selection")
+	}
+
+	ranked, err := RankCandidates(createOneSelectionDto, credentials)
+	if err != nil {
+		return false, err
+	}
+
+	// only the families of ranked samples are kept, they are all the selection depends on
+	families := make(map[string]string, len(createOneSelectionDto.Families))
+	for _, member := range createOneSelectionDto.Families {
+		if previous, ok := families[member.SampleID]; ok && previous != member.Family {
+			return false, fmt.Errorf("sample %s is assigned to families %s and %s", member.SampleID, previous, member.Family)
+		}
+		families[member.SampleID] = member.Family
+	}
+	createOneSelectionDto.Families = nil
+	for _, candidate := range ranked {
+		if family, ok := families[candidate.SampleID]; ok {
+			createOneSelectionDto.Families = append(createOneSelectionDto.Families, FamilyMember{SampleID: candidate.SampleID, Family: family})
+		}
+	}
+
+	settings := selection.Settings{MaxPerFamily: createOneSelectionDto.MaxPerFamily, Families: families}
+	if createOneSelectionDto.Mode == TOP_K {
+		settings.Count = createOneSelectionDto.Count
+		createOneSelectionDto.Percent = 0
+	} else {
+		settings.Percent = createOneSelectionDto.Percent
+		createOneSelectionDto.Count = 0
+	}
+
+	createOneSelectionDto.Ranked = len(ranked)
+	createOneSelectionDto.Candidates = selection.Truncate(ranked, settings)
+
+	SelectionsModel := shared.MongoSession.C("selections")
+	err = SelectionsModel.Insert(createOneSelectionDto)
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// RankCandidates ranks the samples predicted by every criterion, the criteria are completed with the name
+// and trait of their prediction
+func RankCandidates(createOneSelectionDto *CreateOneSelectionDto, credentials shared.Credentials) ([]selection.Candidate, error) {
+	traits := make([]selection.Trait, len(createOneSelectionDto.Criteria))
+	training := map[string]bool{}
+
+	for i := range createOneSelectionDto.Criteria {
+		criterion := &createOneSelectionDto.Criteria[i]
+		prediction, err := predictions.FindOnePrediction(&predictions.FindOnePredictionDto{ID: criterion.PredictionID, ProjectID: createOneSelectionDto.ProjectID}, credentials)
+		if err != nil {
+			return nil, err
+		}
+		if prediction.Status != predictions.SUCCEEDED {
+			return nil, fmt.Errorf("prediction %s has not succeeded", prediction.Name)
+		}
+
+		if criterion.Weight == nil {
+			weight := 1.0
+			criterion.Weight = &weight
+		}
+		criterion.Name = prediction.Name
+		criterion.Trait = prediction.Trait
+
+		traits[i] = selection.Trait{Values: make(map[string]float64, len(prediction.Results)), Weight: *criterion.Weight}
+		for _, result := range prediction.Results {
+			traits[i].Values[result.SampleID] = result.Predicted
+			if result.Training {
+				training[result.SampleID] = true
+			}
+		}
+	}
+
+	if createOneSelectionDto.ExcludeTraining {
+		for _, trait := range traits {
+			for sampleID := range training {
+				delete(trait.Values, sampleID)
+			}
+		}
+	}
+
+	return selection.Rank(traits, createOneSelectionDto.Standardize)
+}
+
+func UpdateSelection(findOneSelectionDto *FindOneSelectionDto, updateSelectionDto *UpdateSelectionDto, credentials shared.Credentials) (bool, error) {
+	var selection *Selection
+	SelectionsModel := shared.MongoSession.C("selections")
+
+	err := SelectionsModel.Find(findOneSelectionDto).One(&selection)
+	if err != nil {
+		return false, err
+	}
+
+	_, err = shared.ValidateAccessToSingle(structs.Map(*selection), credentials)
+	if err != nil {
+		return false, err
+	}
+
+	updateSelectionDto.UpdatedAt = time.Now()
+	updateSelectionDto.UpdatedBy = credentials.Id
+
+	err = SelectionsModel.Update(findOneSelectionDto, bson.M{"$set": updateSelectionDto})
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+func DeleteSelection(deleteSelectionDto *DeleteSelectionDto, credentials shared.Credentials) (bool, error) {
+	var selection *Selection
+	SelectionsModel := shared.MongoSession.C("selections")
+	err := SelectionsModel.Find(deleteSelectionDto).One(&selection)
+	if err != nil {
+		return false, err
+	}
+
+	_, err = shared.ValidateAccessToSingle(structs.Map(*selection), credentials)
+	if err != nil {
+		return false, err
+	}
+
+	err = SelectionsModel.Remove(deleteSelectionDto)
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// ExportSelection writes one CSV row per candidate with the predicted value of every criterion
+func ExportSelection(w io.Writer, selection Selection) error {
+	writer := csv.NewWriter(w)
+
+	header := []string{"rank", "sampleID", "family", "index"}
+	for _, criterion := range selection.Criteria {
+		column := criterion.Name
+		if criterion.Trait != "" {
+			column = fmt.Sprintf("%s (%s)", criterion.Name, criterion.Trait)
+		}
+		header = append(header, column)
+	}
+
+	err := writer.Write(header)
+	if err != nil {
+		return err
+	}
+
+	for _, candidate := range selection.Candidates {
+		row := []string{
+			strconv.Itoa(candidate.Rank),
+			candidate.SampleID,
+			candidate.Family,
+			strconv.FormatFloat(candidate.Index, 'g', -1, 64),
+		}
+		for _, value := range candidate.Values {
+			row = append(row, strconv.FormatFloat(value, 'g', -1, 64))
+		}
+
+		err = writer.Write(row)
+		if err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
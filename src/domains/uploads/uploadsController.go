@@ -0,0 +1,173 @@
+package uploads
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	cloudstorage "github.com/khoa5773/go-server/src/domains/cloudStorage"
+	"github.com/khoa5773/go-server/src/domains/documents"
+	"github.com/khoa5773/go-server/src/middleware/auth"
+	"github.com/khoa5773/go-server/src/middleware/authz"
+	"github.com/khoa5773/go-server/src/shared"
+	"gopkg.in/mgo.v2/bson"
+)
+
+func ApplyRoutes(r *gin.Engine) {
+	UploadsControllers := r.Group("projects/:projectID/repositories/:repositoryID/uploads")
+	UploadsControllers.POST("", auth.JWTRequired, authz.Scopes([]string{"documents:create", "proj:documents:create"}), createUploadController)
+	UploadsControllers.GET("/:uploadID", auth.JWTRequired, authz.Scopes([]string{"documents:read", "proj:documents:read"}), findOneUploadController)
+	UploadsControllers.HEAD("/:uploadID", auth.JWTRequired, authz.Scopes([]string{"documents:read", "proj:documents:read"}), findOneUploadController)
+	UploadsControllers.PATCH("/:uploadID", auth.JWTRequired, authz.Scopes([]string{"documents:create", "proj:documents:create"}), patchUploadController)
+	UploadsControllers.DELETE("/:uploadID", auth.JWTRequired, authz.Scopes([]string{"documents:create", "proj:documents:create"}), abortUploadController)
+}
+
+// setOffsetHeaders lets clients resume with a HEAD request only
+func setOffsetHeaders(c *gin.Context, upload Upload) {
+	c.Header("Upload-Offset", strconv.FormatInt(upload.Offset, 10))
+	c.Header("Upload-Length", strconv.FormatInt(upload.Size, 10))
+	c.Header("Cache-Control", "no-store")
+}
+
+func createUploadController(c *gin.Context) {
+	var createOneUploadDto CreateOneUploadDto
+	err := c.ShouldBindJSON(&createOneUploadDto)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	credentials := shared.Credentials{
+		Id:                c.MustGet("userID").(string),
+		HasPersonalScopes: c.MustGet("hasPersonalScopes").(bool),
+		HasProjectScopes:  c.MustGet("hasProjectScopes").(bool),
+		ProjectIDs:        c.MustGet("userProjectIDs").([]bson.ObjectId),
+		RequestID:         c.GetString("requestID"),
+	}
+	createOneUploadDto.ID = bson.NewObjectId()
+	createOneUploadDto.ProjectID = bson.ObjectIdHex(c.Param("projectID"))
+	createOneUploadDto.RepositoryID = bson.ObjectIdHex(c.Param("repositoryID"))
+
+	isSuccess, err := CreateOneUpload(&createOneUploadDto, credentials)
+	if err != nil {
+		var tooLargeErr *cloudstorage.TooLargeError
+		if errors.As(err, &tooLargeErr) {
+			c.AbortWithStatusJSON(http.StatusRequestEntityTooLarge, gin.H{"errors": []gin.H{{"error": err.Error()}}})
+			return
+		}
+		_ = c.Error(err)
+		return
+	}
+
+	c.Header("Upload-Offset", "0")
+	c.JSON(http.StatusCreated, gin.H{"success": isSuccess, "_id": createOneUploadDto.ID, "offset": 0})
+}
+
+func findOneUploadController(c *gin.Context) {
+	var findOneUploadDto FindOneUploadDto
+	err := c.ShouldBindUri(&findOneUploadDto)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	credentials := shared.Credentials{
+		Id:                c.MustGet("userID").(string),
+		HasPersonalScopes: c.MustGet("hasPersonalScopes").(bool),
+		HasProjectScopes:  c.MustGet("hasProjectScopes").(bool),
+		ProjectIDs:        c.MustGet("userProjectIDs").([]bson.ObjectId),
+		RequestID:         c.GetString("requestID"),
+	}
+
+	upload, err := FindOneUpload(&findOneUploadDto, credentials)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	setOffsetHeaders(c, upload)
+	if c.Request.Method == http.MethodHead {
+		c.Status(http.StatusOK)
+		return
+	}
+	if upload.Status == COMPLETED {
+		duplicates, _ := documents.FindDuplicateDocuments(upload.DocumentID, credentials)
+		c.JSON(http.StatusOK, gin.H{"upload": upload, "duplicates": duplicates})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"upload": upload})
+}
+
+func patchUploadController(c *gin.Context) {
+	var findOneUploadDto FindOneUploadDto
+	var patchUploadDto PatchUploadDto
+
+	err := c.ShouldBindUri(&findOneUploadDto)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	err = c.ShouldBindHeader(&patchUploadDto)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	credentials := shared.Credentials{
+		Id:                c.MustGet("userID").(string),
+		HasPersonalScopes: c.MustGet("hasPersonalScopes").(bool),
+		HasProjectScopes:  c.MustGet("hasProjectScopes").(bool),
+		ProjectIDs:        c.MustGet("userProjectIDs").([]bson.ObjectId),
+		RequestID:         c.GetString("requestID"),
+	}
+
+	upload, err := PatchUpload(c, &findOneUploadDto, *patchUploadDto.Offset, c.Request.Body, credentials)
+	if err != nil {
+		var offsetMismatchErr *OffsetMismatchError
+		var tooLargeErr *cloudstorage.TooLargeError
+		switch {
+		case errors.As(err, &offsetMismatchErr):
+			c.Header("Upload-Offset", strconv.FormatInt(offsetMismatchErr.Offset, 10))
+			c.AbortWithStatusJSON(http.StatusConflict, gin.H{"errors": []gin.H{{"error": err.Error()}}})
+		case errors.As(err, &tooLargeErr):
+			c.AbortWithStatusJSON(http.StatusRequestEntityTooLarge, gin.H{"errors": []gin.H{{"error": "chunk goes past the declared upload size"}}})
+		default:
+			_ = c.Error(err)
+		}
+		return
+	}
+
+	setOffsetHeaders(c, upload)
+	if upload.Status == FINALIZING {
+		c.JSON(http.StatusAccepted, gin.H{"success": true, "jobID": upload.JobID, "documentID": upload.DocumentID})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func abortUploadController(c *gin.Context) {
+	var findOneUploadDto FindOneUploadDto
+	err := c.ShouldBindUri(&findOneUploadDto)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	credentials := shared.Credentials{
+		Id:                c.MustGet("userID").(string),
+		HasPersonalScopes: c.MustGet("hasPersonalScopes").(bool),
+		HasProjectScopes:  c.MustGet("hasProjectScopes").(bool),
+		ProjectIDs:        c.MustGet("userProjectIDs").([]bson.ObjectId),
+		RequestID:         c.GetString("requestID"),
+	}
+
+	isSuccess, err := AbortUpload(&findOneUploadDto, credentials)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": isSuccess})
+}
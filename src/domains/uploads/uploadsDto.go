@@ -0,0 +1,38 @@
+package uploads
+
+import (
+	"time"
+
+	"gopkg.in/mgo.v2/bson"
+)
+
+type CreateOneUploadDto struct {
+	ID           bson.ObjectId `bson:"_id"`
+	Name         string        `json:"name" bson:"name" binding:"required"`
+	Description  string        `json:"description" bson:"description"`
+	Type         string        `json:"type" bson:"type" binding:"required,oneof=GENOTYPE PHENOTYPE"`
+	Coding       string        `json:"coding" bson:"coding,omitempty" binding:"omitempty,oneof=DOSAGE_012 DOSAGE_101 NUCLEOTIDE IUPAC"`
+	FileName     string        `json:"fileName" bson:"fileName" binding:"required"`
+	Size         int64         `json:"size" bson:"size" binding:"required,min=1"`
+	MimeType     string        `bson:"mimeType"`
+	Offset       int64         `bson:"offset"`
+	Chunks       []Chunk       `bson:"chunks"`
+	Status       UploadStatus  `bson:"status"`
+	ProjectID    bson.ObjectId `bson:"projectID"`
+	RepositoryID bson.ObjectId `bson:"repositoryID"`
+	CreatedBy    string        `bson:"createdBy"`
+	CreatedAt    time.Time     `bson:"createdAt"`
+	UpdatedAt    time.Time     `bson:"updatedAt"`
+	ExpiresAt    time.Time     `bson:"expiresAt"`
+}
+
+type FindOneUploadDto struct {
+	ID           bson.ObjectId `json:"_id,omitempty" bson:"_id,omitempty" uri:"uploadID" binding:"required,mongoid"`
+	ProjectID    bson.ObjectId `json:"projectID,omitempty" bson:"projectID,omitempty" uri:"projectID" binding:"required,mongoid"`
+	RepositoryID bson.ObjectId `json:"repositoryID,omitempty" bson:"repositoryID,omitempty" uri:"repositoryID" binding:"required,mongoid"`
+}
+
+// PatchUploadDto carries the offset the chunk in the body starts at, it must be the current offset
+type PatchUploadDto struct {
+	Offset *int64 `header:"Upload-Offset" binding:"required,min=0"`
+}
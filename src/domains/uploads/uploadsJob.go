@@ -0,0 +1,76 @@
+package uploads
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/khoa5773/go-server/src/domains/jobs"
+	"github.com/khoa5773/go-server/src/shared"
+	"gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+)
+
+const FINALIZATION_JOB_TYPE = "UPLOAD_FINALIZATION"
+
+type finalizationJobHandler struct{}
+
+func init() {
+	jobs.RegisterHandler(FINALIZATION_JOB_TYPE, finalizationJobHandler{})
+}
+
+// Run assembles the chunks of the upload into its document, it is safe to run again for the same upload
+func (finalizationJobHandler) Run(ctx context.Context, job *jobs.Job, progress func(percentage int)) (map[string]interface{}, error) {
+	uploadID, ok := job.Payload["uploadID"].(bson.ObjectId)
+	if !ok {
+		return nil, jobs.Permanent(errors.New("job payload has no uploadID"))
+	}
+
+	var upload Upload
+	UploadsModel := shared.MongoSession.C("uploads")
+	err := UploadsModel.Find(bson.M{"_id": uploadID, "projectID": job.ProjectID}).One(&upload)
+	if err != nil {
+		return nil, err
+	}
+
+	result := map[string]interface{}{"uploadID": upload.ID, "documentID": upload.DocumentID}
+	if upload.Status == COMPLETED && upload.JobID == job.ID {
+		return result, nil
+	}
+	if upload.Status != FINALIZING || upload.JobID != job.ID {
+		return nil, jobs.Permanent(errors.New("upload is no longer finalized by this job"))
+	}
+
+	credentials := shared.Credentials{Id: job.CreatedBy, IsAdmin: true}
+	err = finalize(ctx, &upload, credentials)
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// Finish gives a failed or cancelled upload back to its client, which retries with an empty PATCH. An
+// upload failed for its invalid file is already FAILED and left as it is.
+func (finalizationJobHandler) Finish(job *jobs.Job) error {
+	uploadID, ok := job.Payload["uploadID"].(bson.ObjectId)
+	if !ok || job.Status == jobs.SUCCEEDED {
+		return nil
+	}
+
+	reason := job.Error
+	if job.Status == jobs.CANCELLED {
+		reason = "finalization was cancelled"
+	}
+
+	now := time.Now()
+	UploadsModel := shared.MongoSession.C("uploads")
+	err := UploadsModel.Update(
+		bson.M{"_id": uploadID, "status": FINALIZING, "jobID": job.ID},
+		bson.M{"$set": bson.M{"status": UPLOADING, "error": reason, "updatedAt": now, "expiresAt": now.Add(uploadExpiry)}},
+	)
+	if err == mgo.ErrNotFound {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,51 @@
+package uploads
+
+import (
+	"time"
+
+	"gopkg.in/mgo.v2/bson"
+)
+
+type UploadStatus string
+
+const (
+	UPLOADING  UploadStatus = "UPLOADING"
+	FINALIZING UploadStatus = "FINALIZING"
+	COMPLETED  UploadStatus = "COMPLETED"
+	FAILED     UploadStatus = "FAILED"
+	ABORTED    UploadStatus = "ABORTED"
+)
+
+// Chunk is one PATCH request, stored as its own object until the upload is finalized
+type Chunk struct {
+	Key    string `json:"key" bson:"key"`
+	Offset int64  `json:"offset" bson:"offset"`
+	Size   int64  `json:"size" bson:"size"`
+}
+
+// Upload model, a resumable upload of a file that becomes a document once all its bytes arrived.
+// DocumentID is chosen when finalization starts and kept across retries, so that a finalization
+// job running twice creates the document once. JobID is the last finalization job. An upload whose
+// file is invalid ends FAILED with the reason in Error.
+type Upload struct {
+	ID           bson.ObjectId `json:"_id" bson:"_id"`
+	ProjectID    bson.ObjectId `json:"projectID" bson:"projectID"`
+	RepositoryID bson.ObjectId `json:"repositoryID" bson:"repositoryID"`
+	Name         string        `json:"name" bson:"name"`
+	Description  string        `json:"description" bson:"description"`
+	Type         string        `json:"type" bson:"type"`
+	Coding       string        `json:"coding,omitempty" bson:"coding,omitempty"`
+	FileName     string        `json:"fileName" bson:"fileName"`
+	MimeType     string        `json:"mimeType" bson:"mimeType"`
+	Size         int64         `json:"size" bson:"size"`
+	Offset       int64         `json:"offset" bson:"offset"`
+	Chunks       []Chunk       `json:"-" bson:"chunks"`
+	Status       UploadStatus  `json:"status" bson:"status"`
+	DocumentID   bson.ObjectId `json:"documentID,omitempty" bson:"documentID,omitempty"`
+	JobID        bson.ObjectId `json:"jobID,omitempty" bson:"jobID,omitempty"`
+	Error        string        `json:"error" bson:"error"`
+	CreatedBy    string        `json:"createdBy" bson:"createdBy"`
+	CreatedAt    time.Time     `json:"createdAt" bson:"createdAt"`
+	UpdatedAt    time.Time     `json:"updatedAt" bson:"updatedAt"`
+	ExpiresAt    time.Time     `json:"expiresAt" bson:"expiresAt"`
+}
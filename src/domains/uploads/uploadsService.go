@@ -0,0 +1,434 @@
+package uploads
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"time"
+
+	"github.com/fatih/structs"
+	cloudstorage "github.com/khoa5773/go-server/src/domains/cloudStorage"
+	"github.com/khoa5773/go-server/src/domains/documents"
+	"github.com/khoa5773/go-server/src/domains/jobs"
+	"github.com/khoa5773/go-server/src/domains/projects"
+	"github.com/khoa5773/go-server/src/domains/repositories"
+	"github.com/khoa5773/go-server/src/genomics"
+	"github.com/khoa5773/go-server/src/helpers"
+	"github.com/khoa5773/go-server/src/shared"
+	"github.com/mitchellh/mapstructure"
+	"gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+)
+
+const (
+	// uploadExpiry is how long an upload can stay idle before it is aborted
+	uploadExpiry    = 24 * time.Hour
+	cleanupInterval = time.Hour
+)
+
+// OffsetMismatchError tells the client where to resume from
+type OffsetMismatchError struct {
+	Offset int64
+}
+
+func (e *OffsetMismatchError) Error() string {
+	return fmt.Sprintf("upload offset is %d", e.Offset)
+}
+
+func CreateOneUpload(createOneUploadDto *CreateOneUploadDto, credentials shared.Credentials) (bool, error) {
+	var err error
+	createOneUploadDto.MimeType, err = helpers.GetMimeTypeFromFilename(createOneUploadDto.FileName)
+	if err != nil {
+		return false, err
+	}
+
+	_, err = repositories.CheckRepositoriesExists([]bson.ObjectId{createOneUploadDto.RepositoryID}, createOneUploadDto.ProjectID)
+	if err != nil {
+		return false, err
+	}
+
+	maxUploadSize, err := projects.MaxUploadSize(createOneUploadDto.ProjectID)
+	if err != nil {
+		return false, err
+	}
+	if createOneUploadDto.Size > maxUploadSize {
+		return false, &cloudstorage.TooLargeError{Limit: maxUploadSize}
+	}
+
+	createOneUploadDto.Status = UPLOADING
+	createOneUploadDto.Chunks = []Chunk{}
+	createOneUploadDto.CreatedBy = credentials.Id
+	createOneUploadDto.CreatedAt = time.Now()
+	createOneUploadDto.UpdatedAt = time.Now()
+	createOneUploadDto.ExpiresAt = createOneUploadDto.CreatedAt.Add(uploadExpiry)
+
+	upload := &Upload{}
+	err = mapstructure.Decode(structs.Map(createOneUploadDto), upload)
+	if err != nil {
+		return false, err
+	}
+
+	_, err = shared.ValidateAccessToSingle(structs.Map(upload), credentials)
+	if err != nil {
+		return false, err
+	}
+
+	UploadsModel := shared.MongoSession.C("uploads")
+	err = UploadsModel.Insert(createOneUploadDto)
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+func FindOneUpload(findOneUploadDto *FindOneUploadDto, credentials shared.Credentials) (Upload, error) {
+	var upload Upload
+	UploadsModel := shared.MongoSession.C("uploads")
+	err := UploadsModel.Find(findOneUploadDto).One(&upload)
+	if err != nil {
+		return Upload{}, err
+	}
+
+	_, err = shared.ValidateAccessToSingle(structs.Map(upload), credentials)
+	if err != nil {
+		return Upload{}, err
+	}
+
+	return upload, nil
+}
+
+// PatchUpload appends a chunk starting at offset. A chunk interrupted midway is discarded, so the
+// client resumes from the offset returned by FindOneUpload. The last chunk starts the finalization
+// job that turns the upload into a document; an empty chunk at the end retries a finalization that
+// failed for another reason than an invalid file.
+func PatchUpload(ctx context.Context, findOneUploadDto *FindOneUploadDto, offset int64, content io.Reader, credentials shared.Credentials) (Upload, error) {
+	upload, err := FindOneUpload(findOneUploadDto, credentials)
+	if err != nil {
+		return Upload{}, err
+	}
+
+	if upload.CreatedBy != credentials.Id {
+		return Upload{}, errors.New("only the user who started the upload can send its content")
+	}
+	if upload.Status != UPLOADING {
+		return Upload{}, fmt.Errorf("upload is %s", upload.Status)
+	}
+	if offset != upload.Offset {
+		return Upload{}, &OffsetMismatchError{Offset: upload.Offset}
+	}
+
+	if upload.Offset < upload.Size {
+		key := fmt.Sprintf("uploads/%s/%020d-%s", upload.ID.Hex(), offset, bson.NewObjectId().Hex())
+		size, _, err := cloudstorage.StreamToObject(ctx, key, content, "application/octet-stream", upload.Size-upload.Offset)
+		if err != nil {
+			return Upload{}, err
+		}
+		if size == 0 {
+			return upload, nil
+		}
+
+		// the offset condition makes concurrent requests for the same offset fail but one
+		now := time.Now()
+		UploadsModel := shared.MongoSession.C("uploads")
+		err = UploadsModel.Update(
+			bson.M{"_id": upload.ID, "status": UPLOADING, "offset": offset},
+			bson.M{
+				"$set":  bson.M{"offset": offset + size, "updatedAt": now, "expiresAt": now.Add(uploadExpiry)},
+				"$push": bson.M{"chunks": Chunk{Key: key, Offset: offset, Size: size}},
+			},
+		)
+		if err != nil {
+			removeObject(key)
+			if err == mgo.ErrNotFound {
+				return Upload{}, errors.New("upload was changed by another request, query its offset and resume")
+			}
+			return Upload{}, err
+		}
+		upload.Offset += size
+	}
+
+	if upload.Offset < upload.Size {
+		return upload, nil
+	}
+
+	return startFinalization(upload, credentials)
+}
+
+// startFinalization moves the upload to FINALIZING and queues the job assembling it. The document
+// ID is picked the first time and reused by the retries.
+func startFinalization(upload Upload, credentials shared.Credentials) (Upload, error) {
+	documentID := upload.DocumentID
+	if documentID == "" {
+		documentID = bson.NewObjectId()
+	}
+	jobID := bson.NewObjectId()
+
+	now := time.Now()
+	UploadsModel := shared.MongoSession.C("uploads")
+	_, err := UploadsModel.Find(bson.M{"_id": upload.ID, "status": UPLOADING}).Apply(mgo.Change{
+		Update: bson.M{"$set": bson.M{
+			"status":     FINALIZING,
+			"documentID": documentID,
+			"jobID":      jobID,
+			"error":      "",
+			"updatedAt":  now,
+			"expiresAt":  now.Add(uploadExpiry),
+		}},
+		ReturnNew: true,
+	}, &upload)
+	if err == mgo.ErrNotFound {
+		return Upload{}, errors.New("upload is already being finalized")
+	}
+	if err != nil {
+		return Upload{}, err
+	}
+
+	_, err = jobs.Enqueue(&jobs.CreateOneJobDto{
+		ID:        jobID,
+		ProjectID: upload.ProjectID,
+		Type:      FINALIZATION_JOB_TYPE,
+		Payload:   map[string]interface{}{"uploadID": upload.ID},
+	}, credentials)
+	if err != nil {
+		_ = UploadsModel.Update(
+			bson.M{"_id": upload.ID, "status": FINALIZING, "jobID": jobID},
+			bson.M{"$set": bson.M{"status": UPLOADING, "error": err.Error(), "updatedAt": time.Now()}},
+		)
+		return Upload{}, err
+	}
+
+	return upload, nil
+}
+
+// finalize assembles the chunks into the document file and creates the document. A job that runs
+// again after a crash or a lost lease finds the document under the ID stored on the upload and only
+// completes the upload.
+func finalize(ctx context.Context, upload *Upload, credentials shared.Credentials) error {
+	exists, err := documents.ResumeDocumentCreation(upload.DocumentID, credentials)
+	if err == nil && !exists {
+		err = createDocument(ctx, upload, credentials)
+	}
+	var invalid *documents.InvalidContentError
+	if errors.As(err, &invalid) {
+		return fail(upload, err)
+	}
+	if err != nil {
+		return err
+	}
+
+	UploadsModel := shared.MongoSession.C("uploads")
+	err = UploadsModel.Update(
+		bson.M{"_id": upload.ID, "status": FINALIZING, "jobID": upload.JobID},
+		bson.M{"$set": bson.M{"status": COMPLETED, "chunks": []Chunk{}, "error": "", "updatedAt": time.Now()}},
+	)
+	if err != nil {
+		return err
+	}
+
+	removeChunks(upload.Chunks)
+	return nil
+}
+
+// fail ends an upload whose file can not become a document, the chunks are dropped since sending them
+// again would fail the same way
+func fail(upload *Upload, reason error) error {
+	UploadsModel := shared.MongoSession.C("uploads")
+	err := UploadsModel.Update(
+		bson.M{"_id": upload.ID, "status": FINALIZING, "jobID": upload.JobID},
+		bson.M{"$set": bson.M{"status": FAILED, "error": reason.Error(), "chunks": []Chunk{}, "updatedAt": time.Now()}},
+	)
+	if err != nil {
+		return err
+	}
+
+	removeChunks(upload.Chunks)
+	return jobs.Permanent(reason)
+}
+
+func createDocument(ctx context.Context, upload *Upload, credentials shared.Credentials) error {
+	store, err := cloudstorage.Store()
+	if err != nil {
+		return err
+	}
+
+	reader := &chunksReader{ctx: ctx, store: store, chunks: upload.Chunks}
+	defer reader.Close()
+
+	staged, err := cloudstorage.StageFile(ctx, reader, upload.Size)
+	if err != nil {
+		return err
+	}
+	if staged.Size != upload.Size {
+		cloudstorage.DiscardStagedFile(staged)
+		return fmt.Errorf("assembled file has %d bytes instead of %d", staged.Size, upload.Size)
+	}
+
+	createOneDocumentDto := &documents.CreateOneDocumentDto{
+		ID:           upload.DocumentID,
+		Name:         upload.Name,
+		Description:  upload.Description,
+		Type:         documents.DocumentType(upload.Type),
+		Coding:       genomics.GenotypeCoding(upload.Coding),
+		MimeType:     upload.MimeType,
+		RepositoryID: upload.RepositoryID,
+		ProjectID:    upload.ProjectID,
+	}
+
+	_, err = documents.CreateOneDocumentFromStagedFile(ctx, createOneDocumentDto, staged, credentials)
+	if mgo.IsDup(err) {
+		// a previous run of the job, which lost its lease, created it meanwhile
+		return nil
+	}
+	return err
+}
+
+func AbortUpload(findOneUploadDto *FindOneUploadDto, credentials shared.Credentials) (bool, error) {
+	upload, err := FindOneUpload(findOneUploadDto, credentials)
+	if err != nil {
+		return false, err
+	}
+
+	err = abort(&upload, "")
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+func abort(upload *Upload, reason string) error {
+	UploadsModel := shared.MongoSession.C("uploads")
+	err := UploadsModel.Update(
+		bson.M{"_id": upload.ID, "status": UPLOADING},
+		bson.M{"$set": bson.M{"status": ABORTED, "error": reason, "chunks": []Chunk{}, "updatedAt": time.Now()}},
+	)
+	if err == mgo.ErrNotFound {
+		return fmt.Errorf("upload is %s", upload.Status)
+	}
+	if err != nil {
+		return err
+	}
+
+	removeChunks(upload.Chunks)
+	return nil
+}
+
+// Start aborts the uploads left idle periodically, along with the finalizations whose job ended
+// without bringing the upload out of FINALIZING
+func Start() {
+	go func() {
+		for {
+			err := abortExpiredUploads()
+			if err != nil {
+				log.Println("uploads: cleanup failed:", err)
+			}
+			time.Sleep(cleanupInterval)
+		}
+	}()
+}
+
+func abortExpiredUploads() error {
+	var uploads []Upload
+	UploadsModel := shared.MongoSession.C("uploads")
+	err := UploadsModel.Find(bson.M{"status": bson.M{"$in": []UploadStatus{UPLOADING, FINALIZING}}, "expiresAt": bson.M{"$lt": time.Now()}}).All(&uploads)
+	if err != nil {
+		return err
+	}
+
+	for i := range uploads {
+		upload := &uploads[i]
+		if upload.Status == FINALIZING {
+			if !finalizationEnded(upload) {
+				continue
+			}
+			_ = UploadsModel.Update(bson.M{"_id": upload.ID, "status": FINALIZING}, bson.M{"$set": bson.M{"status": UPLOADING}})
+			upload.Status = UPLOADING
+		}
+		err = abort(upload, "upload expired")
+		if err != nil {
+			log.Println("uploads: aborting", upload.ID.Hex(), "failed:", err)
+		}
+	}
+
+	return nil
+}
+
+// finalizationEnded tells whether the finalization job of the upload is over, or gone
+func finalizationEnded(upload *Upload) bool {
+	if upload.JobID == "" {
+		return true
+	}
+
+	job, err := jobs.FindOneJob(&jobs.FindOneJobDto{ID: upload.JobID, ProjectID: upload.ProjectID}, shared.Credentials{IsAdmin: true})
+	if err == mgo.ErrNotFound {
+		return true
+	}
+	if err != nil {
+		log.Println("uploads: finding the finalization job of", upload.ID.Hex(), "failed:", err)
+		return false
+	}
+	return job.IsFinished()
+}
+
+func removeChunks(chunks []Chunk) {
+	for _, chunk := range chunks {
+		removeObject(chunk.Key)
+	}
+}
+
+func removeObject(key string) {
+	store, err := cloudstorage.Store()
+	if err != nil {
+		return
+	}
+
+	err = store.Delete(context.Background(), key)
+	if err != nil && err != cloudstorage.ErrNotFound {
+		log.Println("uploads: removing", key, "failed:", err)
+	}
+}
+
+// chunksReader reads the chunks one after the other, opening each only when it is reached
+type chunksReader struct {
+	ctx     context.Context
+	store   cloudstorage.BlobStore
+	chunks  []Chunk
+	current io.ReadCloser
+}
+
+func (r *chunksReader) Read(p []byte) (int, error) {
+	for {
+		if r.current == nil {
+			if len(r.chunks) == 0 {
+				return 0, io.EOF
+			}
+			content, err := r.store.Get(r.ctx, r.chunks[0].Key)
+			if err != nil {
+				return 0, err
+			}
+			r.current = content
+			r.chunks = r.chunks[1:]
+		}
+
+		n, err := r.current.Read(p)
+		if err == io.EOF {
+			r.current.Close()
+			r.current = nil
+			if n > 0 {
+				return n, nil
+			}
+			continue
+		}
+		return n, err
+	}
+}
+
+func (r *chunksReader) Close() {
+	if r.current != nil {
+		r.current.Close()
+		r.current = nil
+	}
+}
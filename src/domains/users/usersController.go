@@ -37,6 +37,7 @@ func findOneUserController(c *gin.Context) {
 		HasPersonalScopes: c.MustGet("hasPersonalScopes").(bool),
 		HasProjectScopes:  c.MustGet("hasProjectScopes").(bool),
 		ProjectIDs:        c.MustGet("userProjectIDs").([]bson.ObjectId),
+		RequestID:         c.GetString("requestID"),
 	}
 
 	user, err := FindOneUser(&findOneUserDto, credentials)
@@ -56,6 +57,7 @@ func userInfoController(c *gin.Context) {
 		HasPersonalScopes: c.MustGet("hasPersonalScopes").(bool),
 		HasProjectScopes:  c.MustGet("hasProjectScopes").(bool),
 		ProjectIDs:        c.MustGet("userProjectIDs").([]bson.ObjectId),
+		RequestID:         c.GetString("requestID"),
 	}
 
 	user, err := FindOneUser(&findOneUserDto, credentials)
@@ -83,6 +85,7 @@ func updateUserController(c *gin.Context) {
 		HasPersonalScopes: c.MustGet("hasPersonalScopes").(bool),
 		HasProjectScopes:  c.MustGet("hasProjectScopes").(bool),
 		ProjectIDs:        c.MustGet("userProjectIDs").([]bson.ObjectId),
+		RequestID:         c.GetString("requestID"),
 	}
 
 	isSuccess, err := UpdateUser(userID, &updateUserDto, credentials)
@@ -120,6 +123,7 @@ func updateUserPictureController(c *gin.Context) {
 		HasPersonalScopes: c.MustGet("hasPersonalScopes").(bool),
 		HasProjectScopes:  c.MustGet("hasProjectScopes").(bool),
 		ProjectIDs:        c.MustGet("userProjectIDs").([]bson.ObjectId),
+		RequestID:         c.GetString("requestID"),
 	}
 
 	isSuccess, err := UpdateUser(userID, &UpdateUserDto{Picture: url}, credentials)
@@ -2,10 +2,11 @@ package users
 
 import (
 	"errors"
-	"strings"
+	"regexp"
 
 	"github.com/fatih/structs"
 	cloudstorage "github.com/khoa5773/go-server/src/domains/cloudStorage"
+	"github.com/khoa5773/go-server/src/domains/logs"
 	"github.com/khoa5773/go-server/src/domains/roles"
 	"github.com/khoa5773/go-server/src/shared"
 	"github.com/mitchellh/mapstructure"
@@ -20,6 +21,7 @@ func CreateUser(createUserDto *CreateUserDto) (bool, error) {
 		return false, err
 	}
 
+	logs.Record(shared.Credentials{Id: createUserDto.ID}, "", logs.USER, createUserDto.ID, logs.CREATE, nil, createUserDto)
 	return true, nil
 }
 
@@ -39,10 +41,7 @@ func FindOneUser(findOneUserDto *FindOneUserDto, credentials shared.Credentials)
 	}
 
 	_ = mapstructure.Decode(data, user)
-	if strings.Contains(user.Picture, "storage.googleapis.com") {
-		user.Picture, err = cloudstorage.GenerateSignedUrl(user.ID, "picture", "jpg")
-	}
-
+	user.Picture, err = cloudstorage.SignObjectURL(user.Picture)
 	if err != nil {
 		return nil, err
 	}
@@ -72,6 +71,12 @@ func UpdateUser(userID string, updateUserDto *UpdateUserDto, credentials shared.
 		return false, err
 	}
 
+	var updated *User
+	err = UsersModel.FindId(userID).One(&updated)
+	if err == nil {
+		logs.Record(credentials, "", logs.USER, userID, logs.UPDATE, user, updated)
+	}
+
 	return true, nil
 }
 
@@ -190,3 +195,15 @@ func FindMany(findManyDto *FindManyDto) ([]*User, error) {
 
 	return users, nil
 }
+
+// FindUserByEmail matches the email case-insensitively, mgo.ErrNotFound is returned when nobody uses it
+func FindUserByEmail(email string) (*User, error) {
+	var user *User
+	UsersModel := shared.MongoSession.C("users")
+	err := UsersModel.Find(bson.M{"email": bson.RegEx{Pattern: "^" + regexp.QuoteMeta(email) + "$", Options: "i"}}).One(&user)
+	if err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
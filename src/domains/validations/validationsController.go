@@ -0,0 +1,164 @@
+package validations
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/khoa5773/go-server/src/middleware/auth"
+	"github.com/khoa5773/go-server/src/middleware/authz"
+	"github.com/khoa5773/go-server/src/shared"
+	"gopkg.in/mgo.v2/bson"
+)
+
+func ApplyRoutes(r *gin.Engine) {
+	ValidationsControllers := r.Group("projects/:projectID/validations")
+	ValidationsControllers.GET("", auth.JWTRequired, authz.Scopes([]string{"predictions:read", "proj:predictions:read"}), findManyValidationsController)
+	ValidationsControllers.POST("", auth.JWTRequired, authz.Scopes([]string{"predictions:create", "proj:predictions:create"}), createValidationController)
+	ValidationsControllers.GET("/:validationID", auth.JWTRequired, authz.Scopes([]string{"predictions:read", "proj:predictions:read"}), findOneValidationController)
+	ValidationsControllers.PUT("/:validationID", auth.JWTRequired, authz.Scopes([]string{"predictions:update", "proj:predictions:update"}), updateValidationController)
+	ValidationsControllers.DELETE("/:validationID", auth.JWTRequired, authz.Scopes([]string{"predictions:delete", "proj:predictions:delete"}), deleteValidationController)
+}
+
+func findManyValidationsController(c *gin.Context) {
+	var findManyValidationsDto FindManyValidationsDto
+	var filterValidationsDto FilterValidationsDto
+
+	err := c.ShouldBindUri(&findManyValidationsDto)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	err = c.ShouldBindQuery(&filterValidationsDto)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	credentials := shared.Credentials{
+		Id:                c.MustGet("userID").(string),
+		HasPersonalScopes: c.MustGet("hasPersonalScopes").(bool),
+		HasProjectScopes:  c.MustGet("hasProjectScopes").(bool),
+		ProjectIDs:        c.MustGet("userProjectIDs").([]bson.ObjectId),
+		RequestID:         c.GetString("requestID"),
+	}
+
+	validations, err := FindManyValidations(findManyValidationsDto, filterValidationsDto, credentials)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"validations": validations})
+}
+
+func findOneValidationController(c *gin.Context) {
+	var findOneValidationDto FindOneValidationDto
+	err := c.ShouldBindUri(&findOneValidationDto)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	credentials := shared.Credentials{
+		Id:                c.MustGet("userID").(string),
+		HasPersonalScopes: c.MustGet("hasPersonalScopes").(bool),
+		HasProjectScopes:  c.MustGet("hasProjectScopes").(bool),
+		ProjectIDs:        c.MustGet("userProjectIDs").([]bson.ObjectId),
+		RequestID:         c.GetString("requestID"),
+	}
+
+	validation, err := FindOneValidation(&findOneValidationDto, credentials)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"validation": validation})
+}
+
+func createValidationController(c *gin.Context) {
+	var createOneValidationDto CreateOneValidationDto
+	err := c.ShouldBindJSON(&createOneValidationDto)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	credentials := shared.Credentials{
+		Id:                c.MustGet("userID").(string),
+		HasPersonalScopes: c.MustGet("hasPersonalScopes").(bool),
+		HasProjectScopes:  c.MustGet("hasProjectScopes").(bool),
+		ProjectIDs:        c.MustGet("userProjectIDs").([]bson.ObjectId),
+		RequestID:         c.GetString("requestID"),
+	}
+	createOneValidationDto.ProjectID = bson.ObjectIdHex(c.Param("projectID"))
+	createOneValidationDto.ID = bson.NewObjectId()
+
+	isSuccess, err := CreateOneValidation(&createOneValidationDto, credentials)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{"success": isSuccess, "_id": createOneValidationDto.ID, "jobID": createOneValidationDto.JobID})
+}
+
+func updateValidationController(c *gin.Context) {
+	var findOneValidationDto FindOneValidationDto
+	var updateValidationDto UpdateValidationDto
+
+	err := c.ShouldBindUri(&findOneValidationDto)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	err = c.ShouldBindJSON(&updateValidationDto)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	credentials := shared.Credentials{
+		Id:                c.MustGet("userID").(string),
+		HasPersonalScopes: c.MustGet("hasPersonalScopes").(bool),
+		HasProjectScopes:  c.MustGet("hasProjectScopes").(bool),
+		ProjectIDs:        c.MustGet("userProjectIDs").([]bson.ObjectId),
+		RequestID:         c.GetString("requestID"),
+	}
+
+	isSuccess, err := UpdateValidation(&findOneValidationDto, &updateValidationDto, credentials)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": isSuccess})
+}
+
+func deleteValidationController(c *gin.Context) {
+	var deleteValidationDto DeleteValidationDto
+
+	err := c.ShouldBindUri(&deleteValidationDto)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	credentials := shared.Credentials{
+		Id:                c.MustGet("userID").(string),
+		HasPersonalScopes: c.MustGet("hasPersonalScopes").(bool),
+		HasProjectScopes:  c.MustGet("hasProjectScopes").(bool),
+		ProjectIDs:        c.MustGet("userProjectIDs").([]bson.ObjectId),
+		RequestID:         c.GetString("requestID"),
+	}
+
+	isSuccess, err := DeleteValidation(&deleteValidationDto, credentials)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": isSuccess})
+}
@@ -0,0 +1,64 @@
+package validations
+
+import (
+	"time"
+
+	"github.com/khoa5773/go-server/src/genomics/crossvalidation"
+	"gopkg.in/mgo.v2/bson"
+)
+
+type FindManyValidationsDto struct {
+	ProjectID bson.ObjectId `json:"projectID,omitempty" bson:"projectID,omitempty" uri:"projectID" binding:"required,mongoid"`
+}
+
+type FilterValidationsDto struct {
+	GenotypeID  bson.ObjectId `json:"genotypeID,omitempty" bson:"genotypeID,omitempty" form:"genotypeID" binding:"mongoid"`
+	PhenotypeID bson.ObjectId `json:"phenotypeID,omitempty" bson:"phenotypeID,omitempty" form:"phenotypeID" binding:"mongoid"`
+	Method      string        `json:"method,omitempty" bson:"method,omitempty" form:"method"`
+	Trait       string        `json:"trait,omitempty" bson:"trait,omitempty" form:"trait"`
+	Seed        *int64        `json:"seed,omitempty" bson:"seed,omitempty" form:"seed"`
+}
+
+type FindOneValidationDto struct {
+	ID        bson.ObjectId `json:"_id,omitempty" bson:"_id,omitempty" uri:"validationID" binding:"required,mongoid"`
+	ProjectID bson.ObjectId `json:"projectID,omitempty" bson:"projectID,omitempty" uri:"projectID" binding:"required,mongoid"`
+}
+
+type CreateOneValidationDto struct {
+	ID               bson.ObjectId                `bson:"_id"`
+	Name             string                       `json:"name" bson:"name" binding:"required"`
+	Description      string                       `json:"description" bson:"description"`
+	GenotypeID       bson.ObjectId                `json:"genotypeID" bson:"genotypeID" binding:"required"`
+	PhenotypeID      bson.ObjectId                `json:"phenotypeID" bson:"phenotypeID" binding:"required"`
+	GenotypeVersion  int                          `json:"genotypeVersion" bson:"genotypeVersion,omitempty" binding:"omitempty,min=1"`
+	PhenotypeVersion int                          `json:"phenotypeVersion" bson:"phenotypeVersion,omitempty" binding:"omitempty,min=1"`
+	Method           string                       `json:"method" bson:"method" binding:"required,oneof=RBF_BLUF ELASTIC_NET LASSO G_BLUP"`
+	Parameters       map[string]interface{}       `json:"parameters" bson:"parameters"`
+	Trait            string                       `json:"trait" bson:"trait"`
+	Folds            int                          `json:"folds" bson:"folds" binding:"omitempty,min=2"`
+	Repetitions      int                          `json:"repetitions" bson:"repetitions" binding:"omitempty,min=1"`
+	Seed             *int64                       `json:"seed" bson:"seed"`
+	JobID            bson.ObjectId                `bson:"jobID"`
+	Status           ValidationStatus             `bson:"status"`
+	Error            string                       `bson:"error"`
+	Mean             crossvalidation.Metrics      `bson:"mean"`
+	SD               crossvalidation.Metrics      `bson:"sd"`
+	FoldResults      []crossvalidation.FoldResult `bson:"foldResults"`
+	ProjectID        bson.ObjectId                `bson:"projectID"`
+	CreatedBy        string                       `bson:"createdBy"`
+	UpdatedBy        string                       `bson:"updatedBy"`
+	CreatedAt        time.Time                    `bson:"createdAt"`
+	UpdatedAt        time.Time                    `bson:"updatedAt"`
+}
+
+type UpdateValidationDto struct {
+	Name        string    `json:"name" bson:"name,omitempty"`
+	Description string    `json:"description" bson:"description,omitempty"`
+	UpdatedBy   string    `bson:"updatedBy,omitempty"`
+	UpdatedAt   time.Time `bson:"updatedAt,omitempty"`
+}
+
+type DeleteValidationDto struct {
+	ID        bson.ObjectId `json:"_id,omitempty" bson:"_id,omitempty" uri:"validationID" binding:"required,mongoid"`
+	ProjectID bson.ObjectId `json:"projectID,omitempty" bson:"projectID,omitempty" uri:"projectID" binding:"required,mongoid"`
+}
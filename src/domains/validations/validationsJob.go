@@ -0,0 +1,92 @@
+package validations
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/khoa5773/go-server/src/domains/documents"
+	"github.com/khoa5773/go-server/src/domains/jobs"
+	"github.com/khoa5773/go-server/src/domains/predictions"
+	"github.com/khoa5773/go-server/src/genomics/crossvalidation"
+	"github.com/khoa5773/go-server/src/shared"
+	"gopkg.in/mgo.v2/bson"
+)
+
+const JOB_TYPE = "VALIDATION"
+
+type validationJobHandler struct{}
+
+func init() {
+	jobs.RegisterHandler(JOB_TYPE, validationJobHandler{})
+}
+
+func (validationJobHandler) Run(ctx context.Context, job *jobs.Job, progress func(percentage int)) (map[string]interface{}, error) {
+	validationID, ok := job.Payload["validationID"].(bson.ObjectId)
+	if !ok {
+		return nil, jobs.Permanent(errors.New("job payload has no validationID"))
+	}
+
+	credentials := shared.Credentials{Id: job.CreatedBy, IsAdmin: true}
+	validation, err := FindOneValidation(&FindOneValidationDto{ID: validationID, ProjectID: job.ProjectID}, credentials)
+	if err != nil {
+		return nil, err
+	}
+
+	ValidationsModel := shared.MongoSession.C("validations")
+	err = ValidationsModel.UpdateId(validationID, bson.M{"$set": bson.M{"status": RUNNING, "error": "", "updatedAt": time.Now()}})
+	if err != nil {
+		return nil, err
+	}
+
+	genotypeDocument, err := predictions.FindDocumentVersionOfType(validation.GenotypeID, validation.ProjectID, documents.GENOTYPE, validation.GenotypeVersion, credentials)
+	if err != nil {
+		return nil, err
+	}
+
+	phenotypeDocument, err := predictions.FindDocumentVersionOfType(validation.PhenotypeID, validation.ProjectID, documents.PHENOTYPE, validation.PhenotypeVersion, credentials)
+	if err != nil {
+		return nil, err
+	}
+
+	settings := crossvalidation.Settings{
+		Folds:       validation.Folds,
+		Repetitions: validation.Repetitions,
+		Seed:        validation.Seed,
+		Progress: func(completed, total int) {
+			progress(completed * 100 / total)
+		},
+	}
+
+	result, err := RunValidation(ctx, genotypeDocument, phenotypeDocument, validation.Method, validation.Parameters, validation.Trait, settings)
+	if err != nil {
+		return nil, err
+	}
+
+	err = ValidationsModel.UpdateId(validationID, bson.M{"$set": bson.M{
+		"mean":        result.Mean,
+		"sd":          result.SD,
+		"foldResults": result.FoldResults,
+		"updatedAt":   time.Now(),
+	}})
+	if err != nil {
+		return nil, err
+	}
+
+	return map[string]interface{}{"validationID": validationID, "pearson": result.Mean.Pearson}, nil
+}
+
+// Finish copies the final job status and error on the validation
+func (validationJobHandler) Finish(job *jobs.Job) error {
+	validationID, ok := job.Payload["validationID"].(bson.ObjectId)
+	if !ok {
+		return nil
+	}
+
+	ValidationsModel := shared.MongoSession.C("validations")
+	return ValidationsModel.UpdateId(validationID, bson.M{"$set": bson.M{
+		"status":    ValidationStatus(job.Status),
+		"error":     job.Error,
+		"updatedAt": time.Now(),
+	}})
+}
@@ -0,0 +1,47 @@
+package validations
+
+import (
+	"time"
+
+	"github.com/khoa5773/go-server/src/genomics/crossvalidation"
+	"gopkg.in/mgo.v2/bson"
+)
+
+type ValidationStatus string
+
+// Statuses mirror the status of the validation job
+const (
+	QUEUED    ValidationStatus = "QUEUED"
+	RUNNING   ValidationStatus = "RUNNING"
+	SUCCEEDED ValidationStatus = "SUCCEEDED"
+	FAILED    ValidationStatus = "FAILED"
+	CANCELLED ValidationStatus = "CANCELLED"
+)
+
+// Validation model, it records the document versions it runs on
+type Validation struct {
+	ID               bson.ObjectId                `json:"_id" bson:"_id"`
+	Name             string                       `json:"name" bson:"name"`
+	Description      string                       `json:"description" bson:"description"`
+	ProjectID        bson.ObjectId                `json:"projectID" bson:"projectID"`
+	GenotypeID       bson.ObjectId                `json:"genotypeID" bson:"genotypeID"`
+	PhenotypeID      bson.ObjectId                `json:"phenotypeID" bson:"phenotypeID"`
+	GenotypeVersion  int                          `json:"genotypeVersion" bson:"genotypeVersion,omitempty"`
+	PhenotypeVersion int                          `json:"phenotypeVersion" bson:"phenotypeVersion,omitempty"`
+	Method           string                       `json:"method" bson:"method"`
+	Parameters       map[string]interface{}       `json:"parameters" bson:"parameters"`
+	Trait            string                       `json:"trait" bson:"trait"`
+	Folds            int                          `json:"folds" bson:"folds"`
+	Repetitions      int                          `json:"repetitions" bson:"repetitions"`
+	Seed             int64                        `json:"seed" bson:"seed"`
+	JobID            bson.ObjectId                `json:"jobID" bson:"jobID"`
+	Status           ValidationStatus             `json:"status" bson:"status"`
+	Error            string                       `json:"error" bson:"error"`
+	Mean             crossvalidation.Metrics      `json:"mean" bson:"mean"`
+	SD               crossvalidation.Metrics      `json:"sd" bson:"sd"`
+	FoldResults      []crossvalidation.FoldResult `json:"foldResults" bson:"foldResults"`
+	CreatedBy        string                       `json:"createdBy" bson:"createdBy"`
+	CreatedAt        time.Time                    `json:"createdAt" bson:"createdAt"`
+	UpdatedAt        time.Time                    `json:"updatedAt" bson:"updatedAt"`
+	UpdatedBy        string                       `json:"updatedBy" bson:"updatedBy"`
+}
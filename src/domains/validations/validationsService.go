@@ -0,0 +1,259 @@
+package validations
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/fatih/structs"
+	"github.com/khoa5773/go-server/src/domains/documents"
+	"github.com/khoa5773/go-server/src/domains/jobs"
+	"github.com/khoa5773/go-server/src/domains/predictions"
+	"github.com/khoa5773/go-server/src/domains/users"
+	"github.com/khoa5773/go-server/src/genomics"
+	"github.com/khoa5773/go-server/src/genomics/crossvalidation"
+	"github.com/khoa5773/go-server/src/shared"
+	"github.com/mitchellh/mapstructure"
+	"gopkg.in/mgo.v2/bson"
+)
+
+func FindManyValidations(findManyValidationsDto FindManyValidationsDto, filterValidationsDto FilterValidationsDto, credentials shared.Credentials) (shared.Result, error) {
+	var validations []map[string]interface{}
+	ValidationsModel := shared.MongoSession.C("validations")
+
+	query := bson.M{"projectID": findManyValidationsDto.ProjectID}
+	if filterValidationsDto.GenotypeID != "" {
+		query["genotypeID"] = filterValidationsDto.GenotypeID
+	}
+	if filterValidationsDto.PhenotypeID != "" {
+		query["phenotypeID"] = filterValidationsDto.PhenotypeID
+	}
+	if filterValidationsDto.Method != "" {
+		query["method"] = filterValidationsDto.Method
+	}
+	if filterValidationsDto.Trait != "" {
+		query["trait"] = filterValidationsDto.Trait
+	}
+	if filterValidationsDto.Seed != nil {
+		query["seed"] = *filterValidationsDto.Seed
+	}
+
+	err := ValidationsModel.Find(query).Select(bson.M{"foldResults": 0}).Sort("-mean.pearson").All(&validations)
+	if err != nil {
+		return shared.Result{}, err
+	}
+
+	data, err := shared.ValidateAccessToList(validations, credentials)
+	if err != nil {
+		return shared.Result{}, err
+	}
+
+	return *data, nil
+}
+
+func FindOneValidation(findOneValidationDto *FindOneValidationDto, credentials shared.Credentials) (Validation, error) {
+	var validation Validation
+	ValidationsModel := shared.MongoSession.C("validations")
+	err := ValidationsModel.Find(findOneValidationDto).One(&validation)
+	if err != nil {
+		return Validation{}, err
+	}
+
+	data, err := shared.ValidateAccessToSingle(structs.Map(validation), credentials)
+	if err != nil {
+		return Validation{}, err
+	}
+
+	err = mapstructure.Decode(data, &validation)
+	if err != nil {
+		return Validation{}, err
+	}
+
+	return validation, nil
+}
+
+// CreateOneValidation stores the validation as QUEUED and enqueues the job that runs the folds
+func CreateOneValidation(createOneValidationDto *CreateOneValidationDto, credentials shared.Credentials) (bool, error) {
+	createOneValidationDto.CreatedBy = credentials.Id
+	createOneValidationDto.UpdatedBy = credentials.Id
+	createOneValidationDto.CreatedAt = time.Now()
+	createOneValidationDto.UpdatedAt = time.Now()
+
+	if createOneValidationDto.Seed == nil {
+		seed := time.Now().UnixNano()
+		createOneValidationDto.Seed = &seed
+	}
+
+	validation := &Validation{}
+	err := mapstructure.Decode(structs.Map(createOneValidationDto), validation)
+	if err != nil {
+		return false, err
+	}
+
+	_, err = shared.ValidateAccessToSingle(structs.Map(validation), credentials)
+	if err != nil {
+		return false, err
+	}
+
+	genotypeDocument, err := predictions.FindDocumentVersionOfType(createOneValidationDto.GenotypeID, createOneValidationDto.ProjectID, documents.GENOTYPE, createOneValidationDto.GenotypeVersion, credentials)
+	if err != nil {
+		return false, err
+	}
+
+	phenotypeDocument, err := predictions.FindDocumentVersionOfType(createOneValidationDto.PhenotypeID, createOneValidationDto.ProjectID, documents.PHENOTYPE, createOneValidationDto.PhenotypeVersion, credentials)
+	if err != nil {
+		return false, err
+	}
+
+	createOneValidationDto.Trait, err = documents.ResolveTrait(phenotypeDocument, createOneValidationDto.Trait)
+	if err != nil {
+		return false, err
+	}
+
+	// the versions are recorded so that the validation keeps using the data it was started with
+	createOneValidationDto.GenotypeVersion = documents.CurrentVersion(genotypeDocument)
+	createOneValidationDto.PhenotypeVersion = documents.CurrentVersion(phenotypeDocument)
+
+	if len(createOneValidationDto.Parameters) == 0 {
+		createOneValidationDto.Parameters, err = predictions.FindDefaultParameters(createOneValidationDto.Method, credentials)
+		if err != nil {
+			return false, err
+		}
+	}
+
+	if createOneValidationDto.Folds == 0 || createOneValidationDto.Repetitions == 0 {
+		folds, repetitions, err := FindDefaultSettings(credentials)
+		if err != nil {
+			return false, err
+		}
+		if createOneValidationDto.Folds == 0 {
+			createOneValidationDto.Folds = folds
+		}
+		if createOneValidationDto.Repetitions == 0 {
+			createOneValidationDto.Repetitions = repetitions
+		}
+	}
+
+	createOneValidationDto.JobID = bson.NewObjectId()
+	createOneValidationDto.Status = QUEUED
+
+	ValidationsModel := shared.MongoSession.C("validations")
+	err = ValidationsModel.Insert(createOneValidationDto)
+	if err != nil {
+		return false, err
+	}
+
+	_, err = jobs.Enqueue(&jobs.CreateOneJobDto{
+		ID:        createOneValidationDto.JobID,
+		ProjectID: createOneValidationDto.ProjectID,
+		Type:      JOB_TYPE,
+		Payload:   map[string]interface{}{"validationID": createOneValidationDto.ID},
+	}, credentials)
+	if err != nil {
+		_ = ValidationsModel.RemoveId(createOneValidationDto.ID)
+		return false, err
+	}
+
+	return true, nil
+}
+
+func UpdateValidation(findOneValidationDto *FindOneValidationDto, updateValidationDto *UpdateValidationDto, credentials shared.Credentials) (bool, error) {
+	var validation *Validation
+	ValidationsModel := shared.MongoSession.C("validations")
+
+	err := ValidationsModel.Find(findOneValidationDto).One(&validation)
+	if err != nil {
+		return false, err
+	}
+
+	_, err = shared.ValidateAccessToSingle(structs.Map(*validation), credentials)
+	if err != nil {
+		return false, err
+	}
+
+	updateValidationDto.UpdatedAt = time.Now()
+	updateValidationDto.UpdatedBy = credentials.Id
+
+	err = ValidationsModel.Update(findOneValidationDto, bson.M{"$set": updateValidationDto})
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+func DeleteValidation(deleteValidationDto *DeleteValidationDto, credentials shared.Credentials) (bool, error) {
+	var validation *Validation
+	ValidationsModel := shared.MongoSession.C("validations")
+	err := ValidationsModel.Find(deleteValidationDto).One(&validation)
+	if err != nil {
+		return false, err
+	}
+
+	_, err = shared.ValidateAccessToSingle(structs.Map(*validation), credentials)
+	if err != nil {
+		return false, err
+	}
+
+	if validation.Status == QUEUED || validation.Status == RUNNING {
+		return false, errors.New("validation is still running, cancel its job first")
+	}
+
+	err = ValidationsModel.Remove(deleteValidationDto)
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// FindDefaultSettings reads the N_FOLD block of the user settingMethod, a fold count below 2 falls back to the default
+func FindDefaultSettings(credentials shared.Credentials) (int, int, error) {
+	credentials.IsAdmin = true
+	user, err := users.FindOneUser(&users.FindOneUserDto{ID: credentials.Id}, credentials)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	var nFold genomics.Parameters
+	switch v := user.SettingMethod["N_FOLD"].(type) {
+	case bson.M:
+		nFold = genomics.Parameters(v)
+	case map[string]interface{}:
+		nFold = v
+	}
+
+	folds := nFold.Int("n", crossvalidation.DefaultFolds)
+	if folds < 2 {
+		folds = crossvalidation.DefaultFolds
+	}
+
+	repetitions := nFold.Int("repetition", crossvalidation.DefaultRepetitions)
+	if repetitions < 1 {
+		repetitions = crossvalidation.DefaultRepetitions
+	}
+
+	return folds, repetitions, nil
+}
+
+func RunValidation(
+	ctx context.Context, genotypeDocument, phenotypeDocument documents.Document, method string, parameters map[string]interface{}, trait string,
+	settings crossvalidation.Settings,
+) (*crossvalidation.Result, error) {
+	predictionMethod, err := genomics.GetMethod(method)
+	if err != nil {
+		return nil, jobs.Permanent(err)
+	}
+
+	dataset, err := predictions.LoadDataset(ctx, genotypeDocument, phenotypeDocument, trait)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := crossvalidation.Run(ctx, predictionMethod, dataset, parameters, settings)
+	if err != nil {
+		return nil, jobs.Permanent(err)
+	}
+
+	return result, nil
+}
@@ -0,0 +1,183 @@
+package crossvalidation
+
+import (
+	"context"
+	"errors"
+	"math"
+	"math/rand"
+	"sort"
+
+	"github.com/khoa5773/go-server/src/genomics"
+)
+
+const (
+	DefaultFolds       = 5
+	DefaultRepetitions = 1
+)
+
+// Settings mirror the N_FOLD block of the user settingMethod
+type Settings struct {
+	Folds       int
+	Repetitions int
+	Seed        int64
+	// Progress is called after every fitted fold when set
+	Progress func(completed, total int)
+}
+
+type Metrics struct {
+	Pearson float64 `json:"pearson" bson:"pearson"`
+	RMSE    float64 `json:"rmse" bson:"rmse"`
+	Bias    float64 `json:"bias" bson:"bias"`
+}
+
+type FoldResult struct {
+	Repetition int     `json:"repetition" bson:"repetition"`
+	Fold       int     `json:"fold" bson:"fold"`
+	Samples    int     `json:"samples" bson:"samples"`
+	Metrics    Metrics `json:"metrics" bson:"metrics"`
+}
+
+type Result struct {
+	Folds       int          `json:"folds" bson:"folds"`
+	Repetitions int          `json:"repetitions" bson:"repetitions"`
+	Seed        int64        `json:"seed" bson:"seed"`
+	FoldResults []FoldResult `json:"foldResults" bson:"foldResults"`
+	Mean        Metrics      `json:"mean" bson:"mean"`
+	SD          Metrics      `json:"sd" bson:"sd"`
+}
+
+// Assign splits sample IDs into folds for one repetition. Sample IDs are sorted first
+// so that the same seed yields the same split whatever the row order of the documents.
+func Assign(sampleIDs []string, folds int, seed int64, repetition int) map[string]int {
+	sorted := append([]string(nil), sampleIDs...)
+	sort.Strings(sorted)
+
+	assignment := make(map[string]int, len(sorted))
+	permutation := rand.New(rand.NewSource(seed + int64(repetition))).Perm(len(sorted))
+	for position, index := range permutation {
+		assignment[sorted[index]] = position % folds
+	}
+	return assignment
+}
+
+// Run fits the method once per fold and repetition on the phenotyped samples of the dataset
+func Run(ctx context.Context, method genomics.Method, dataset *genomics.Dataset, parameters genomics.Parameters, settings Settings) (*Result, error) {
+	rows := dataset.TrainingRows()
+	if settings.Folds < 2 || settings.Folds > len(rows) {
+		return nil, errors.New("number of folds must be between 2 and the number of phenotyped samples")
+	}
+	if settings.Repetitions < 1 {
+		return nil, errors.New("number of repetitions must be positive")
+	}
+
+	sampleIDs := make([]string, len(rows))
+	for i, row := range rows {
+		sampleIDs[i] = dataset.Genotype.SampleIDs[row]
+	}
+
+	result := &Result{Folds: settings.Folds, Repetitions: settings.Repetitions, Seed: settings.Seed}
+	total := settings.Folds * settings.Repetitions
+	for repetition := 0; repetition < settings.Repetitions; repetition++ {
+		assignment := Assign(sampleIDs, settings.Folds, settings.Seed, repetition)
+
+		for fold := 0; fold < settings.Folds; fold++ {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+
+			var training, testing []int
+			for i, row := range rows {
+				if assignment[sampleIDs[i]] == fold {
+					testing = append(testing, row)
+				} else {
+					training = append(training, row)
+				}
+			}
+
+			model, err := genomics.Fit(ctx, method, dataset, training, parameters)
+			if err != nil {
+				return nil, err
+			}
+
+			predicted, err := model.Predict(dataset.Genotype.Subset(testing))
+			if err != nil {
+				return nil, err
+			}
+
+			observed := make([]float64, len(testing))
+			for i, row := range testing {
+				observed[i] = dataset.Observed[row]
+			}
+
+			result.FoldResults = append(result.FoldResults, FoldResult{
+				Repetition: repetition,
+				Fold:       fold,
+				Samples:    len(testing),
+				Metrics:    Evaluate(observed, predicted),
+			})
+
+			if settings.Progress != nil {
+				settings.Progress(len(result.FoldResults), total)
+			}
+		}
+	}
+
+	result.Mean, result.SD = aggregate(result.FoldResults)
+	return result, nil
+}
+
+// Evaluate returns the Pearson correlation, the RMSE and the slope of observed on predicted values.
+// Undefined metrics, e.g. the correlation of a constant prediction, are reported as 0.
+func Evaluate(observed, predicted []float64) Metrics {
+	n := float64(len(observed))
+	if n == 0 {
+		return Metrics{}
+	}
+
+	meanObserved, meanPredicted := 0.0, 0.0
+	for i := range observed {
+		meanObserved += observed[i] / n
+		meanPredicted += predicted[i] / n
+	}
+
+	covariance, varianceObserved, variancePredicted, squaredError := 0.0, 0.0, 0.0, 0.0
+	for i := range observed {
+		o, p := observed[i]-meanObserved, predicted[i]-meanPredicted
+		covariance += o * p
+		varianceObserved += o * o
+		variancePredicted += p * p
+		squaredError += (observed[i] - predicted[i]) * (observed[i] - predicted[i])
+	}
+
+	metrics := Metrics{RMSE: math.Sqrt(squaredError / n)}
+	if varianceObserved > 0 && variancePredicted > 0 {
+		metrics.Pearson = covariance / math.Sqrt(varianceObserved*variancePredicted)
+	}
+	if variancePredicted > 0 {
+		metrics.Bias = covariance / variancePredicted
+	}
+	return metrics
+}
+
+func aggregate(foldResults []FoldResult) (Metrics, Metrics) {
+	n := float64(len(foldResults))
+	var mean, sd Metrics
+	for _, v := range foldResults {
+		mean.Pearson += v.Metrics.Pearson / n
+		mean.RMSE += v.Metrics.RMSE / n
+		mean.Bias += v.Metrics.Bias / n
+	}
+
+	if n < 2 {
+		return mean, sd
+	}
+	for _, v := range foldResults {
+		sd.Pearson += math.Pow(v.Metrics.Pearson-mean.Pearson, 2) / (n - 1)
+		sd.RMSE += math.Pow(v.Metrics.RMSE-mean.RMSE, 2) / (n - 1)
+		sd.Bias += math.Pow(v.Metrics.Bias-mean.Bias, 2) / (n - 1)
+	}
+	sd.Pearson = math.Sqrt(sd.Pearson)
+	sd.RMSE = math.Sqrt(sd.RMSE)
+	sd.Bias = math.Sqrt(sd.Bias)
+	return mean, sd
+}
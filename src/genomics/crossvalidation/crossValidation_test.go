@@ -0,0 +1,71 @@
+package crossvalidation
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestAssignIsDeterministic(t *testing.T) {
+	sampleIDs := []string{"s1", "s2", "s3", "s4", "s5", "s6", "s7", "s8", "s9", "s10", "s11"}
+	reversed := make([]string, len(sampleIDs))
+	for i, v := range sampleIDs {
+		reversed[len(sampleIDs)-1-i] = v
+	}
+
+	tests := []struct {
+		folds      int
+		seed       int64
+		repetition int
+	}{
+		{2, 1, 0},
+		{5, 1, 0},
+		{5, 1, 1},
+		{5, 42, 0},
+		{11, 3, 0},
+	}
+	for _, test := range tests {
+		assignment := Assign(sampleIDs, test.folds, test.seed, test.repetition)
+		if again := Assign(sampleIDs, test.folds, test.seed, test.repetition); !reflect.DeepEqual(assignment, again) {
+			t.Errorf("%+v: two calls differ: %v and %v", test, assignment, again)
+		}
+		if shuffled := Assign(reversed, test.folds, test.seed, test.repetition); !reflect.DeepEqual(assignment, shuffled) {
+			t.Errorf("%+v: assignment depends on the sample order: %v and %v", test, assignment, shuffled)
+		}
+
+		sizes := make([]int, test.folds)
+		for _, fold := range assignment {
+			sizes[fold]++
+		}
+		for fold, size := range sizes {
+			if size < len(sampleIDs)/test.folds || size > len(sampleIDs)/test.folds+1 {
+				t.Errorf("%+v: fold %d has %d samples", test, fold, size)
+			}
+		}
+	}
+
+	if reflect.DeepEqual(Assign(sampleIDs, 5, 1, 0), Assign(sampleIDs, 5, 1, 1)) {
+		t.Error("repetitions use the same split")
+	}
+}
+
+func TestEvaluate(t *testing.T) {
+	tests := []struct {
+		name      string
+		observed  []float64
+		predicted []float64
+		want      Metrics
+	}{
+		{"exact", []float64{1, 2, 3}, []float64{1, 2, 3}, Metrics{Pearson: 1, RMSE: 0, Bias: 1}},
+		{"shrunk", []float64{1, 2, 3}, []float64{1.5, 2, 2.5}, Metrics{Pearson: 1, RMSE: math.Sqrt(1.0 / 6), Bias: 2}},
+		{"reversed", []float64{1, 2, 3}, []float64{3, 2, 1}, Metrics{Pearson: -1, RMSE: math.Sqrt(8.0 / 3), Bias: -1}},
+		{"constant prediction", []float64{1, 2, 3}, []float64{2, 2, 2}, Metrics{Pearson: 0, RMSE: math.Sqrt(2.0 / 3), Bias: 0}},
+		{"empty", nil, nil, Metrics{}},
+	}
+	for _, test := range tests {
+		got := Evaluate(test.observed, test.predicted)
+		if math.Abs(got.Pearson-test.want.Pearson) > 1e-12 || math.Abs(got.RMSE-test.want.RMSE) > 1e-12 || math.Abs(got.Bias-test.want.Bias) > 1e-12 {
+			t.Errorf("%s: got %+v, want %+v", test.name, got, test.want)
+		}
+	}
+}
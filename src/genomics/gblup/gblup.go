@@ -0,0 +1,110 @@
+package gblup
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+
+	"github.com/khoa5773/go-server/src/genomics"
+)
+
+type Method struct{}
+
+func init() {
+	genomics.RegisterMethod(genomics.GBLUP, Method{})
+}
+
+// Model keeps the GBLUP solution as equivalent marker effects so new genotypes can be scored without the kernel
+type Model struct {
+	genomics.LinearModel `bson:",inline"`
+	VarianceG            float64 `json:"varianceG" bson:"varianceG"`
+	VarianceE            float64 `json:"varianceE" bson:"varianceE"`
+	Heritability         float64 `json:"heritability" bson:"heritability"`
+	LogLikelihood        float64 `json:"logLikelihood" bson:"logLikelihood"`
+	Samples              int     `json:"samples" bson:"samples"`
+}
+
+func (Method) Decode(data []byte) (genomics.Model, error) {
+	model := &Model{}
+	err := json.Unmarshal(data, model)
+	if err != nil {
+		return nil, err
+	}
+	return model, nil
+}
+
+func (m *Model) Summary() map[string]float64 {
+	return map[string]float64{
+		"intercept":     m.Intercept,
+		"varianceG":     m.VarianceG,
+		"varianceE":     m.VarianceE,
+		"heritability":  m.Heritability,
+		"logLikelihood": m.LogLikelihood,
+		"samples":       float64(m.Samples),
+		"markers":       float64(len(m.MarkerIDs)),
+	}
+}
+
+// RelationshipMatrix builds the VanRaden genomic relationship matrix ZZ'/(2Σp(1-p)) from centered dosages
+func RelationshipMatrix(ctx context.Context, centered [][]float64, scale float64) ([][]float64, error) {
+	n := len(centered)
+	relationship := genomics.NewMatrix(n, n)
+	for i := 0; i < n; i++ {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		for j := 0; j <= i; j++ {
+			value := genomics.Dot(centered[i], centered[j]) / scale
+			relationship[i][j] = value
+			relationship[j][i] = value
+		}
+	}
+	return relationship, nil
+}
+
+func Scale(frequencies []float64) float64 {
+	scale := 0.0
+	for _, p := range frequencies {
+		scale += 2 * p * (1 - p)
+	}
+	return scale
+}
+
+func (Method) Fit(ctx context.Context, genotype *genomics.Genotype, phenotypes []float64, parameters genomics.Parameters) (genomics.Model, error) {
+	frequencies := genotype.AlleleFrequencies()
+	scale := Scale(frequencies)
+	if scale == 0 {
+		return nil, errors.New("genotype has no polymorphic markers")
+	}
+
+	centered, err := genotype.Centered(frequencies)
+	if err != nil {
+		return nil, err
+	}
+
+	relationship, err := RelationshipMatrix(ctx, centered, scale)
+	if err != nil {
+		return nil, err
+	}
+
+	solution, err := Solve(ctx, relationship, phenotypes, parameters.Float("heritability", 0))
+	if err != nil {
+		return nil, err
+	}
+
+	model := &Model{
+		LinearModel:   genomics.NewLinearModel(genotype.Markers, frequencies),
+		VarianceG:     solution.VarianceG,
+		VarianceE:     solution.VarianceE,
+		Heritability:  solution.Heritability(),
+		LogLikelihood: solution.LogLikelihood,
+		Samples:       len(phenotypes),
+	}
+	model.Intercept = solution.Intercept
+	model.Effects = genomics.MulTransVec(centered, solution.Alpha)
+	for j := range model.Effects {
+		model.Effects[j] /= scale
+	}
+
+	return model, nil
+}
@@ -0,0 +1,128 @@
+package gblup
+
+import (
+	"context"
+	"math"
+	"testing"
+)
+
+func near(a, b float64) bool {
+	return math.Abs(a-b) <= 1e-9*math.Max(1, math.Abs(b))
+}
+
+func TestSolveFixedHeritability(t *testing.T) {
+	// With h² = 0.5, δ = 1 and V = K + I. For this K the GLS intercept is 42/13
+	// and α = V⁻¹(y - 1β) = (-40/39, -14/39, 18/13).
+	kernel := [][]float64{
+		{1, 0.5, 0},
+		{0.5, 1, 0},
+		{0, 0, 1},
+	}
+	y := []float64{1, 2, 6}
+
+	solution, err := Solve(context.Background(), kernel, y, 0.5)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !near(solution.Delta, 1) {
+		t.Errorf("delta = %v, want 1", solution.Delta)
+	}
+	if !near(solution.Intercept, 42.0/13) {
+		t.Errorf("intercept = %v, want %v", solution.Intercept, 42.0/13)
+	}
+	for i, want := range []float64{-40.0 / 39, -14.0 / 39, 18.0 / 13} {
+		if !near(solution.Alpha[i], want) {
+			t.Errorf("alpha[%d] = %v, want %v", i, solution.Alpha[i], want)
+		}
+	}
+	// σg² = (y - 1β)'V⁻¹(y - 1β) / (n - 1)
+	if want := 1664.0 / 507; !near(solution.VarianceG, want) {
+		t.Errorf("varianceG = %v, want %v", solution.VarianceG, want)
+	}
+	if !near(solution.VarianceE, solution.VarianceG) || !near(solution.Heritability(), 0.5) {
+		t.Errorf("varianceE = %v, heritability = %v", solution.VarianceE, solution.Heritability())
+	}
+}
+
+func TestSolveIdentityKernel(t *testing.T) {
+	y := []float64{3, 5, 7, 9}
+	kernel := [][]float64{{1, 0, 0, 0}, {0, 1, 0, 0}, {0, 0, 1, 0}, {0, 0, 0, 1}}
+
+	for _, heritability := range []float64{0.2, 0.5, 0.8} {
+		solution, err := Solve(context.Background(), kernel, y, heritability)
+		if err != nil {
+			t.Fatal(err)
+		}
+		delta := (1 - heritability) / heritability
+		if !near(solution.Intercept, 6) {
+			t.Errorf("h² %v: intercept = %v, want 6", heritability, solution.Intercept)
+		}
+		for i, v := range y {
+			if want := (v - 6) / (1 + delta); !near(solution.Alpha[i], want) {
+				t.Errorf("h² %v: alpha[%d] = %v, want %v", heritability, i, solution.Alpha[i], want)
+			}
+		}
+	}
+}
+
+func TestSolveREMLMaximizesLikelihood(t *testing.T) {
+	kernel := [][]float64{
+		{1.0, 0.6, 0.1, 0.0, 0.2},
+		{0.6, 1.0, 0.2, 0.1, 0.0},
+		{0.1, 0.2, 1.0, 0.5, 0.3},
+		{0.0, 0.1, 0.5, 1.0, 0.4},
+		{0.2, 0.0, 0.3, 0.4, 1.0},
+	}
+	y := []float64{4.1, 3.7, 6.2, 5.9, 5.1}
+
+	reml, err := Solve(context.Background(), kernel, y, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, heritability := range []float64{0.05, 0.25, 0.5, 0.75, 0.95} {
+		fixed, err := Solve(context.Background(), kernel, y, heritability)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if fixed.LogLikelihood > reml.LogLikelihood+1e-9 {
+			t.Errorf("h² %v has log-likelihood %v above the REML estimate %v", heritability, fixed.LogLikelihood, reml.LogLikelihood)
+		}
+	}
+}
+
+func TestSolveErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		kernel [][]float64
+		y      []float64
+	}{
+		{"too few samples", [][]float64{{1, 0}, {0, 1}}, []float64{1, 2}},
+		{"kernel size", [][]float64{{1, 0}, {0, 1}}, []float64{1, 2, 3}},
+	}
+	for _, test := range tests {
+		if _, err := Solve(context.Background(), test.kernel, test.y, 0); err == nil {
+			t.Errorf("%s: expected an error", test.name)
+		}
+	}
+}
+
+func TestRelationshipMatrix(t *testing.T) {
+	centered := [][]float64{{1, -1}, {-1, 1}, {0, 0}}
+	relationship, err := RelationshipMatrix(context.Background(), centered, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := [][]float64{{1, -1, 0}, {-1, 1, 0}, {0, 0, 0}}
+	for i := range want {
+		for j := range want[i] {
+			if !near(relationship[i][j], want[i][j]) {
+				t.Errorf("G[%d][%d] = %v, want %v", i, j, relationship[i][j], want[i][j])
+			}
+		}
+	}
+	if scale := Scale([]float64{0.5, 0.25}); !near(scale, 0.875) {
+		t.Errorf("scale = %v, want 0.875", scale)
+	}
+}
@@ -0,0 +1,161 @@
+package gblup
+
+import (
+	"context"
+	"errors"
+	"math"
+
+	"github.com/khoa5773/go-server/src/genomics"
+)
+
+const (
+	minLogDelta = -10.0
+	maxLogDelta = 10.0
+	gridPoints  = 41
+)
+
+// Solution of y = 1β + g + e with g ~ N(0, σg²K) and e ~ N(0, σe²I)
+type Solution struct {
+	Intercept     float64
+	VarianceG     float64
+	VarianceE     float64
+	Delta         float64
+	LogLikelihood float64
+	// Alpha is (K + δI)⁻¹(y - 1β), so that the BLUP of g for any individual is K(·, train)·Alpha
+	Alpha []float64
+}
+
+func (s *Solution) Heritability() float64 {
+	return s.VarianceG / (s.VarianceG + s.VarianceE)
+}
+
+type rotatedModel struct {
+	eigenvalues []float64
+	y           []float64
+	x           []float64
+}
+
+// Solve fits the mixed model equations through the spectral decomposition of the
+// relationship kernel. Variance components are estimated by REML unless a fixed
+// heritability in (0, 1) is given.
+func Solve(ctx context.Context, kernel [][]float64, y []float64, heritability float64) (*Solution, error) {
+	n := len(y)
+	if n < 3 {
+		return nil, errors.New("at least three phenotyped individuals are required")
+	}
+	if len(kernel) != n {
+		return nil, errors.New("kernel does not match phenotypes")
+	}
+
+	eigenvalues, eigenvectors, err := genomics.SymmetricEigen(kernel)
+	if err != nil {
+		return nil, err
+	}
+	if err = ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	for i, v := range eigenvalues {
+		if v < 0 {
+			eigenvalues[i] = 0
+		}
+	}
+
+	ones := make([]float64, n)
+	for i := range ones {
+		ones[i] = 1
+	}
+	model := rotatedModel{
+		eigenvalues: eigenvalues,
+		y:           genomics.MulTransVec(eigenvectors, y),
+		x:           genomics.MulTransVec(eigenvectors, ones),
+	}
+
+	var logDelta float64
+	if heritability > 0 && heritability < 1 {
+		logDelta = math.Log((1 - heritability) / heritability)
+	} else {
+		logDelta = model.maximize()
+	}
+
+	delta := math.Exp(logDelta)
+	intercept, quadratic, logLikelihood := model.evaluate(delta)
+	varianceG := quadratic / float64(n-1)
+
+	weighted := make([]float64, n)
+	for i, v := range eigenvalues {
+		weighted[i] = (model.y[i] - model.x[i]*intercept) / (v + delta)
+	}
+
+	return &Solution{
+		Intercept:     intercept,
+		VarianceG:     varianceG,
+		VarianceE:     varianceG * delta,
+		Delta:         delta,
+		LogLikelihood: logLikelihood,
+		Alpha:         genomics.MulVec(eigenvectors, weighted),
+	}, nil
+}
+
+// evaluate returns the GLS intercept, the weighted residual sum of squares and the REML log-likelihood
+func (m *rotatedModel) evaluate(delta float64) (float64, float64, float64) {
+	n := len(m.y)
+	xwx, xwy, logDeterminant := 0.0, 0.0, 0.0
+	for i, v := range m.eigenvalues {
+		w := 1 / (v + delta)
+		xwx += w * m.x[i] * m.x[i]
+		xwy += w * m.x[i] * m.y[i]
+		logDeterminant += math.Log(v + delta)
+	}
+	intercept := xwy / xwx
+
+	quadratic := 0.0
+	for i, v := range m.eigenvalues {
+		r := m.y[i] - m.x[i]*intercept
+		quadratic += r * r / (v + delta)
+	}
+
+	df := float64(n - 1)
+	logLikelihood := -0.5 * (df*math.Log(2*math.Pi*quadratic/df) + df + logDeterminant + math.Log(xwx))
+	return intercept, quadratic, logLikelihood
+}
+
+func (m *rotatedModel) logLikelihood(logDelta float64) float64 {
+	_, _, logLikelihood := m.evaluate(math.Exp(logDelta))
+	if math.IsNaN(logLikelihood) {
+		return math.Inf(-1)
+	}
+	return logLikelihood
+}
+
+// maximize scans a grid of log δ and refines the best cell by golden section search
+func (m *rotatedModel) maximize() float64 {
+	step := (maxLogDelta - minLogDelta) / (gridPoints - 1)
+	best, bestValue := minLogDelta, math.Inf(-1)
+	for i := 0; i < gridPoints; i++ {
+		logDelta := minLogDelta + float64(i)*step
+		if value := m.logLikelihood(logDelta); value > bestValue {
+			best, bestValue = logDelta, value
+		}
+	}
+
+	lower := math.Max(minLogDelta, best-step)
+	upper := math.Min(maxLogDelta, best+step)
+	ratio := (math.Sqrt(5) - 1) / 2
+	a := upper - ratio*(upper-lower)
+	b := lower + ratio*(upper-lower)
+	fa, fb := m.logLikelihood(a), m.logLikelihood(b)
+	for upper-lower > 1e-6 {
+		if fa > fb {
+			upper, b, fb = b, a, fa
+			a = upper - ratio*(upper-lower)
+			fa = m.logLikelihood(a)
+		} else {
+			lower, a, fa = a, b, fb
+			b = lower + ratio*(upper-lower)
+			fb = m.logLikelihood(b)
+		}
+	}
+
+	return (lower + upper) / 2
+}
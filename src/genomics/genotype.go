@@ -0,0 +1,435 @@
+package genomics
+
+import (
+	"bufio"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// GenotypeCoding is how the calls of a genotype file are written
+type GenotypeCoding string
+
+const (
+	DOSAGE_012 GenotypeCoding = "DOSAGE_012"
+	DOSAGE_101 GenotypeCoding = "DOSAGE_101"
+	NUCLEOTIDE GenotypeCoding = "NUCLEOTIDE"
+	IUPAC      GenotypeCoding = "IUPAC"
+)
+
+// Marker describes a single genotyped locus, dosages count its alternate allele
+type Marker struct {
+	ID         string `json:"id" bson:"id"`
+	Chromosome string `json:"chromosome,omitempty" bson:"chromosome,omitempty"`
+	Position   int64  `json:"position,omitempty" bson:"position,omitempty"`
+	Reference  string `json:"reference,omitempty" bson:"reference,omitempty"`
+	Alternate  string `json:"alternate,omitempty" bson:"alternate,omitempty"`
+}
+
+// Genotype holds one row of allele dosages per sample, NaN marks a missing call
+type Genotype struct {
+	SampleIDs []string
+	Markers   []Marker
+	Dosages   [][]float64
+}
+
+func (g *Genotype) NumSamples() int {
+	return len(g.SampleIDs)
+}
+
+func (g *Genotype) NumMarkers() int {
+	return len(g.Markers)
+}
+
+func (g *Genotype) SampleIndex() map[string]int {
+	index := make(map[string]int, len(g.SampleIDs))
+	for i, v := range g.SampleIDs {
+		index[v] = i
+	}
+	return index
+}
+
+// Subset returns a genotype restricted to the given sample rows, dosage rows are shared
+func (g *Genotype) Subset(rows []int) *Genotype {
+	subset := &Genotype{
+		SampleIDs: make([]string, len(rows)),
+		Markers:   g.Markers,
+		Dosages:   make([][]float64, len(rows)),
+	}
+	for i, row := range rows {
+		subset.SampleIDs[i] = g.SampleIDs[row]
+		subset.Dosages[i] = g.Dosages[row]
+	}
+	return subset
+}
+
+// AlleleFrequencies returns the alternate allele frequency of each marker ignoring missing calls
+func (g *Genotype) AlleleFrequencies() []float64 {
+	frequencies := make([]float64, g.NumMarkers())
+	for j := range frequencies {
+		sum, count := 0.0, 0
+		for _, row := range g.Dosages {
+			if !math.IsNaN(row[j]) {
+				sum += row[j]
+				count++
+			}
+		}
+		if count > 0 {
+			frequencies[j] = sum / float64(2*count)
+		}
+	}
+	return frequencies
+}
+
+// Centered returns dosages minus twice the given allele frequencies, missing calls become 0
+func (g *Genotype) Centered(frequencies []float64) ([][]float64, error) {
+	if len(frequencies) != g.NumMarkers() {
+		return nil, errors.New("allele frequencies do not match markers")
+	}
+
+	centered := make([][]float64, g.NumSamples())
+	for i, row := range g.Dosages {
+		if len(row) != len(frequencies) {
+			return nil, fmt.Errorf("sample %s has %d markers, expected %d", g.SampleIDs[i], len(row), len(frequencies))
+		}
+		centered[i] = make([]float64, len(row))
+		for j, v := range row {
+			if !math.IsNaN(v) {
+				centered[i][j] = v - 2*frequencies[j]
+			}
+		}
+	}
+	return centered, nil
+}
+
+// WriteMatrix writes a genotype as a tab separated sample by marker dosage matrix, NA marks a missing call
+func WriteMatrix(w io.Writer, genotype *Genotype) error {
+	buffered := bufio.NewWriter(w)
+
+	buffered.WriteString("sample")
+	for _, marker := range genotype.Markers {
+		buffered.WriteString("\t" + marker.ID)
+	}
+	buffered.WriteString("\n")
+	for i, row := range genotype.Dosages {
+		buffered.WriteString(genotype.SampleIDs[i])
+		for _, dosage := range row {
+			value := "NA"
+			if !math.IsNaN(dosage) {
+				value = strconv.FormatFloat(dosage, 'g', -1, 64)
+			}
+			buffered.WriteString("\t" + value)
+		}
+		buffered.WriteString("\n")
+	}
+
+	return buffered.Flush()
+}
+
+func delimiterFromMimeType(mimeType string) rune {
+	switch strings.ToLower(mimeType) {
+	case "csv":
+		return ','
+	default:
+		return '\t'
+	}
+}
+
+// readRecords calls visit with the fields of every record of a delimited file and the line the record
+// starts on, blank lines and # comments are skipped. Fields follow the CSV quoting rules, so a quoted
+// field can hold the delimiter, doubled quotes and line breaks. Tab separated lines without a tab are
+// split on whitespace.
+func readRecords(r io.Reader, mimeType string, maxLine int, visit func(lineNumber int, fields []string) error) error {
+	delimiter := delimiterFromMimeType(mimeType)
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 1024*1024), maxLine)
+
+	var record strings.Builder
+	inQuotes := false
+	lineNumber, start := 0, 0
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if inQuotes {
+			record.WriteString("\n")
+		} else {
+			if strings.TrimSpace(line) == "" || strings.HasPrefix(line, "#") {
+				continue
+			}
+			start = lineNumber
+		}
+		record.WriteString(line)
+
+		inQuotes = endsInQuotedField(line, delimiter, inQuotes)
+		if inQuotes {
+			continue
+		}
+
+		fields, err := splitRecord(record.String(), delimiter)
+		record.Reset()
+		if err == nil {
+			err = visit(start, fields)
+		}
+		if err != nil {
+			return fmt.Errorf("line %d: %v", start, err)
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("line %d: %v", lineNumber+1, err)
+	}
+	if inQuotes {
+		return fmt.Errorf("line %d: quoted field is not closed", start)
+	}
+
+	return nil
+}
+
+// endsInQuotedField tells whether a quoted field is still open at the end of a line, given whether one
+// was open at its start. A quote only opens a field at its start, as encoding/csv with LazyQuotes reads.
+func endsInQuotedField(line string, delimiter rune, inQuotes bool) bool {
+	fieldStart := !inQuotes
+	for i := 0; i < len(line); i++ {
+		c := rune(line[i])
+		switch {
+		case inQuotes:
+			if c == '"' {
+				if i+1 < len(line) && line[i+1] == '"' {
+					i++
+				} else {
+					inQuotes = false
+				}
+			}
+		case c == '"' && fieldStart:
+			inQuotes = true
+			fieldStart = false
+		case c == delimiter:
+			fieldStart = true
+		case c == ' ' && delimiter == ',' && fieldStart:
+			// spaces before a quote are dropped by TrimLeadingSpace
+		default:
+			fieldStart = false
+		}
+	}
+	return inQuotes
+}
+
+func splitRecord(record string, delimiter rune) ([]string, error) {
+	var fields []string
+	if delimiter == '\t' && !strings.Contains(record, "\t") {
+		fields = strings.Fields(record)
+	} else {
+		reader := csv.NewReader(strings.NewReader(record))
+		reader.Comma = delimiter
+		reader.LazyQuotes = true
+		reader.FieldsPerRecord = -1
+		reader.TrimLeadingSpace = delimiter == ','
+		var err error
+		fields, err = reader.Read()
+		if err != nil {
+			return nil, err
+		}
+	}
+	for i, v := range fields {
+		fields[i] = strings.TrimSpace(v)
+	}
+	return fields, nil
+}
+
+func isMissing(value string) bool {
+	switch strings.ToUpper(value) {
+	case "", "NA", "NAN", ".", "-", "?":
+		return true
+	}
+	return false
+}
+
+func parseValue(value string) (float64, error) {
+	if isMissing(value) {
+		return math.NaN(), nil
+	}
+	return strconv.ParseFloat(value, 64)
+}
+
+// IsGenotypeFormat tells whether files with the given extension can be read by ReadGenotype
+func IsGenotypeFormat(mimeType string) bool {
+	switch strings.ToLower(mimeType) {
+	case "csv", "tsv", "txt", PLINK_MIME_TYPE:
+		return true
+	}
+	return IsVCF(mimeType) || IsHapMap(mimeType)
+}
+
+// ReadGenotype reads a genotype file as alternate allele dosages. VCF files and PLINK archives are read
+// with ReadVCF and ReadPLINKArchive, HapMap files and dosage matrices are normalized from the given
+// coding, or the detected one when it is empty. Matrices of only 0 and 1 are read as 0/1/2.
+func ReadGenotype(r io.Reader, mimeType string, coding GenotypeCoding) (*Genotype, error) {
+	genotype, _, _, err := readGenotype(r, mimeType, coding, false)
+	return genotype, err
+}
+
+// InspectGenotype validates a genotype file and returns its sample IDs and a summary of its variants,
+// with the coding that was given or detected. The coding of a matrix of only 0 and 1 can not be
+// detected and must be given.
+func InspectGenotype(r io.Reader, mimeType string, coding GenotypeCoding) ([]string, *VariantSummary, error) {
+	_, sampleIDs, summary, err := readGenotype(r, mimeType, coding, true)
+	return sampleIDs, summary, err
+}
+
+// readGenotype reads a genotype file, VCF files and PLINK archives are only validated when strict
+func readGenotype(
+	r io.Reader, mimeType string, coding GenotypeCoding, strict bool,
+) (*Genotype, []string, *VariantSummary, error) {
+	if IsVCF(mimeType) || strings.ToLower(mimeType) == PLINK_MIME_TYPE {
+		if coding != "" {
+			return nil, nil, nil, fmt.Errorf("the coding of %s files can not be set, only of HapMap files and dosage matrices", mimeType)
+		}
+	}
+
+	switch {
+	case IsVCF(mimeType) && strict:
+		sampleIDs, summary, err := ValidateVCF(r)
+		return nil, sampleIDs, summary, err
+	case IsVCF(mimeType):
+		genotype, err := ReadVCF(r)
+		return genotype, nil, nil, err
+	case strings.ToLower(mimeType) == PLINK_MIME_TYPE && strict:
+		sampleIDs, summary, err := ValidatePLINKArchive(r)
+		return nil, sampleIDs, summary, err
+	case strings.ToLower(mimeType) == PLINK_MIME_TYPE:
+		genotype, err := ReadPLINKArchive(r)
+		return genotype, nil, nil, err
+	case IsHapMap(mimeType):
+		genotype, summary, err := readHapMap(r, coding)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		return genotype, genotype.SampleIDs, summary, nil
+	}
+
+	genotype, err := readMatrix(r, mimeType)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	coding, err = normalizeMatrix(genotype, coding, strict)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	summary := &VariantSummary{
+		FileFormat:  "dosage matrix",
+		Samples:     genotype.NumSamples(),
+		Variants:    genotype.NumMarkers(),
+		Chromosomes: []ChromosomeSummary{},
+		Coding:      coding,
+	}
+	missing := 0
+	for _, row := range genotype.Dosages {
+		for _, v := range row {
+			if math.IsNaN(v) {
+				missing++
+			}
+		}
+	}
+	if genotype.NumMarkers() > 0 {
+		summary.MissingRate = float64(missing) / float64(genotype.NumSamples()*genotype.NumMarkers())
+	}
+
+	return genotype, genotype.SampleIDs, summary, nil
+}
+
+// normalizeMatrix detects the coding of a dosage matrix when none is given, checks its values against
+// the coding and shifts -1/0/1 values to 0/1/2
+func normalizeMatrix(genotype *Genotype, coding GenotypeCoding, strict bool) (GenotypeCoding, error) {
+	minimum, maximum := math.Inf(1), math.Inf(-1)
+	for _, row := range genotype.Dosages {
+		for _, v := range row {
+			if !math.IsNaN(v) {
+				minimum = math.Min(minimum, v)
+				maximum = math.Max(maximum, v)
+			}
+		}
+	}
+
+	if coding == "" {
+		switch {
+		case minimum < 0:
+			coding = DOSAGE_101
+		case maximum > 1:
+			coding = DOSAGE_012
+		case strict:
+			return "", fmt.Errorf("dosages are all 0 or 1, set the coding to %s or %s", DOSAGE_012, DOSAGE_101)
+		default:
+			coding = DOSAGE_012
+		}
+	}
+
+	shift := 0.0
+	switch coding {
+	case DOSAGE_012:
+	case DOSAGE_101:
+		shift = 1
+	default:
+		return "", fmt.Errorf("dosage matrices use the %s or %s coding, not %s", DOSAGE_012, DOSAGE_101, coding)
+	}
+
+	for i, row := range genotype.Dosages {
+		for j, v := range row {
+			if math.IsNaN(v) {
+				continue
+			}
+			if v+shift < 0 || v+shift > 2 {
+				return "", fmt.Errorf("sample %s: dosage %v for marker %s is outside the %s coding", genotype.SampleIDs[i], v, genotype.Markers[j].ID, coding)
+			}
+			row[j] = v + shift
+		}
+	}
+
+	return coding, nil
+}
+
+// readMatrix parses a sample by marker dosage matrix, the header row names the markers
+func readMatrix(r io.Reader, mimeType string) (*Genotype, error) {
+	genotype := &Genotype{}
+	err := readRecords(r, mimeType, 1024*1024*1024, func(lineNumber int, fields []string) error {
+		if genotype.Markers == nil {
+			if len(fields) < 2 {
+				return errors.New("header must contain a sample column and at least one marker")
+			}
+			genotype.Markers = make([]Marker, len(fields)-1)
+			for j, v := range fields[1:] {
+				genotype.Markers[j] = Marker{ID: v}
+			}
+			return nil
+		}
+
+		if len(fields) != len(genotype.Markers)+1 {
+			return fmt.Errorf("expected %d columns, got %d", len(genotype.Markers)+1, len(fields))
+		}
+
+		row := make([]float64, len(genotype.Markers))
+		for j, v := range fields[1:] {
+			value, err := parseValue(v)
+			if err != nil {
+				return fmt.Errorf("invalid dosage %q for marker %s", v, genotype.Markers[j].ID)
+			}
+			row[j] = value
+		}
+		genotype.SampleIDs = append(genotype.SampleIDs, fields[0])
+		genotype.Dosages = append(genotype.Dosages, row)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if genotype.NumSamples() == 0 {
+		return nil, errors.New("genotype contains no samples")
+	}
+
+	return genotype, nil
+}
@@ -0,0 +1,138 @@
+package genomics
+
+import (
+	"bytes"
+	"io/ioutil"
+	"math"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func readFixture(t *testing.T, name string) []byte {
+	t.Helper()
+	content, err := ioutil.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return content
+}
+
+func openFixture(t *testing.T, name string) *bytes.Reader {
+	t.Helper()
+	return bytes.NewReader(readFixture(t, name))
+}
+
+// checkDosages compares dosages sample by sample, NaN matches NaN
+func checkDosages(t *testing.T, genotype *Genotype, want [][]float64) {
+	t.Helper()
+	if len(genotype.Dosages) != len(want) {
+		t.Fatalf("%d dosage rows, want %d", len(genotype.Dosages), len(want))
+	}
+	for i, row := range want {
+		if len(genotype.Dosages[i]) != len(row) {
+			t.Fatalf("sample %d has %d dosages, want %d", i, len(genotype.Dosages[i]), len(row))
+		}
+		for j, v := range row {
+			got := genotype.Dosages[i][j]
+			if got != v && !(math.IsNaN(got) && math.IsNaN(v)) {
+				t.Errorf("dosage of sample %s at marker %s = %v, want %v", genotype.SampleIDs[i], genotype.Markers[j].ID, got, v)
+			}
+		}
+	}
+}
+
+func checkError(t *testing.T, err error, want string) {
+	t.Helper()
+	if err == nil {
+		t.Fatalf("no error, want %q", want)
+	}
+	if !strings.Contains(err.Error(), want) {
+		t.Fatalf("error %q, want %q", err, want)
+	}
+}
+
+func TestReadDosageMatrix(t *testing.T) {
+	nan := math.NaN()
+	tests := []struct {
+		fixture string
+		coding  GenotypeCoding
+		want    [][]float64
+	}{
+		{fixture: "dosage012.csv", coding: DOSAGE_012, want: [][]float64{{0, 1, 2}, {2, nan, 1}}},
+		// -1/0/1 is shifted to 0/1/2
+		{fixture: "dosage101.tsv", coding: DOSAGE_101, want: [][]float64{{0, 1, 2}, {2, nan, 1}}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.fixture, func(t *testing.T) {
+			mimeType := test.fixture[strings.LastIndex(test.fixture, ".")+1:]
+			sampleIDs, summary, err := InspectGenotype(openFixture(t, test.fixture), mimeType, "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if summary.Coding != test.coding {
+				t.Errorf("detected coding %s, want %s", summary.Coding, test.coding)
+			}
+			if !reflect.DeepEqual(sampleIDs, []string{"S1", "S2"}) {
+				t.Errorf("sample IDs %v", sampleIDs)
+			}
+			if summary.Variants != 3 || summary.MissingRate != 1.0/6 {
+				t.Errorf("summary %+v", summary)
+			}
+
+			genotype, err := ReadGenotype(openFixture(t, test.fixture), mimeType, "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			checkDosages(t, genotype, test.want)
+		})
+	}
+}
+
+func TestDosageMatrixCoding(t *testing.T) {
+	// only 0 and 1 could be either coding, which validation refuses to guess
+	_, _, err := InspectGenotype(openFixture(t, "dosage01.csv"), "csv", "")
+	checkError(t, err, "dosages are all 0 or 1, set the coding to DOSAGE_012 or DOSAGE_101")
+
+	_, summary, err := InspectGenotype(openFixture(t, "dosage01.csv"), "csv", DOSAGE_101)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if summary.Coding != DOSAGE_101 {
+		t.Errorf("coding %s, want %s", summary.Coding, DOSAGE_101)
+	}
+
+	genotype, err := ReadGenotype(openFixture(t, "dosage01.csv"), "csv", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkDosages(t, genotype, [][]float64{{0, 1}, {1, 0}})
+
+	_, _, err = InspectGenotype(openFixture(t, "dosage101.tsv"), "tsv", DOSAGE_012)
+	checkError(t, err, "sample S1: dosage -1 for marker m1 is outside the DOSAGE_012 coding")
+
+	_, _, err = InspectGenotype(openFixture(t, "dosage012.csv"), "csv", IUPAC)
+	checkError(t, err, "dosage matrices use the DOSAGE_012 or DOSAGE_101 coding, not IUPAC")
+
+	_, _, err = InspectGenotype(openFixture(t, "sample.vcf"), "vcf", DOSAGE_012)
+	checkError(t, err, "the coding of vcf files can not be set")
+}
+
+func TestDosageMatrixErrors(t *testing.T) {
+	tests := []struct {
+		content string
+		want    string
+	}{
+		{content: "sample\n", want: "line 1: header must contain a sample column and at least one marker"},
+		{content: "sample,m1,m2\nS1,0,1\nS2,2\n", want: "line 3: expected 3 columns, got 2"},
+		{content: "# comment\nsample,m1\n\nS1,x\n", want: `line 4: invalid dosage "x" for marker m1`},
+		{content: "sample,m1\n", want: "genotype contains no samples"},
+	}
+
+	for _, test := range tests {
+		_, _, err := InspectGenotype(strings.NewReader(test.content), "csv", DOSAGE_012)
+		checkError(t, err, test.want)
+	}
+}
@@ -0,0 +1,208 @@
+package genomics
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// hapMapColumns are the columns before the samples in a HapMap file
+const hapMapColumns = 11
+
+// iupacBases are the two bases of each IUPAC nucleotide code
+var iupacBases = map[byte]string{
+	'A': "AA", 'C': "CC", 'G': "GG", 'T': "TT",
+	'R': "AG", 'Y': "CT", 'S': "CG", 'W': "AT", 'K': "GT", 'M': "AC",
+}
+
+// IsHapMap tells whether a file with the given extension is read as HapMap
+func IsHapMap(mimeType string) bool {
+	switch strings.ToLower(mimeType) {
+	case "hmp", "hmp.txt":
+		return true
+	}
+	return false
+}
+
+// readHapMap reads a marker by sample HapMap file as alternate allele dosages, the first allele of the
+// alleles column is the reference. The coding is detected from the first call unless given.
+func readHapMap(r io.Reader, coding GenotypeCoding) (*Genotype, *VariantSummary, error) {
+	if coding != "" && coding != NUCLEOTIDE && coding != IUPAC {
+		return nil, nil, fmt.Errorf("HapMap files use the %s or %s coding, not %s", NUCLEOTIDE, IUPAC, coding)
+	}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 1024*1024), 1024*1024*1024)
+
+	var sampleIDs []string
+	var markers []Marker
+	var variants [][]float64
+	summary := &VariantSummary{FileFormat: "HapMap", Chromosomes: []ChromosomeSummary{}}
+	chromosomes := map[string]int{}
+	calls, missingCalls := 0, 0
+
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		fields := strings.Fields(line)
+
+		if sampleIDs == nil {
+			if len(fields) <= hapMapColumns || !strings.EqualFold(fields[0], "rs#") || !strings.EqualFold(fields[1], "alleles") {
+				return nil, nil, fmt.Errorf("line %d: expected a HapMap header starting with rs# alleles chrom pos and naming the samples after column %d", lineNumber, hapMapColumns)
+			}
+			sampleIDs = fields[hapMapColumns:]
+			seen := map[string]bool{}
+			for _, sampleID := range sampleIDs {
+				if seen[sampleID] {
+					return nil, nil, fmt.Errorf("line %d: duplicate sample ID %s", lineNumber, sampleID)
+				}
+				seen[sampleID] = true
+			}
+			continue
+		}
+
+		if len(fields) != hapMapColumns+len(sampleIDs) {
+			return nil, nil, fmt.Errorf("line %d: expected %d columns, got %d", lineNumber, hapMapColumns+len(sampleIDs), len(fields))
+		}
+
+		alleles := strings.Split(strings.ToUpper(fields[1]), "/")
+		if len(alleles) < 2 {
+			return nil, nil, fmt.Errorf("line %d: invalid alleles %q, expected reference/alternate", lineNumber, fields[1])
+		}
+		for _, allele := range alleles {
+			if len(allele) != 1 || !strings.Contains("ACGT", allele) {
+				return nil, nil, fmt.Errorf("line %d: invalid alleles %q, only single nucleotides are supported", lineNumber, fields[1])
+			}
+		}
+
+		position, err := strconv.ParseInt(fields[3], 10, 64)
+		if err != nil || position < 0 {
+			return nil, nil, fmt.Errorf("line %d: invalid position %q", lineNumber, fields[3])
+		}
+
+		marker := Marker{
+			ID:         fields[0],
+			Chromosome: fields[2],
+			Position:   position,
+			Reference:  alleles[0],
+			Alternate:  strings.Join(alleles[1:], ","),
+		}
+
+		dosages := make([]float64, len(sampleIDs))
+		for i, call := range fields[hapMapColumns:] {
+			call = strings.ToUpper(call)
+			if coding == "" && !isMissingCall(call) {
+				coding = NUCLEOTIDE
+				if len(call) == 1 {
+					coding = IUPAC
+				}
+			}
+
+			dosages[i], err = parseHapMapCall(call, coding, alleles)
+			if err != nil {
+				return nil, nil, fmt.Errorf("line %d: sample %s: %v", lineNumber, sampleIDs[i], err)
+			}
+			calls++
+			if math.IsNaN(dosages[i]) {
+				missingCalls++
+			}
+		}
+
+		markers = append(markers, marker)
+		variants = append(variants, dosages)
+
+		summary.Variants++
+		if len(alleles) > 2 {
+			summary.Multiallelic++
+		} else {
+			summary.SNPs++
+		}
+		index, ok := chromosomes[marker.Chromosome]
+		if !ok {
+			index = len(summary.Chromosomes)
+			chromosomes[marker.Chromosome] = index
+			summary.Chromosomes = append(summary.Chromosomes, ChromosomeSummary{Name: marker.Chromosome, FirstPosition: position})
+		}
+		chromosome := &summary.Chromosomes[index]
+		chromosome.Variants++
+		if position < chromosome.FirstPosition {
+			chromosome.FirstPosition = position
+		}
+		if position > chromosome.LastPosition {
+			chromosome.LastPosition = position
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, nil, fmt.Errorf("line %d: %v", lineNumber+1, err)
+	}
+	if sampleIDs == nil {
+		return nil, nil, errors.New("file is empty")
+	}
+	if len(markers) == 0 {
+		return nil, nil, errors.New("file contains no markers")
+	}
+
+	genotype := &Genotype{SampleIDs: sampleIDs, Markers: markers, Dosages: make([][]float64, len(sampleIDs))}
+	for i := range genotype.Dosages {
+		genotype.Dosages[i] = make([]float64, len(markers))
+		for j := range markers {
+			genotype.Dosages[i][j] = variants[j][i]
+		}
+	}
+
+	summary.Samples = len(sampleIDs)
+	summary.MissingRate = float64(missingCalls) / float64(calls)
+	summary.Coding = coding
+	return genotype, summary, nil
+}
+
+func isMissingCall(call string) bool {
+	return call == "N" || call == "NN" || call == "--" || call == "-" || call == "00" || call == "0"
+}
+
+// parseHapMapCall counts the bases of a call which are not the reference, the first of the alleles
+func parseHapMapCall(call string, coding GenotypeCoding, alleles []string) (float64, error) {
+	if isMissingCall(call) {
+		return math.NaN(), nil
+	}
+
+	bases := call
+	if coding == IUPAC {
+		if len(call) != 1 || iupacBases[call[0]] == "" {
+			return 0, fmt.Errorf("invalid %s call %q", IUPAC, call)
+		}
+		bases = iupacBases[call[0]]
+	} else if len(call) != 2 {
+		return 0, fmt.Errorf("invalid %s call %q, expected two bases", NUCLEOTIDE, call)
+	}
+
+	dosage := 0.0
+	for _, base := range bases {
+		allele := string(base)
+		if !containsString(alleles, allele) {
+			return 0, fmt.Errorf("call %q does not match the alleles %s", call, strings.Join(alleles, "/"))
+		}
+		if allele != alleles[0] {
+			dosage++
+		}
+	}
+	return dosage, nil
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,82 @@
+package genomics
+
+import (
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestReadHapMap(t *testing.T) {
+	for fixture, coding := range map[string]GenotypeCoding{"nucleotide.hmp.txt": NUCLEOTIDE, "iupac.hmp.txt": IUPAC} {
+		t.Run(fixture, func(t *testing.T) {
+			sampleIDs, summary, err := InspectGenotype(openFixture(t, fixture), "hmp.txt", "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(sampleIDs, []string{"S1", "S2", "S3"}) {
+				t.Errorf("sample IDs %v", sampleIDs)
+			}
+			want := &VariantSummary{
+				FileFormat:  "HapMap",
+				Samples:     3,
+				Variants:    3,
+				SNPs:        3,
+				MissingRate: 1.0 / 9,
+				Chromosomes: []ChromosomeSummary{
+					{Name: "1", Variants: 2, FirstPosition: 100, LastPosition: 300},
+					{Name: "2", Variants: 1, FirstPosition: 10, LastPosition: 10},
+				},
+				Coding: coding,
+			}
+			if !reflect.DeepEqual(summary, want) {
+				t.Errorf("summary %+v, want %+v", summary, want)
+			}
+
+			genotype, err := ReadGenotype(openFixture(t, fixture), "hmp.txt", coding)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if marker := genotype.Markers[2]; marker.Reference != "G" || marker.Alternate != "C" {
+				t.Errorf("marker m3 %+v, want G/C", marker)
+			}
+			// the dosage counts the bases other than the first allele
+			checkDosages(t, genotype, [][]float64{
+				{0, math.NaN(), 1},
+				{1, 0, 2},
+				{2, 1, 0},
+			})
+		})
+	}
+}
+
+func TestHapMapErrors(t *testing.T) {
+	const header = "rs#\talleles\tchrom\tpos\tstrand\tassembly#\tcenter\tprotLSID\tassayLSID\tpanelLSID\tQCcode\tS1\tS2\n"
+	const columns = "\t+\tNA\tNA\tNA\tNA\tNA\tNA"
+	tests := []struct {
+		name    string
+		content string
+		coding  GenotypeCoding
+		want    string
+	}{
+		{name: "empty", content: "", want: "file is empty"},
+		{name: "no markers", content: header, want: "file contains no markers"},
+		{name: "header", content: "marker\tS1\tS2\n", want: "line 1: expected a HapMap header starting with rs# alleles chrom pos"},
+		{name: "duplicate sample", content: strings.Replace(header, "S2", "S1", 1), want: "line 1: duplicate sample ID S1"},
+		{name: "columns", content: header + "m1\tA/G\t1\t100" + columns + "\tAA\n", want: "line 2: expected 13 columns, got 12"},
+		{name: "single allele", content: header + "m1\tA\t1\t100" + columns + "\tAA\tAA\n", want: `line 2: invalid alleles "A", expected reference/alternate`},
+		{name: "indel", content: header + "m1\tA/-\t1\t100" + columns + "\tAA\tAA\n", want: `line 2: invalid alleles "A/-", only single nucleotides are supported`},
+		{name: "position", content: header + "m1\tA/G\t1\tx" + columns + "\tAA\tAA\n", want: `line 2: invalid position "x"`},
+		{name: "allele mismatch", content: header + "m1\tA/G\t1\t100" + columns + "\tAA\tAT\n", want: `line 2: sample S2: call "AT" does not match the alleles A/G`},
+		{name: "one base", content: header + "m1\tA/G\t1\t100" + columns + "\tAA\tA\n", want: `line 2: sample S2: invalid NUCLEOTIDE call "A", expected two bases`},
+		{name: "IUPAC code", content: header + "m1\tA/G\t1\t100" + columns + "\tA\tX\n", want: `line 2: sample S2: invalid IUPAC call "X"`},
+		{name: "coding", content: header, coding: DOSAGE_012, want: "HapMap files use the NUCLEOTIDE or IUPAC coding, not DOSAGE_012"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, _, err := InspectGenotype(strings.NewReader(test.content), "hmp.txt", test.coding)
+			checkError(t, err, test.want)
+		})
+	}
+}
@@ -0,0 +1,266 @@
+package genomics
+
+import (
+	"errors"
+	"math"
+)
+
+func NewMatrix(rows, cols int) [][]float64 {
+	data := make([]float64, rows*cols)
+	matrix := make([][]float64, rows)
+	for i := range matrix {
+		matrix[i] = data[i*cols : (i+1)*cols]
+	}
+	return matrix
+}
+
+func Dot(a, b []float64) float64 {
+	sum := 0.0
+	for i := range a {
+		sum += a[i] * b[i]
+	}
+	return sum
+}
+
+// MulVec returns a·x
+func MulVec(a [][]float64, x []float64) []float64 {
+	result := make([]float64, len(a))
+	for i, row := range a {
+		result[i] = Dot(row, x)
+	}
+	return result
+}
+
+// MulTransVec returns a'·x
+func MulTransVec(a [][]float64, x []float64) []float64 {
+	if len(a) == 0 {
+		return nil
+	}
+	result := make([]float64, len(a[0]))
+	for i, row := range a {
+		if x[i] == 0 {
+			continue
+		}
+		for j, v := range row {
+			result[j] += v * x[i]
+		}
+	}
+	return result
+}
+
+// SymmetricEigen decomposes a symmetric matrix, eigenvalues are ascending and
+// eigenvectors are the columns of the returned matrix. The input is left untouched.
+func SymmetricEigen(a [][]float64) ([]float64, [][]float64, error) {
+	n := len(a)
+	if n == 0 {
+		return nil, nil, errors.New("empty matrix")
+	}
+
+	v := NewMatrix(n, n)
+	for i := range a {
+		if len(a[i]) != n {
+			return nil, nil, errors.New("matrix is not square")
+		}
+		copy(v[i], a[i])
+	}
+
+	d := make([]float64, n)
+	e := make([]float64, n)
+	tridiagonalize(v, d, e)
+	if err := diagonalize(v, d, e); err != nil {
+		return nil, nil, err
+	}
+
+	return d, v, nil
+}
+
+// tridiagonalize applies the Householder reduction, following the EISPACK tred2 routine
+func tridiagonalize(v [][]float64, d, e []float64) {
+	n := len(d)
+	copy(d, v[n-1])
+
+	for i := n - 1; i > 0; i-- {
+		scale, h := 0.0, 0.0
+		for k := 0; k < i; k++ {
+			scale += math.Abs(d[k])
+		}
+
+		if scale == 0 {
+			e[i] = d[i-1]
+			for j := 0; j < i; j++ {
+				d[j] = v[i-1][j]
+				v[i][j] = 0
+				v[j][i] = 0
+			}
+		} else {
+			for k := 0; k < i; k++ {
+				d[k] /= scale
+				h += d[k] * d[k]
+			}
+			f := d[i-1]
+			g := math.Sqrt(h)
+			if f > 0 {
+				g = -g
+			}
+			e[i] = scale * g
+			h -= f * g
+			d[i-1] = f - g
+			for j := 0; j < i; j++ {
+				e[j] = 0
+			}
+
+			for j := 0; j < i; j++ {
+				f = d[j]
+				v[j][i] = f
+				g = e[j] + v[j][j]*f
+				for k := j + 1; k <= i-1; k++ {
+					g += v[k][j] * d[k]
+					e[k] += v[k][j] * f
+				}
+				e[j] = g
+			}
+
+			f = 0
+			for j := 0; j < i; j++ {
+				e[j] /= h
+				f += e[j] * d[j]
+			}
+			hh := f / (h + h)
+			for j := 0; j < i; j++ {
+				e[j] -= hh * d[j]
+			}
+			for j := 0; j < i; j++ {
+				f = d[j]
+				g = e[j]
+				for k := j; k <= i-1; k++ {
+					v[k][j] -= f*e[k] + g*d[k]
+				}
+				d[j] = v[i-1][j]
+				v[i][j] = 0
+			}
+		}
+		d[i] = h
+	}
+
+	for i := 0; i < n-1; i++ {
+		v[n-1][i] = v[i][i]
+		v[i][i] = 1
+		h := d[i+1]
+		if h != 0 {
+			for k := 0; k <= i; k++ {
+				d[k] = v[k][i+1] / h
+			}
+			for j := 0; j <= i; j++ {
+				g := 0.0
+				for k := 0; k <= i; k++ {
+					g += v[k][i+1] * v[k][j]
+				}
+				for k := 0; k <= i; k++ {
+					v[k][j] -= g * d[k]
+				}
+			}
+		}
+		for k := 0; k <= i; k++ {
+			v[k][i+1] = 0
+		}
+	}
+
+	for j := 0; j < n; j++ {
+		d[j] = v[n-1][j]
+		v[n-1][j] = 0
+	}
+	v[n-1][n-1] = 1
+	e[0] = 0
+}
+
+// diagonalize runs the implicit QL iterations, following the EISPACK tql2 routine
+func diagonalize(v [][]float64, d, e []float64) error {
+	n := len(d)
+	for i := 1; i < n; i++ {
+		e[i-1] = e[i]
+	}
+	e[n-1] = 0
+
+	f, tst1 := 0.0, 0.0
+	eps := math.Pow(2, -52)
+	for l := 0; l < n; l++ {
+		tst1 = math.Max(tst1, math.Abs(d[l])+math.Abs(e[l]))
+		m := l
+		for m < n-1 && math.Abs(e[m]) > eps*tst1 {
+			m++
+		}
+
+		if m > l {
+			for iteration := 0; ; iteration++ {
+				if iteration > 60 {
+					return errors.New("eigen decomposition did not converge")
+				}
+
+				g := d[l]
+				p := (d[l+1] - g) / (2 * e[l])
+				r := math.Hypot(p, 1)
+				if p < 0 {
+					r = -r
+				}
+				d[l] = e[l] / (p + r)
+				d[l+1] = e[l] * (p + r)
+				dl1 := d[l+1]
+				h := g - d[l]
+				for i := l + 2; i < n; i++ {
+					d[i] -= h
+				}
+				f += h
+
+				p = d[m]
+				c, c2, c3 := 1.0, 1.0, 1.0
+				el1 := e[l+1]
+				s, s2 := 0.0, 0.0
+				for i := m - 1; i >= l; i-- {
+					c3 = c2
+					c2 = c
+					s2 = s
+					g = c * e[i]
+					h = c * p
+					r = math.Hypot(p, e[i])
+					e[i+1] = s * r
+					s = e[i] / r
+					c = p / r
+					p = c*d[i] - s*g
+					d[i+1] = h + s*(c*g+s*d[i])
+					for k := 0; k < n; k++ {
+						h = v[k][i+1]
+						v[k][i+1] = s*v[k][i] + c*h
+						v[k][i] = c*v[k][i] - s*h
+					}
+				}
+				p = -s * s2 * c3 * el1 * e[l] / dl1
+				e[l] = s * p
+				d[l] = c * p
+
+				if math.Abs(e[l]) <= eps*tst1 {
+					break
+				}
+			}
+		}
+		d[l] += f
+		e[l] = 0
+	}
+
+	for i := 0; i < n-1; i++ {
+		k, p := i, d[i]
+		for j := i + 1; j < n; j++ {
+			if d[j] < p {
+				k, p = j, d[j]
+			}
+		}
+		if k != i {
+			d[k] = d[i]
+			d[i] = p
+			for j := 0; j < n; j++ {
+				v[j][i], v[j][k] = v[j][k], v[j][i]
+			}
+		}
+	}
+
+	return nil
+}
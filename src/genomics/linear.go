@@ -0,0 +1,76 @@
+package genomics
+
+import (
+	"errors"
+	"math"
+)
+
+// LinearModel scores intercept + Σ (dosage - 2p)·effect, markers are matched by ID
+// so that genotypes with a different marker order or panel can be scored
+type LinearModel struct {
+	Intercept   float64   `json:"intercept" bson:"intercept"`
+	MarkerIDs   []string  `json:"markerIDs" bson:"markerIDs"`
+	Frequencies []float64 `json:"frequencies" bson:"frequencies"`
+	Effects     []float64 `json:"effects" bson:"effects"`
+}
+
+func NewLinearModel(markers []Marker, frequencies []float64) LinearModel {
+	markerIDs := make([]string, len(markers))
+	for i, v := range markers {
+		markerIDs[i] = v.ID
+	}
+
+	return LinearModel{
+		MarkerIDs:   markerIDs,
+		Frequencies: frequencies,
+		Effects:     make([]float64, len(markers)),
+	}
+}
+
+func (m *LinearModel) Predict(genotype *Genotype) ([]float64, error) {
+	columns := make(map[string]int, genotype.NumMarkers())
+	for j, v := range genotype.Markers {
+		columns[v.ID] = j
+	}
+
+	type term struct {
+		column int
+		mean   float64
+		effect float64
+	}
+	terms := make([]term, 0, len(m.MarkerIDs))
+	for j, markerID := range m.MarkerIDs {
+		column, ok := columns[markerID]
+		if !ok || m.Effects[j] == 0 {
+			continue
+		}
+		terms = append(terms, term{column: column, mean: 2 * m.Frequencies[j], effect: m.Effects[j]})
+	}
+
+	if len(terms) == 0 && len(m.MarkerIDs) > 0 && matchedMarkers(columns, m.MarkerIDs) == 0 {
+		return nil, errors.New("genotype shares no markers with the model")
+	}
+
+	predicted := make([]float64, genotype.NumSamples())
+	for i, row := range genotype.Dosages {
+		value := m.Intercept
+		for _, t := range terms {
+			if dosage := row[t.column]; !math.IsNaN(dosage) {
+				value += (dosage - t.mean) * t.effect
+			}
+		}
+		predicted[i] = value
+	}
+
+	return predicted, nil
+}
+
+func matchedMarkers(columns map[string]int, markerIDs []string) int {
+	count := 0
+	for _, v := range markerIDs {
+		if _, ok := columns[v]; ok {
+			count++
+		}
+	}
+	return count
+}
@@ -0,0 +1,159 @@
+package genomics
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"sync"
+)
+
+// Method names as stored in the user settingSelection
+const (
+	RBF        = "RBF_BLUF"
+	ELASTICNET = "ELASTIC_NET"
+	LASSO      = "LASSO"
+	GBLUP      = "G_BLUP"
+)
+
+type Parameters map[string]interface{}
+
+func (p Parameters) String(key, fallback string) string {
+	if v, ok := p[key]; ok && v != nil {
+		if s := fmt.Sprintf("%v", v); s != "" {
+			return s
+		}
+	}
+	return fallback
+}
+
+func (p Parameters) Float(key string, fallback float64) float64 {
+	switch v := p[key].(type) {
+	case float64:
+		return v
+	case float32:
+		return float64(v)
+	case int:
+		return float64(v)
+	case int32:
+		return float64(v)
+	case int64:
+		return float64(v)
+	case string:
+		if f, err := strconv.ParseFloat(v, 64); err == nil {
+			return f
+		}
+	}
+	return fallback
+}
+
+func (p Parameters) Int(key string, fallback int) int {
+	return int(p.Float(key, float64(fallback)))
+}
+
+// Model is a fitted prediction method
+type Model interface {
+	Predict(genotype *Genotype) ([]float64, error)
+	Summary() map[string]float64
+}
+
+// Method fits a model on genotype rows aligned with the phenotype values.
+// Decode restores a model of the method saved with encoding/json.
+type Method interface {
+	Fit(ctx context.Context, genotype *Genotype, phenotypes []float64, parameters Parameters) (Model, error)
+	Decode(data []byte) (Model, error)
+}
+
+var (
+	methodsMutex sync.RWMutex
+	methods      = map[string]Method{}
+)
+
+func RegisterMethod(name string, method Method) {
+	methodsMutex.Lock()
+	defer methodsMutex.Unlock()
+	methods[name] = method
+}
+
+func GetMethod(name string) (Method, error) {
+	methodsMutex.RLock()
+	defer methodsMutex.RUnlock()
+	method, ok := methods[name]
+	if !ok {
+		return nil, fmt.Errorf("prediction method %s is not available", name)
+	}
+	return method, nil
+}
+
+// DecodeModel restores a saved model with the decoder of its method
+func DecodeModel(name string, data []byte) (Model, error) {
+	method, err := GetMethod(name)
+	if err != nil {
+		return nil, err
+	}
+	return method.Decode(data)
+}
+
+func Methods() []string {
+	methodsMutex.RLock()
+	defer methodsMutex.RUnlock()
+	names := make([]string, 0, len(methods))
+	for name := range methods {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Dataset aligns a trait with every genotyped sample, Observed is NaN for unphenotyped samples
+type Dataset struct {
+	Genotype *Genotype
+	Observed []float64
+}
+
+// NewDataset pairs the genotype and phenotype samples as MatchSamples does with the given normalization,
+// nil to compare trimmed IDs. Samples of an ambiguous key are left unphenotyped.
+func NewDataset(genotype *Genotype, phenotype *Phenotype, trait string, normalization *SampleNormalization) (*Dataset, error) {
+	values, err := phenotype.Trait(trait)
+	if err != nil {
+		return nil, err
+	}
+
+	dataset := &Dataset{Genotype: genotype, Observed: make([]float64, genotype.NumSamples())}
+	for i := range dataset.Observed {
+		dataset.Observed[i] = math.NaN()
+	}
+	rows := genotype.SampleIndex()
+	for _, matched := range MatchSamples(genotype.SampleIDs, phenotype.SampleIDs, normalization).Matched {
+		if v, ok := values[matched.PhenotypeID]; ok {
+			dataset.Observed[rows[matched.GenotypeID]] = v
+		}
+	}
+
+	if len(dataset.TrainingRows()) < 2 {
+		return nil, errors.New("genotype and phenotype share fewer than two samples")
+	}
+
+	return dataset, nil
+}
+
+func (d *Dataset) TrainingRows() []int {
+	var rows []int
+	for i, v := range d.Observed {
+		if !math.IsNaN(v) {
+			rows = append(rows, i)
+		}
+	}
+	return rows
+}
+
+// Fit trains a method on the given dataset rows
+func Fit(ctx context.Context, method Method, dataset *Dataset, rows []int, parameters Parameters) (Model, error) {
+	phenotypes := make([]float64, len(rows))
+	for i, row := range rows {
+		phenotypes[i] = dataset.Observed[row]
+	}
+	return method.Fit(ctx, dataset.Genotype.Subset(rows), phenotypes, parameters)
+}
@@ -0,0 +1,37 @@
+package genomics
+
+import (
+	"math"
+	"testing"
+)
+
+func TestNewDatasetNormalization(t *testing.T) {
+	genotype := &Genotype{
+		SampleIDs: []string{"plot-01", "plot-02", "PLOT-03", "plot-04", "plot-05"},
+		Markers:   []Marker{{ID: "m1"}},
+		Dosages:   [][]float64{{0}, {1}, {2}, {1}, {0}},
+	}
+	phenotype := &Phenotype{
+		// P04 and p_04 share a key, so plot-04 is left out. p06 has no genotype.
+		SampleIDs: []string{"P01", "p02", "p_03", "P04", "p_04", "p06", "plot-05"},
+		Traits:    []string{"yield"},
+		Values:    [][]float64{{1}, {2}, {3}, {4}, {5}, {6}, {math.NaN()}},
+	}
+
+	dataset, err := NewDataset(genotype, phenotype, "yield", nil)
+	if err == nil {
+		t.Fatalf("matched without normalization: %v", dataset.Observed)
+	}
+
+	normalization := &SampleNormalization{IgnoreCase: true, IgnoreSeparators: true, StripPrefixes: []string{"plot", "p"}}
+	dataset, err = NewDataset(genotype, phenotype, "yield", normalization)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []float64{1, 2, 3, math.NaN(), math.NaN()}
+	for i, v := range want {
+		if got := dataset.Observed[i]; got != v && !(math.IsNaN(got) && math.IsNaN(v)) {
+			t.Errorf("observed value of %s = %v, want %v", genotype.SampleIDs[i], got, v)
+		}
+	}
+}
@@ -0,0 +1,237 @@
+package penalized
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"time"
+
+	"github.com/khoa5773/go-server/src/genomics"
+)
+
+// Values of the "default" setting key, any other value fits the given "lambda". TIME searches λ
+// like AUTO but stops after the "time" setting, a number of minutes.
+const (
+	AUTO = "auto"
+	TIME = "time"
+)
+
+const (
+	defaultLambdas = 100
+	defaultFolds   = 5
+	defaultAlpha   = 0.5
+	// defaultTime is the λ search budget in minutes used when "time" is missing
+	defaultTime = 1.5
+)
+
+// Method fits LASSO when Lasso is set, otherwise an elastic net mixing with the "alpha" setting
+type Method struct {
+	Lasso bool
+}
+
+func init() {
+	genomics.RegisterMethod(genomics.LASSO, Method{Lasso: true})
+	genomics.RegisterMethod(genomics.ELASTICNET, Method{})
+}
+
+type Model struct {
+	genomics.LinearModel `bson:",inline"`
+	Alpha                float64 `json:"alpha" bson:"alpha"`
+	Lambda               float64 `json:"lambda" bson:"lambda"`
+	CVError              float64 `json:"cvError" bson:"cvError"`
+	NonZero              int     `json:"nonZero" bson:"nonZero"`
+	Samples              int     `json:"samples" bson:"samples"`
+	LambdasEvaluated     int     `json:"lambdasEvaluated" bson:"lambdasEvaluated"`
+	TimeBudgetExceeded   bool    `json:"timeBudgetExceeded" bson:"timeBudgetExceeded"`
+}
+
+func (Method) Decode(data []byte) (genomics.Model, error) {
+	model := &Model{}
+	err := json.Unmarshal(data, model)
+	if err != nil {
+		return nil, err
+	}
+	return model, nil
+}
+
+func (m *Model) Summary() map[string]float64 {
+	summary := map[string]float64{
+		"intercept":          m.Intercept,
+		"alpha":              m.Alpha,
+		"lambda":             m.Lambda,
+		"nonZero":            float64(m.NonZero),
+		"samples":            float64(m.Samples),
+		"markers":            float64(len(m.MarkerIDs)),
+		"lambdasEvaluated":   float64(m.LambdasEvaluated),
+		"timeBudgetExceeded": 0,
+	}
+	if m.LambdasEvaluated > 0 {
+		summary["cvError"] = m.CVError
+	}
+	if m.TimeBudgetExceeded {
+		summary["timeBudgetExceeded"] = 1
+	}
+	return summary
+}
+
+func (m Method) Fit(ctx context.Context, genotype *genomics.Genotype, phenotypes []float64, parameters genomics.Parameters) (genomics.Model, error) {
+	alpha := 1.0
+	if !m.Lasso {
+		alpha = parameters.Float("alpha", defaultAlpha)
+	}
+	if alpha <= 0 || alpha > 1 {
+		return nil, errors.New("alpha must be in (0, 1]")
+	}
+
+	n := len(phenotypes)
+	if n < 3 {
+		return nil, errors.New("at least three phenotyped individuals are required")
+	}
+
+	x := standardize(genotype.Dosages, genotype.NumMarkers())
+	rows := make([]int, n)
+	for i := range rows {
+		rows[i] = i
+	}
+	full := newSolver(x, phenotypes, rows, alpha)
+	path := lambdaPath(full.maxLambda(), n, genotype.NumMarkers(), parameters.Int("nlambda", defaultLambdas))
+
+	model := &Model{
+		LinearModel: genomics.NewLinearModel(genotype.Markers, make([]float64, genotype.NumMarkers())),
+		Alpha:       alpha,
+		Samples:     n,
+	}
+
+	switch mode := parameters.String("default", AUTO); mode {
+	case AUTO, TIME:
+		minutes := parameters.Float("time", defaultTime)
+		selectionCtx := ctx
+		if mode == TIME {
+			if minutes <= 0 {
+				return nil, errors.New("time must be a positive number of minutes")
+			}
+			var cancel context.CancelFunc
+			selectionCtx, cancel = context.WithTimeout(ctx, time.Duration(minutes*float64(time.Minute)))
+			defer cancel()
+		}
+
+		folds := parameters.Int("nfolds", defaultFolds)
+		if folds < 2 || folds > n {
+			return nil, errors.New("nfolds must be between 2 and the number of phenotyped individuals")
+		}
+
+		err := model.selectLambda(selectionCtx, x, phenotypes, path, folds, int64(parameters.Int("seed", 1)))
+		if err != nil {
+			return nil, err
+		}
+		if err = ctx.Err(); err != nil {
+			return nil, err
+		}
+		if model.LambdasEvaluated == 0 {
+			return nil, fmt.Errorf("the time budget of %v minutes ran out before any λ was evaluated", minutes)
+		}
+	default:
+		model.Lambda = parameters.Float("lambda", -1)
+		if model.Lambda < 0 {
+			return nil, errors.New("lambda is required unless default is auto or time")
+		}
+	}
+
+	for _, lambda := range path {
+		if lambda <= model.Lambda {
+			break
+		}
+		if err := full.fit(ctx, lambda); err != nil {
+			return nil, err
+		}
+	}
+	if err := full.fit(ctx, model.Lambda); err != nil {
+		return nil, err
+	}
+
+	model.Intercept = full.intercept
+	model.NonZero = full.nonZero()
+	for j, v := range full.beta {
+		model.Frequencies[j] = x.means[j] / 2
+		if v != 0 {
+			model.Effects[j] = v / x.scales[j]
+		}
+	}
+
+	return model, nil
+}
+
+// selectLambda walks the λ path with k-fold cross-validation and keeps the λ with the lowest
+// mean squared error. When ctx expires the best λ evaluated so far is kept, LambdasEvaluated is 0
+// if there is none.
+func (m *Model) selectLambda(ctx context.Context, x *standardized, y []float64, path []float64, folds int, seed int64) error {
+	n := len(y)
+	assignment := make([]int, n)
+	for position, row := range rand.New(rand.NewSource(seed)).Perm(n) {
+		assignment[row] = position % folds
+	}
+
+	solvers := make([]*solver, folds)
+	holdouts := make([][]int, folds)
+	for fold := range solvers {
+		var training []int
+		for i, v := range assignment {
+			if v == fold {
+				holdouts[fold] = append(holdouts[fold], i)
+			} else {
+				training = append(training, i)
+			}
+		}
+		solvers[fold] = newSolver(x, y, training, m.Alpha)
+	}
+
+	m.Lambda = path[0]
+	m.CVError = math.Inf(1)
+search:
+	for _, lambda := range path {
+		squaredError := 0.0
+		for fold, s := range solvers {
+			if err := s.fit(ctx, lambda); err != nil {
+				if err == context.DeadlineExceeded {
+					m.TimeBudgetExceeded = true
+					break search
+				}
+				return err
+			}
+			for _, i := range holdouts[fold] {
+				residual := y[i] - s.predict(i)
+				squaredError += residual * residual
+			}
+		}
+
+		m.LambdasEvaluated++
+		if mse := squaredError / float64(n); mse < m.CVError {
+			m.Lambda = lambda
+			m.CVError = mse
+		}
+	}
+
+	return nil
+}
+
+// lambdaPath returns a log-spaced decreasing sequence of λ starting where every effect is zero
+func lambdaPath(maxLambda float64, samples, markers, count int) []float64 {
+	if maxLambda <= 0 || count < 2 {
+		return []float64{math.Max(maxLambda, 0)}
+	}
+
+	ratio := 0.01
+	if samples > markers {
+		ratio = 0.0001
+	}
+
+	path := make([]float64, count)
+	step := math.Log(ratio) / float64(count-1)
+	for i := range path {
+		path[i] = maxLambda * math.Exp(step*float64(i))
+	}
+	return path
+}
@@ -0,0 +1,159 @@
+package penalized
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/khoa5773/go-server/src/genomics"
+)
+
+func testData(n, markers int, seed int64) ([][]float64, []float64) {
+	random := rand.New(rand.NewSource(seed))
+	dosages := make([][]float64, n)
+	y := make([]float64, n)
+	for i := range dosages {
+		dosages[i] = make([]float64, markers)
+		for j := range dosages[i] {
+			dosages[i][j] = float64(random.Intn(3))
+		}
+		y[i] = 2*dosages[i][0] - 1.5*dosages[i][3] + dosages[i][7] + random.NormFloat64()
+	}
+	return dosages, y
+}
+
+// checkKKT verifies the optimality conditions of the elastic net objective: for every marker
+// g = x'r/n - λ(1-α)β equals λα·sign(β) when β ≠ 0 and is at most λα in absolute value otherwise
+func checkKKT(t *testing.T, s *solver, lambda float64) {
+	n := float64(len(s.rows))
+	residuals := make([]float64, len(s.y))
+	mean := 0.0
+	for _, i := range s.rows {
+		residuals[i] = s.y[i] - s.predict(i)
+		mean += residuals[i] / n
+	}
+	if math.Abs(mean) > 1e-8 {
+		t.Errorf("λ %v: mean residual = %v, want 0", lambda, mean)
+	}
+
+	// coordinate descent stops once no squared step exceeds the threshold, the gradient is as far off
+	slack := 2 * math.Sqrt(s.threshold)
+	l1 := lambda * s.alpha
+	for j, column := range s.x.columns {
+		if column == nil {
+			continue
+		}
+		gradient := 0.0
+		for _, i := range s.rows {
+			gradient += column[i] * residuals[i] / n
+		}
+		gradient -= lambda * (1 - s.alpha) * s.beta[j]
+
+		switch {
+		case s.beta[j] > 0 && math.Abs(gradient-l1) > slack:
+			t.Errorf("λ %v: marker %d has β %v > 0 but gradient %v ≠ %v", lambda, j, s.beta[j], gradient, l1)
+		case s.beta[j] < 0 && math.Abs(gradient+l1) > slack:
+			t.Errorf("λ %v: marker %d has β %v < 0 but gradient %v ≠ %v", lambda, j, s.beta[j], gradient, -l1)
+		case s.beta[j] == 0 && math.Abs(gradient) > l1+slack:
+			t.Errorf("λ %v: marker %d has β 0 but |gradient| %v > %v", lambda, j, math.Abs(gradient), l1)
+		}
+	}
+}
+
+func TestSolverKKT(t *testing.T) {
+	dosages, y := testData(60, 20, 7)
+	x := standardize(dosages, 20)
+	rows := make([]int, len(y))
+	for i := range rows {
+		rows[i] = i
+	}
+
+	for _, alpha := range []float64{1, 0.5, 0.1} {
+		s := newSolver(x, y, rows, alpha)
+		maxLambda := s.maxLambda()
+
+		if err := s.fit(context.Background(), maxLambda); err != nil {
+			t.Fatal(err)
+		}
+		if s.nonZero() != 0 {
+			t.Errorf("α %v: %d effects are not zero at the largest λ", alpha, s.nonZero())
+		}
+
+		for _, lambda := range lambdaPath(maxLambda, len(y), 20, 10)[1:] {
+			if err := s.fit(context.Background(), lambda); err != nil {
+				t.Fatal(err)
+			}
+			checkKKT(t, s, lambda)
+		}
+		if s.nonZero() == 0 {
+			t.Errorf("α %v: every effect is zero at the smallest λ", alpha)
+		}
+	}
+}
+
+func TestSoftThreshold(t *testing.T) {
+	tests := []struct {
+		z, gamma, want float64
+	}{
+		{3, 1, 2},
+		{-3, 1, -2},
+		{0.5, 1, 0},
+		{-1, 1, 0},
+	}
+	for _, test := range tests {
+		if got := softThreshold(test.z, test.gamma); got != test.want {
+			t.Errorf("softThreshold(%v, %v) = %v, want %v", test.z, test.gamma, got, test.want)
+		}
+	}
+}
+
+func TestLambdaPath(t *testing.T) {
+	path := lambdaPath(2, 10, 100, 5)
+	if len(path) != 5 || path[0] != 2 || math.Abs(path[4]-0.02) > 1e-12 {
+		t.Errorf("path = %v, want 5 values from 2 down to 0.02", path)
+	}
+	for i := 1; i < len(path); i++ {
+		if path[i] >= path[i-1] {
+			t.Errorf("path is not decreasing: %v", path)
+		}
+	}
+	if path := lambdaPath(0, 10, 100, 5); len(path) != 1 || path[0] != 0 {
+		t.Errorf("path = %v, want [0] when every effect is already zero", path)
+	}
+}
+
+func TestFitTimeBudget(t *testing.T) {
+	dosages, y := testData(40, 10, 3)
+	genotype := &genomics.Genotype{SampleIDs: make([]string, len(y)), Markers: make([]genomics.Marker, 10), Dosages: dosages}
+	for j := range genotype.Markers {
+		genotype.Markers[j].ID = fmt.Sprintf("m%d", j)
+	}
+
+	tests := []struct {
+		name       string
+		parameters genomics.Parameters
+		fails      bool
+	}{
+		{"auto", genomics.Parameters{"default": AUTO}, false},
+		{"time", genomics.Parameters{"default": TIME, "time": 1}, false},
+		{"expired budget", genomics.Parameters{"default": TIME, "time": 1e-12}, true},
+		{"negative time", genomics.Parameters{"default": TIME, "time": -1}, true},
+		{"fixed lambda", genomics.Parameters{"default": "", "lambda": 0.1}, false},
+	}
+	for _, test := range tests {
+		model, err := Method{Lasso: true}.Fit(context.Background(), genotype, y, test.parameters)
+		if test.fails {
+			if err == nil {
+				t.Errorf("%s: expected an error, got %+v", test.name, model.Summary())
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", test.name, err)
+		} else if model.(*Model).NonZero == 0 {
+			t.Errorf("%s: every effect is zero", test.name)
+		}
+	}
+}
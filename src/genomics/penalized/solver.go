@@ -0,0 +1,241 @@
+package penalized
+
+import (
+	"context"
+	"math"
+)
+
+const (
+	tolerance = 1e-7
+	maxSweeps = 10000
+)
+
+// standardized holds marker columns scaled to zero mean and unit variance over the training samples
+type standardized struct {
+	columns [][]float64
+	means   []float64
+	scales  []float64
+}
+
+func standardize(dosages [][]float64, markers int) *standardized {
+	n := float64(len(dosages))
+	s := &standardized{
+		columns: make([][]float64, markers),
+		means:   make([]float64, markers),
+		scales:  make([]float64, markers),
+	}
+
+	for j := 0; j < markers; j++ {
+		sum, count := 0.0, 0.0
+		for _, row := range dosages {
+			if !math.IsNaN(row[j]) {
+				sum += row[j]
+				count++
+			}
+		}
+		if count == 0 {
+			continue
+		}
+		mean := sum / count
+
+		column := make([]float64, len(dosages))
+		squares := 0.0
+		for i, row := range dosages {
+			if !math.IsNaN(row[j]) {
+				column[i] = row[j] - mean
+				squares += column[i] * column[i]
+			}
+		}
+		s.means[j] = mean
+		if squares == 0 {
+			continue
+		}
+
+		scale := math.Sqrt(squares / n)
+		for i := range column {
+			column[i] /= scale
+		}
+		s.columns[j] = column
+		s.scales[j] = scale
+	}
+
+	return s
+}
+
+// solver runs coordinate descent for the elastic net objective
+// 1/(2n) Σ (y - β0 - xβ)² + λ((1-α)/2 ‖β‖² + α‖β‖₁) on a subset of rows
+type solver struct {
+	x         *standardized
+	y         []float64
+	rows      []int
+	alpha     float64
+	intercept float64
+	beta      []float64
+	residual  []float64
+	variances []float64
+	threshold float64
+}
+
+func newSolver(x *standardized, y []float64, rows []int, alpha float64) *solver {
+	s := &solver{
+		x:         x,
+		y:         y,
+		rows:      rows,
+		alpha:     alpha,
+		beta:      make([]float64, len(x.columns)),
+		residual:  make([]float64, len(y)),
+		variances: make([]float64, len(x.columns)),
+	}
+
+	n := float64(len(rows))
+	for _, i := range rows {
+		s.intercept += y[i] / n
+	}
+	for _, i := range rows {
+		s.residual[i] = y[i] - s.intercept
+		s.threshold += s.residual[i] * s.residual[i] / n
+	}
+	s.threshold *= tolerance
+
+	for j, column := range x.columns {
+		if column == nil {
+			continue
+		}
+		squares := 0.0
+		for _, i := range rows {
+			squares += column[i] * column[i]
+		}
+		s.variances[j] = squares / n
+	}
+
+	return s
+}
+
+func (s *solver) maxLambda() float64 {
+	n := float64(len(s.rows))
+	maximum := 0.0
+	for _, column := range s.x.columns {
+		if column == nil {
+			continue
+		}
+		product := 0.0
+		for _, i := range s.rows {
+			product += column[i] * s.residual[i]
+		}
+		maximum = math.Max(maximum, math.Abs(product)/n)
+	}
+	return maximum / math.Max(s.alpha, 1e-3)
+}
+
+// sweep updates the given coordinates once and returns the largest weighted squared change
+func (s *solver) sweep(lambda float64, coordinates []int) float64 {
+	n := float64(len(s.rows))
+	l1 := lambda * s.alpha
+	l2 := lambda * (1 - s.alpha)
+	maxChange := 0.0
+
+	for _, j := range coordinates {
+		column := s.x.columns[j]
+		if column == nil || s.variances[j] == 0 {
+			continue
+		}
+
+		product := 0.0
+		for _, i := range s.rows {
+			product += column[i] * s.residual[i]
+		}
+		old := s.beta[j]
+		z := product/n + s.variances[j]*old
+		updated := softThreshold(z, l1) / (s.variances[j] + l2)
+		if updated == old {
+			continue
+		}
+
+		delta := updated - old
+		for _, i := range s.rows {
+			s.residual[i] -= column[i] * delta
+		}
+		s.beta[j] = updated
+		maxChange = math.Max(maxChange, s.variances[j]*delta*delta)
+	}
+
+	shift := 0.0
+	for _, i := range s.rows {
+		shift += s.residual[i] / n
+	}
+	s.intercept += shift
+	for _, i := range s.rows {
+		s.residual[i] -= shift
+	}
+
+	return maxChange
+}
+
+// fit runs coordinate descent to convergence for one λ, warm started from the current coefficients
+func (s *solver) fit(ctx context.Context, lambda float64) error {
+	all := make([]int, len(s.beta))
+	for j := range all {
+		all[j] = j
+	}
+
+	for sweeps := 0; sweeps < maxSweeps; {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		sweeps++
+		if s.sweep(lambda, all) <= s.threshold {
+			return nil
+		}
+
+		var active []int
+		for j, v := range s.beta {
+			if v != 0 {
+				active = append(active, j)
+			}
+		}
+		for sweeps < maxSweeps {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+
+			sweeps++
+			if s.sweep(lambda, active) <= s.threshold {
+				break
+			}
+		}
+	}
+
+	return nil
+}
+
+func (s *solver) predict(row int) float64 {
+	value := s.intercept
+	for j, v := range s.beta {
+		if v != 0 {
+			value += s.x.columns[j][row] * v
+		}
+	}
+	return value
+}
+
+func (s *solver) nonZero() int {
+	count := 0
+	for _, v := range s.beta {
+		if v != 0 {
+			count++
+		}
+	}
+	return count
+}
+
+func softThreshold(z, gamma float64) float64 {
+	switch {
+	case z > gamma:
+		return z - gamma
+	case z < -gamma:
+		return z + gamma
+	default:
+		return 0
+	}
+}
@@ -0,0 +1,298 @@
+package genomics
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+type TraitType string
+
+const (
+	NUMERIC     TraitType = "NUMERIC"
+	CATEGORICAL TraitType = "CATEGORICAL"
+)
+
+// maxTraitLevels bounds the levels kept in the schema of a categorical trait
+const maxTraitLevels = 100
+
+// sampleColumnNames are the headers of a sample ID column, compared lowercased without separators
+var sampleColumnNames = map[string]bool{
+	"sample": true, "sampleid": true, "samplename": true, "id": true, "iid": true, "individual": true,
+	"taxa": true, "taxon": true, "genotype": true, "gid": true, "accession": true, "line": true, "entry": true,
+}
+
+// TraitSchema describes one trait column of a phenotype table
+type TraitSchema struct {
+	Name     string    `json:"name" bson:"name"`
+	Column   int       `json:"column" bson:"column"`
+	Type     TraitType `json:"type" bson:"type"`
+	Observed int       `json:"observed" bson:"observed"`
+	Missing  int       `json:"missing" bson:"missing"`
+	Levels   []string  `json:"levels,omitempty" bson:"levels,omitempty"`
+}
+
+// PhenotypeSchema describes the columns of a phenotype table, columns are numbered from 0
+type PhenotypeSchema struct {
+	SampleColumn  string        `json:"sampleColumn" bson:"sampleColumn"`
+	Samples       int           `json:"samples" bson:"samples"`
+	MissingTokens []string      `json:"missingTokens" bson:"missingTokens"`
+	Traits        []TraitSchema `json:"traits" bson:"traits"`
+}
+
+// Trait returns the schema of the trait with the given name, the first numeric trait when it is empty
+func (s *PhenotypeSchema) Trait(name string) (*TraitSchema, error) {
+	for i, trait := range s.Traits {
+		if trait.Name == name || name == "" && trait.Type == NUMERIC {
+			return &s.Traits[i], nil
+		}
+	}
+	if name == "" {
+		return nil, errors.New("phenotype contains no numeric traits")
+	}
+	return nil, fmt.Errorf("trait %s not found", name)
+}
+
+// Phenotype holds one row of numeric trait values per sample, NaN marks a missing record
+type Phenotype struct {
+	SampleIDs []string
+	Traits    []string
+	Values    [][]float64
+	Schema    *PhenotypeSchema
+}
+
+type tableRow struct {
+	number int
+	fields []string
+}
+
+// IsPhenotypeFormat tells whether files with the given extension can be read by ReadPhenotype
+func IsPhenotypeFormat(mimeType string) bool {
+	switch strings.ToLower(mimeType) {
+	case "csv", "tsv", "txt", "xlsx":
+		return true
+	}
+	return false
+}
+
+func (p *Phenotype) TraitIndex(trait string) (int, error) {
+	if trait == "" {
+		if len(p.Traits) == 0 {
+			return -1, errors.New("phenotype contains no traits")
+		}
+		return 0, nil
+	}
+
+	for i, v := range p.Traits {
+		if v == trait {
+			return i, nil
+		}
+	}
+	if p.Schema != nil {
+		if schema, err := p.Schema.Trait(trait); err == nil && schema.Type == CATEGORICAL {
+			return -1, fmt.Errorf("trait %s is categorical, only numeric traits can be predicted", trait)
+		}
+	}
+	return -1, fmt.Errorf("trait %s not found", trait)
+}
+
+// Trait returns the observed values of a trait keyed by sample ID, missing records are skipped
+func (p *Phenotype) Trait(trait string) (map[string]float64, error) {
+	index, err := p.TraitIndex(trait)
+	if err != nil {
+		return nil, err
+	}
+
+	values := make(map[string]float64, len(p.SampleIDs))
+	for i, sampleID := range p.SampleIDs {
+		if v := p.Values[i][index]; !math.IsNaN(v) {
+			values[sampleID] = v
+		}
+	}
+	return values, nil
+}
+
+// InspectPhenotype validates a phenotype table and returns its sample IDs and the schema of its traits
+func InspectPhenotype(r io.Reader, mimeType string) ([]string, *PhenotypeSchema, error) {
+	phenotype, err := ReadPhenotype(r, mimeType)
+	if err != nil {
+		return nil, nil, err
+	}
+	return phenotype.SampleIDs, phenotype.Schema, nil
+}
+
+// ReadPhenotype reads the numeric traits of a CSV, TSV or XLSX table whose header row names the
+// columns. The sample ID column is found by its header, or else is the first column of distinct,
+// non-numeric values, or else the first column. Trait columns of only numbers and missing values are
+// numeric, the others categorical.
+func ReadPhenotype(r io.Reader, mimeType string) (*Phenotype, error) {
+	unit := "line"
+	var rows []tableRow
+	var err error
+	if strings.ToLower(mimeType) == "xlsx" {
+		unit = "row"
+		rows, err = readXLSX(r)
+	} else {
+		rows, err = readTextTable(r, mimeType)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return nil, errors.New("phenotype is empty")
+	}
+
+	header := rows[0].fields
+	for len(header) > 0 && header[len(header)-1] == "" {
+		header = header[:len(header)-1]
+	}
+	if len(header) < 2 {
+		return nil, fmt.Errorf("%s %d: header must contain a sample column and at least one trait", unit, rows[0].number)
+	}
+	seen := map[string]bool{}
+	for j, name := range header {
+		if name == "" {
+			return nil, fmt.Errorf("%s %d: column %d has no name", unit, rows[0].number, j+1)
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("%s %d: duplicate column %s", unit, rows[0].number, name)
+		}
+		seen[name] = true
+	}
+
+	rows = rows[1:]
+	for _, row := range rows {
+		for j := len(header); j < len(row.fields); j++ {
+			if row.fields[j] != "" {
+				return nil, fmt.Errorf("%s %d: expected %d columns, got %d", unit, row.number, len(header), len(row.fields))
+			}
+		}
+		if unit == "line" && len(row.fields) < len(header) {
+			return nil, fmt.Errorf("%s %d: expected %d columns, got %d", unit, row.number, len(header), len(row.fields))
+		}
+	}
+	cell := func(row tableRow, j int) string {
+		if j < len(row.fields) {
+			return row.fields[j]
+		}
+		return ""
+	}
+
+	sampleColumn := findSampleColumn(header, rows, cell)
+	phenotype := &Phenotype{
+		SampleIDs: make([]string, len(rows)),
+		Values:    make([][]float64, len(rows)),
+		Schema:    &PhenotypeSchema{SampleColumn: header[sampleColumn], Samples: len(rows), MissingTokens: []string{}},
+	}
+	samples := map[string]bool{}
+	for i, row := range rows {
+		sampleID := cell(row, sampleColumn)
+		if isMissing(sampleID) {
+			return nil, fmt.Errorf("%s %d: missing sample ID", unit, row.number)
+		}
+		if samples[sampleID] {
+			return nil, fmt.Errorf("%s %d: duplicate sample ID %s", unit, row.number, sampleID)
+		}
+		samples[sampleID] = true
+		phenotype.SampleIDs[i] = sampleID
+	}
+
+	missingTokens := map[string]bool{}
+	var numericColumns []int
+	for j, name := range header {
+		if j == sampleColumn {
+			continue
+		}
+
+		trait := TraitSchema{Name: name, Column: j, Type: NUMERIC}
+		levels := map[string]bool{}
+		for _, row := range rows {
+			value := cell(row, j)
+			if isMissing(value) {
+				trait.Missing++
+				if !missingTokens[value] {
+					missingTokens[value] = true
+					phenotype.Schema.MissingTokens = append(phenotype.Schema.MissingTokens, value)
+				}
+				continue
+			}
+
+			trait.Observed++
+			if _, err := strconv.ParseFloat(value, 64); err != nil {
+				trait.Type = CATEGORICAL
+			}
+			if !levels[value] && len(levels) < maxTraitLevels {
+				levels[value] = true
+				trait.Levels = append(trait.Levels, value)
+			}
+		}
+
+		if trait.Type == NUMERIC {
+			trait.Levels = nil
+			phenotype.Traits = append(phenotype.Traits, name)
+			numericColumns = append(numericColumns, j)
+		}
+		phenotype.Schema.Traits = append(phenotype.Schema.Traits, trait)
+	}
+
+	for i, row := range rows {
+		phenotype.Values[i] = make([]float64, len(numericColumns))
+		for k, j := range numericColumns {
+			phenotype.Values[i][k], _ = parseValue(cell(row, j))
+		}
+	}
+
+	if len(phenotype.SampleIDs) == 0 {
+		return nil, errors.New("phenotype contains no samples")
+	}
+
+	return phenotype, nil
+}
+
+// readTextTable splits the records of a delimited file, blank lines and # comments are skipped
+func readTextTable(r io.Reader, mimeType string) ([]tableRow, error) {
+	rows := []tableRow{}
+	err := readRecords(r, mimeType, 64*1024*1024, func(lineNumber int, fields []string) error {
+		rows = append(rows, tableRow{number: lineNumber, fields: fields})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return rows, nil
+}
+
+func findSampleColumn(header []string, rows []tableRow, cell func(row tableRow, j int) string) int {
+	for j, name := range header {
+		name = strings.ToLower(strings.NewReplacer(" ", "", "_", "", "-", "", "#", "").Replace(name))
+		if sampleColumnNames[name] {
+			return j
+		}
+	}
+
+	for j := range header {
+		distinct := len(rows) > 0
+		numeric := true
+		seen := map[string]bool{}
+		for _, row := range rows {
+			value := cell(row, j)
+			if isMissing(value) || seen[value] {
+				distinct = false
+				break
+			}
+			seen[value] = true
+			if _, err := strconv.ParseFloat(value, 64); err != nil {
+				numeric = false
+			}
+		}
+		if distinct && !numeric {
+			return j
+		}
+	}
+
+	return 0
+}
@@ -0,0 +1,63 @@
+package genomics
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestReadPhenotypeQuotedCSV(t *testing.T) {
+	content := strings.Join([]string{
+		`sample,"yield, t/ha",note`,
+		`"S1, plot 3",5.5,"said ""ok"""`,
+		`S2 , 6 ,"two`,
+		`lines"`,
+		`# comment`,
+		``,
+		`  "S3",NA,plain "quote"`,
+	}, "\n")
+
+	phenotype, err := ReadPhenotype(strings.NewReader(content), "csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := []string{"S1, plot 3", "S2", "S3"}; !reflect.DeepEqual(phenotype.SampleIDs, want) {
+		t.Errorf("sample IDs %q, want %q", phenotype.SampleIDs, want)
+	}
+	if want := []string{"yield, t/ha"}; !reflect.DeepEqual(phenotype.Traits, want) {
+		t.Errorf("traits %q, want %q", phenotype.Traits, want)
+	}
+	note, err := phenotype.Schema.Trait("note")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{`said "ok"`, "two\nlines", `plain "quote"`}; !reflect.DeepEqual(note.Levels, want) {
+		t.Errorf("note levels %q, want %q", note.Levels, want)
+	}
+	if phenotype.Values[1][0] != 6 {
+		t.Errorf("yield of S2 = %v, want 6", phenotype.Values[1][0])
+	}
+}
+
+func TestReadPhenotypeLineNumbers(t *testing.T) {
+	tests := []struct {
+		content string
+		want    string
+	}{
+		// a record spanning lines is reported at the line it starts on
+		{content: "sample,a\n\"S1\nS1\",1\nS2,1,2\n", want: "line 4: expected 2 columns, got 3"},
+		{content: "sample,a\nS1,1\nS1,2\n", want: "line 3: duplicate sample ID S1"},
+		{content: "sample,a\nS1,\"1\nS2,2\n", want: "line 2: quoted field is not closed"},
+		{content: "sample\ta\nS1\t1\t2\n", want: "line 2: expected 2 columns, got 3"},
+	}
+
+	for _, test := range tests {
+		mimeType := "csv"
+		if strings.Contains(test.content, "\t") {
+			mimeType = "tsv"
+		}
+		_, err := ReadPhenotype(strings.NewReader(test.content), mimeType)
+		checkError(t, err, test.want)
+	}
+}
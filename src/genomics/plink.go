@@ -0,0 +1,303 @@
+package genomics
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// PLINK_MIME_TYPE is the extension of a PLINK binary fileset stored as one tar archive of its .fam, .bim
+// and .bed files, in that order
+const PLINK_MIME_TYPE = "plink.tar"
+
+// PLINKParts are the extensions of the files of a PLINK binary fileset, in archive order
+var PLINKParts = []string{"fam", "bim", "bed"}
+
+// bedMagic starts a SNP-major .bed file
+var bedMagic = []byte{0x6c, 0x1b, 0x01}
+
+// bedDosages decodes a .bed byte into the A1 allele counts of four samples, the lowest bits first
+var bedDosages = func() [256][4]float64 {
+	var table [256][4]float64
+	codes := [4]float64{2, math.NaN(), 1, 0}
+	for b := 0; b < 256; b++ {
+		for k := 0; k < 4; k++ {
+			table[b][k] = codes[(b>>(2*uint(k)))&3]
+		}
+	}
+	return table
+}()
+
+type bimVariant struct {
+	marker  Marker
+	alleles [2]string
+}
+
+// WritePLINKArchive writes the files of a fileset as a PLINK archive, open returns the content and size
+// of the file with the given extension. Entries carry no time, so that the same files always make the
+// same archive.
+func WritePLINKArchive(w io.Writer, open func(part string) (io.ReadCloser, int64, error)) error {
+	archive := tar.NewWriter(w)
+	for _, part := range PLINKParts {
+		content, size, err := open(part)
+		if err != nil {
+			return err
+		}
+
+		err = archive.WriteHeader(&tar.Header{Name: "genotype." + part, Mode: 0644, Size: size, Typeflag: tar.TypeReg, Format: tar.FormatUSTAR})
+		if err == nil {
+			_, err = io.Copy(archive, content)
+		}
+		content.Close()
+		if err != nil {
+			return err
+		}
+	}
+
+	return archive.Close()
+}
+
+// ValidatePLINKArchive reads a PLINK fileset archive to the end, checking that its files agree, and
+// returns its sample IDs and a summary of its variants
+func ValidatePLINKArchive(r io.Reader) ([]string, *VariantSummary, error) {
+	return scanPLINKArchive(r, nil)
+}
+
+// ReadPLINKArchive reads the genotype calls of a PLINK fileset archive as A1 allele dosages
+func ReadPLINKArchive(r io.Reader) (*Genotype, error) {
+	genotype := &Genotype{}
+	sampleIDs, _, err := scanPLINKArchive(r, func(sampleIDs []string, markers []Marker) func(j int, dosages []float64) {
+		genotype.SampleIDs = sampleIDs
+		genotype.Markers = markers
+		genotype.Dosages = make([][]float64, len(sampleIDs))
+		for i := range genotype.Dosages {
+			genotype.Dosages[i] = make([]float64, len(markers))
+		}
+		return func(j int, dosages []float64) {
+			for i, dosage := range dosages {
+				genotype.Dosages[i][j] = dosage
+			}
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(sampleIDs) == 0 {
+		return nil, errors.New("genotype contains no samples")
+	}
+
+	return genotype, nil
+}
+
+// scanPLINKArchive reads the .fam and .bim files, then streams the .bed file one variant at a time. When
+// start is given it is called once the samples and markers are known and returns the visitor of the
+// variants.
+func scanPLINKArchive(
+	r io.Reader, start func(sampleIDs []string, markers []Marker) func(j int, dosages []float64),
+) ([]string, *VariantSummary, error) {
+	archive := tar.NewReader(r)
+
+	var sampleIDs []string
+	var variants []bimVariant
+	summary := &VariantSummary{FileFormat: "PLINK 1 binary", Chromosomes: []ChromosomeSummary{}}
+	for _, part := range PLINKParts {
+		header, err := archive.Next()
+		if err == io.EOF {
+			return nil, nil, fmt.Errorf("the .%s file is missing", part)
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid PLINK archive: %v", err)
+		}
+		if strings.TrimPrefix(path.Ext(header.Name), ".") != part {
+			return nil, nil, fmt.Errorf("expected the .%s file, found %s", part, header.Name)
+		}
+
+		switch part {
+		case "fam":
+			sampleIDs, err = readFam(archive)
+		case "bim":
+			variants, err = readBim(archive)
+		case "bed":
+			markers := make([]Marker, len(variants))
+			for j, variant := range variants {
+				markers[j] = variant.marker
+			}
+			var visit func(j int, dosages []float64)
+			if start != nil {
+				visit = start(sampleIDs, markers)
+			}
+			summary.MissingRate, err = readBed(archive, header.Size, len(sampleIDs), len(variants), visit)
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf(".%s file: %v", part, err)
+		}
+	}
+
+	chromosomes := map[string]int{}
+	for _, variant := range variants {
+		summary.Variants++
+		first, second := variant.alleles[0], variant.alleles[1]
+		switch {
+		case isBases(first) && isBases(second) && len(first) == 1 && len(second) == 1:
+			summary.SNPs++
+		case isBases(first) && isBases(second):
+			summary.Indels++
+		}
+
+		index, ok := chromosomes[variant.marker.Chromosome]
+		if !ok {
+			index = len(summary.Chromosomes)
+			chromosomes[variant.marker.Chromosome] = index
+			summary.Chromosomes = append(summary.Chromosomes, ChromosomeSummary{
+				Name:          variant.marker.Chromosome,
+				FirstPosition: variant.marker.Position,
+			})
+		}
+		chromosome := &summary.Chromosomes[index]
+		chromosome.Variants++
+		if variant.marker.Position < chromosome.FirstPosition {
+			chromosome.FirstPosition = variant.marker.Position
+		}
+		if variant.marker.Position > chromosome.LastPosition {
+			chromosome.LastPosition = variant.marker.Position
+		}
+	}
+	summary.Samples = len(sampleIDs)
+
+	return sampleIDs, summary, nil
+}
+
+// readFam returns the individual IDs of a .fam file
+func readFam(r io.Reader) ([]string, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	sampleIDs := []string{}
+	seen := map[string]bool{}
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) != 6 {
+			return nil, fmt.Errorf("line %d: expected 6 columns, got %d", lineNumber, len(fields))
+		}
+		if seen[fields[1]] {
+			return nil, fmt.Errorf("line %d: duplicate sample ID %s", lineNumber, fields[1])
+		}
+		seen[fields[1]] = true
+		sampleIDs = append(sampleIDs, fields[1])
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("line %d: %v", lineNumber+1, err)
+	}
+	if len(sampleIDs) == 0 {
+		return nil, errors.New("no samples")
+	}
+
+	return sampleIDs, nil
+}
+
+func readBim(r io.Reader) ([]bimVariant, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 64*1024*1024)
+
+	variants := []bimVariant{}
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) != 6 {
+			return nil, fmt.Errorf("line %d: expected 6 columns, got %d", lineNumber, len(fields))
+		}
+
+		_, err := strconv.ParseFloat(fields[2], 64)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid genetic distance %q", lineNumber, fields[2])
+		}
+		position, err := strconv.ParseInt(fields[3], 10, 64)
+		if err != nil || position < 0 {
+			return nil, fmt.Errorf("line %d: invalid position %q", lineNumber, fields[3])
+		}
+
+		id := fields[1]
+		if id == "." {
+			id = fields[0] + ":" + fields[3]
+		}
+		variants = append(variants, bimVariant{
+			marker:  Marker{ID: id, Chromosome: fields[0], Position: position, Reference: fields[5], Alternate: fields[4]},
+			alleles: [2]string{fields[4], fields[5]},
+		})
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("line %d: %v", lineNumber+1, err)
+	}
+	if len(variants) == 0 {
+		return nil, errors.New("no variants")
+	}
+
+	return variants, nil
+}
+
+// readBed checks the size of a SNP-major .bed file against the samples and variants, decodes every
+// variant and returns the rate of missing calls
+func readBed(r io.Reader, size int64, samples, variants int, visit func(j int, dosages []float64)) (float64, error) {
+	bytesPerVariant := (samples + 3) / 4
+	expected := int64(len(bedMagic)) + int64(variants)*int64(bytesPerVariant)
+	if size != expected {
+		return 0, fmt.Errorf(
+			"has %d bytes, %d samples and %d variants take %d bytes, the .fam and .bim files do not match it",
+			size, samples, variants, expected,
+		)
+	}
+
+	buffered := bufio.NewReaderSize(r, 1024*1024)
+	magic := make([]byte, len(bedMagic))
+	_, err := io.ReadFull(buffered, magic)
+	if err != nil {
+		return 0, err
+	}
+	if !bytes.Equal(magic[:2], bedMagic[:2]) {
+		return 0, errors.New("not a PLINK .bed file")
+	}
+	if magic[2] != bedMagic[2] {
+		return 0, errors.New("individual-major .bed files are not supported, convert the fileset with plink --make-bed")
+	}
+
+	block := make([]byte, bytesPerVariant)
+	dosages := make([]float64, samples)
+	missing := 0
+	for j := 0; j < variants; j++ {
+		_, err = io.ReadFull(buffered, block)
+		if err != nil {
+			return 0, fmt.Errorf("variant %d: %v", j+1, err)
+		}
+
+		for i := 0; i < samples; i++ {
+			dosages[i] = bedDosages[block[i/4]][i%4]
+			if math.IsNaN(dosages[i]) {
+				missing++
+			}
+		}
+		if visit != nil {
+			visit(j, dosages)
+		}
+	}
+
+	return float64(missing) / float64(samples*variants), nil
+}
@@ -0,0 +1,139 @@
+package genomics
+
+import (
+	"archive/tar"
+	"bytes"
+	"io"
+	"io/ioutil"
+	"math"
+	"reflect"
+	"testing"
+)
+
+// plinkArchive packs the sample fileset fixture, with the .bed content replaced when given
+func plinkArchive(t *testing.T, bed []byte) *bytes.Buffer {
+	t.Helper()
+	if bed == nil {
+		bed = readFixture(t, "sample.bed")
+	}
+
+	var archive bytes.Buffer
+	err := WritePLINKArchive(&archive, func(part string) (io.ReadCloser, int64, error) {
+		content := readFixture(t, "sample."+part)
+		if part == "bed" {
+			content = bed
+		}
+		return ioutil.NopCloser(bytes.NewReader(content)), int64(len(content)), nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &archive
+}
+
+func TestReadPLINKArchive(t *testing.T) {
+	sampleIDs, summary, err := ValidatePLINKArchive(plinkArchive(t, nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(sampleIDs, []string{"S1", "S2", "S3", "S4", "S5"}) {
+		t.Errorf("sample IDs %v", sampleIDs)
+	}
+	want := &VariantSummary{
+		FileFormat:  "PLINK 1 binary",
+		Samples:     5,
+		Variants:    2,
+		SNPs:        2,
+		MissingRate: 0.1,
+		Chromosomes: []ChromosomeSummary{
+			{Name: "1", Variants: 1, FirstPosition: 100, LastPosition: 100},
+			{Name: "2", Variants: 1, FirstPosition: 250, LastPosition: 250},
+		},
+	}
+	if !reflect.DeepEqual(summary, want) {
+		t.Errorf("summary %+v, want %+v", summary, want)
+	}
+
+	genotype, err := ReadPLINKArchive(plinkArchive(t, nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantMarkers := []Marker{
+		{ID: "snp1", Chromosome: "1", Position: 100, Reference: "A", Alternate: "G"},
+		{ID: "2:250", Chromosome: "2", Position: 250, Reference: "C", Alternate: "T"},
+	}
+	if !reflect.DeepEqual(genotype.Markers, wantMarkers) {
+		t.Errorf("markers %+v", genotype.Markers)
+	}
+	// dosages count A1, the fifth .bim column, and the fifth sample starts the second byte
+	checkDosages(t, genotype, [][]float64{
+		{2, 0},
+		{1, 0},
+		{0, 1},
+		{math.NaN(), 2},
+		{2, 1},
+	})
+}
+
+func TestPLINKArchiveBedErrors(t *testing.T) {
+	bed := readFixture(t, "sample.bed")
+	withMagic := func(magic ...byte) []byte {
+		return append(magic, bed[3:]...)
+	}
+
+	tests := []struct {
+		name string
+		bed  []byte
+		want string
+	}{
+		{name: "truncated", bed: bed[:len(bed)-1], want: ".bed file: has 6 bytes, 5 samples and 2 variants take 7 bytes, the .fam and .bim files do not match it"},
+		{name: "extra variant", bed: append(append([]byte{}, bed...), 0, 0), want: ".bed file: has 9 bytes, 5 samples and 2 variants take 7 bytes"},
+		{name: "magic", bed: withMagic(0x00, 0x00, 0x01), want: ".bed file: not a PLINK .bed file"},
+		{name: "individual-major", bed: withMagic(0x6c, 0x1b, 0x00), want: ".bed file: individual-major .bed files are not supported"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, _, err := ValidatePLINKArchive(plinkArchive(t, test.bed))
+			checkError(t, err, test.want)
+		})
+	}
+}
+
+func TestPLINKArchiveErrors(t *testing.T) {
+	archive := func(files ...[2]string) *bytes.Buffer {
+		var buffer bytes.Buffer
+		writer := tar.NewWriter(&buffer)
+		for _, file := range files {
+			writer.WriteHeader(&tar.Header{Name: file[0], Mode: 0644, Size: int64(len(file[1]))})
+			writer.Write([]byte(file[1]))
+		}
+		writer.Close()
+		return &buffer
+	}
+	fam := string(readFixture(t, "sample.fam"))
+	bim := string(readFixture(t, "sample.bim"))
+
+	tests := []struct {
+		name    string
+		archive *bytes.Buffer
+		want    string
+	}{
+		{name: "not an archive", archive: bytes.NewBufferString("not a tar archive, long enough to fill a tar block header......."), want: "invalid PLINK archive"},
+		{name: "missing part", archive: archive([2]string{"a.fam", fam}), want: "the .bim file is missing"},
+		{name: "order", archive: archive([2]string{"a.bim", bim}), want: "expected the .fam file, found a.bim"},
+		{name: "fam columns", archive: archive([2]string{"a.fam", "F1 S1 0 0 0\n"}), want: ".fam file: line 1: expected 6 columns, got 5"},
+		{name: "duplicate sample", archive: archive([2]string{"a.fam", "F1 S1 0 0 0 -9\n\nF2 S1 0 0 0 -9\n"}), want: ".fam file: line 3: duplicate sample ID S1"},
+		{name: "no samples", archive: archive([2]string{"a.fam", "\n"}), want: ".fam file: no samples"},
+		{name: "bim position", archive: archive([2]string{"a.fam", fam}, [2]string{"a.bim", "1 snp1 0 x G A\n"}), want: `.bim file: line 1: invalid position "x"`},
+		{name: "bim distance", archive: archive([2]string{"a.fam", fam}, [2]string{"a.bim", "1 snp1 0 1 G A\n1 snp2 cm 2 G A\n"}), want: `.bim file: line 2: invalid genetic distance "cm"`},
+		{name: "no variants", archive: archive([2]string{"a.fam", fam}, [2]string{"a.bim", ""}), want: ".bim file: no variants"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, _, err := ValidatePLINKArchive(test.archive)
+			checkError(t, err, test.want)
+		})
+	}
+}
@@ -0,0 +1,267 @@
+package genomics
+
+import (
+	"errors"
+	"math"
+	"sort"
+)
+
+type QCReason string
+
+const (
+	QC_CALL_RATE      QCReason = "CALL_RATE"
+	QC_HETEROZYGOSITY QCReason = "HETEROZYGOSITY"
+	QC_DUPLICATE      QCReason = "DUPLICATE"
+	QC_MINOR_ALLELE   QCReason = "MINOR_ALLELE_FREQUENCY"
+)
+
+// QCParameters are the thresholds of a genotype quality control, a nil threshold skips its filter
+type QCParameters struct {
+	MinMarkerCallRate       *float64 `json:"minMarkerCallRate,omitempty" bson:"minMarkerCallRate,omitempty" binding:"omitempty,min=0,max=1"`
+	MinSampleCallRate       *float64 `json:"minSampleCallRate,omitempty" bson:"minSampleCallRate,omitempty" binding:"omitempty,min=0,max=1"`
+	MaxSampleHeterozygosity *float64 `json:"maxSampleHeterozygosity,omitempty" bson:"maxSampleHeterozygosity,omitempty" binding:"omitempty,min=0,max=1"`
+	DuplicateConcordance    *float64 `json:"duplicateConcordance,omitempty" bson:"duplicateConcordance,omitempty" binding:"omitempty,min=0.5,max=1"`
+	MinMAF                  *float64 `json:"minMAF,omitempty" bson:"minMAF,omitempty" binding:"omitempty,min=0,max=0.5"`
+	MaxMarkerHeterozygosity *float64 `json:"maxMarkerHeterozygosity,omitempty" bson:"maxMarkerHeterozygosity,omitempty" binding:"omitempty,min=0,max=1"`
+}
+
+// IsEmpty tells whether no filter is set
+func (p QCParameters) IsEmpty() bool {
+	return p.MinMarkerCallRate == nil && p.MinSampleCallRate == nil && p.MaxSampleHeterozygosity == nil &&
+		p.DuplicateConcordance == nil && p.MinMAF == nil && p.MaxMarkerHeterozygosity == nil
+}
+
+// RemovedSample is a sample dropped by a quality control, DuplicateOf names the sample it duplicates
+type RemovedSample struct {
+	SampleID    string   `json:"sampleID" bson:"sampleID"`
+	Reason      QCReason `json:"reason" bson:"reason"`
+	DuplicateOf string   `json:"duplicateOf,omitempty" bson:"duplicateOf,omitempty"`
+}
+
+// QCReport counts what a quality control kept, removed markers are counted per reason
+type QCReport struct {
+	Samples        int             `json:"samples" bson:"samples"`
+	Markers        int             `json:"markers" bson:"markers"`
+	KeptSamples    int             `json:"keptSamples" bson:"keptSamples"`
+	KeptMarkers    int             `json:"keptMarkers" bson:"keptMarkers"`
+	RemovedSamples []RemovedSample `json:"removedSamples" bson:"removedSamples"`
+	RemovedMarkers map[string]int  `json:"removedMarkers" bson:"removedMarkers"`
+}
+
+// RunQC filters the markers and samples of a genotype as SelectQC does and returns what it kept
+func RunQC(genotype *Genotype, parameters QCParameters) (*Genotype, QCReport, error) {
+	samples, markers, report, err := SelectQC(genotype, parameters)
+	if err != nil {
+		return nil, report, err
+	}
+
+	filtered := &Genotype{
+		SampleIDs: make([]string, len(samples)),
+		Markers:   make([]Marker, len(markers)),
+		Dosages:   make([][]float64, len(samples)),
+	}
+	for k, j := range markers {
+		filtered.Markers[k] = genotype.Markers[j]
+	}
+	for k, i := range samples {
+		filtered.SampleIDs[k] = genotype.SampleIDs[i]
+		filtered.Dosages[k] = make([]float64, len(markers))
+		for l, j := range markers {
+			filtered.Dosages[k][l] = genotype.Dosages[i][j]
+		}
+	}
+	return filtered, report, nil
+}
+
+// SelectQC returns the indices of the samples and markers of a genotype that pass a quality control, in
+// their original order. The filters run in the order of PLINK: marker call rate, sample call rate,
+// sample heterozygosity, duplicate samples, minor allele frequency and marker heterozygosity. Each
+// filter only sees what the previous ones kept. Of two duplicates, the sample with the lower call rate
+// is removed.
+func SelectQC(genotype *Genotype, parameters QCParameters) ([]int, []int, QCReport, error) {
+	report := QCReport{
+		Samples:        genotype.NumSamples(),
+		Markers:        genotype.NumMarkers(),
+		RemovedSamples: []RemovedSample{},
+		RemovedMarkers: map[string]int{},
+	}
+
+	samples := make([]int, genotype.NumSamples())
+	for i := range samples {
+		samples[i] = i
+	}
+	markers := make([]int, genotype.NumMarkers())
+	for j := range markers {
+		markers[j] = j
+	}
+
+	if parameters.MinMarkerCallRate != nil {
+		markers = filterMarkers(genotype, samples, markers, QC_CALL_RATE, &report, func(called, heterozygous int, sum float64) bool {
+			return float64(called)/float64(len(samples)) >= *parameters.MinMarkerCallRate
+		})
+	}
+
+	callRates := make([]float64, genotype.NumSamples())
+	for _, i := range samples {
+		called, _ := countCalls(genotype.Dosages[i], markers)
+		if len(markers) > 0 {
+			callRates[i] = float64(called) / float64(len(markers))
+		}
+	}
+
+	if parameters.MinSampleCallRate != nil {
+		samples = filterSamples(genotype, samples, QC_CALL_RATE, &report, func(i int) bool {
+			return callRates[i] >= *parameters.MinSampleCallRate
+		})
+	}
+
+	if parameters.MaxSampleHeterozygosity != nil {
+		samples = filterSamples(genotype, samples, QC_HETEROZYGOSITY, &report, func(i int) bool {
+			called, heterozygous := countCalls(genotype.Dosages[i], markers)
+			return called == 0 || float64(heterozygous)/float64(called) <= *parameters.MaxSampleHeterozygosity
+		})
+	}
+
+	if parameters.DuplicateConcordance != nil {
+		samples = removeDuplicates(genotype, samples, markers, callRates, *parameters.DuplicateConcordance, &report)
+	}
+
+	if parameters.MinMAF != nil {
+		markers = filterMarkers(genotype, samples, markers, QC_MINOR_ALLELE, &report, func(called, heterozygous int, sum float64) bool {
+			if called == 0 {
+				return false
+			}
+			frequency := sum / float64(2*called)
+			return math.Min(frequency, 1-frequency) >= *parameters.MinMAF
+		})
+	}
+
+	if parameters.MaxMarkerHeterozygosity != nil {
+		markers = filterMarkers(genotype, samples, markers, QC_HETEROZYGOSITY, &report, func(called, heterozygous int, sum float64) bool {
+			return called == 0 || float64(heterozygous)/float64(called) <= *parameters.MaxMarkerHeterozygosity
+		})
+	}
+
+	if len(samples) == 0 {
+		return nil, nil, report, errors.New("quality control removed every sample")
+	}
+	if len(markers) == 0 {
+		return nil, nil, report, errors.New("quality control removed every marker")
+	}
+
+	report.KeptSamples = len(samples)
+	report.KeptMarkers = len(markers)
+	return samples, markers, report, nil
+}
+
+// isHeterozygous reads a dosage as the nearest diploid call
+func isHeterozygous(dosage float64) bool {
+	return math.Round(dosage) == 1
+}
+
+func countCalls(row []float64, markers []int) (int, int) {
+	called, heterozygous := 0, 0
+	for _, j := range markers {
+		if math.IsNaN(row[j]) {
+			continue
+		}
+		called++
+		if isHeterozygous(row[j]) {
+			heterozygous++
+		}
+	}
+	return called, heterozygous
+}
+
+func filterMarkers(
+	genotype *Genotype, samples, markers []int, reason QCReason, report *QCReport, keep func(called, heterozygous int, sum float64) bool,
+) []int {
+	kept := markers[:0:0]
+	for _, j := range markers {
+		called, heterozygous, sum := 0, 0, 0.0
+		for _, i := range samples {
+			dosage := genotype.Dosages[i][j]
+			if math.IsNaN(dosage) {
+				continue
+			}
+			called++
+			sum += dosage
+			if isHeterozygous(dosage) {
+				heterozygous++
+			}
+		}
+
+		if keep(called, heterozygous, sum) {
+			kept = append(kept, j)
+		} else {
+			report.RemovedMarkers[string(reason)]++
+		}
+	}
+	return kept
+}
+
+func filterSamples(genotype *Genotype, samples []int, reason QCReason, report *QCReport, keep func(i int) bool) []int {
+	kept := samples[:0:0]
+	for _, i := range samples {
+		if keep(i) {
+			kept = append(kept, i)
+		} else {
+			report.RemovedSamples = append(report.RemovedSamples, RemovedSample{SampleID: genotype.SampleIDs[i], Reason: reason})
+		}
+	}
+	return kept
+}
+
+// removeDuplicates compares every pair of samples on the markers both are called at. Comparing a pair
+// stops once it has more discordant calls than the concordance allows over all the markers, the bound
+// is rounded with a margin so 0.8 of 5 markers allows 1.
+func removeDuplicates(genotype *Genotype, samples, markers []int, callRates []float64, concordance float64, report *QCReport) []int {
+	order := append([]int(nil), samples...)
+	sort.SliceStable(order, func(a, b int) bool { return callRates[order[a]] > callRates[order[b]] })
+
+	maxDiscordant := int(math.Floor((1-concordance)*float64(len(markers)) + 1e-9))
+	duplicateOf := map[int]int{}
+	for a, i := range order {
+		if _, ok := duplicateOf[i]; ok {
+			continue
+		}
+		for _, k := range order[a+1:] {
+			if _, ok := duplicateOf[k]; ok {
+				continue
+			}
+
+			shared, discordant := 0, 0
+			for _, j := range markers {
+				x, y := genotype.Dosages[i][j], genotype.Dosages[k][j]
+				if math.IsNaN(x) || math.IsNaN(y) {
+					continue
+				}
+				shared++
+				if math.Round(x) != math.Round(y) {
+					discordant++
+					if discordant > maxDiscordant {
+						break
+					}
+				}
+			}
+			if shared > 0 && float64(shared-discordant)/float64(shared) >= concordance {
+				duplicateOf[k] = i
+			}
+		}
+	}
+
+	kept := samples[:0:0]
+	for _, i := range samples {
+		original, ok := duplicateOf[i]
+		if !ok {
+			kept = append(kept, i)
+			continue
+		}
+		report.RemovedSamples = append(report.RemovedSamples, RemovedSample{
+			SampleID:    genotype.SampleIDs[i],
+			Reason:      QC_DUPLICATE,
+			DuplicateOf: genotype.SampleIDs[original],
+		})
+	}
+	return kept
+}
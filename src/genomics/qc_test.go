@@ -0,0 +1,252 @@
+package genomics
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"testing"
+)
+
+func threshold(value float64) *float64 {
+	return &value
+}
+
+// newQCGenotype names the samples S1, S2... and the markers M1, M2... of dosage rows
+func newQCGenotype(rows [][]float64) *Genotype {
+	genotype := &Genotype{Dosages: rows}
+	for i := range rows {
+		genotype.SampleIDs = append(genotype.SampleIDs, fmt.Sprintf("S%d", i+1))
+	}
+	for j := range rows[0] {
+		genotype.Markers = append(genotype.Markers, Marker{ID: fmt.Sprintf("M%d", j+1)})
+	}
+	return genotype
+}
+
+func TestRunQC(t *testing.T) {
+	nan := math.NaN()
+	tests := []struct {
+		name           string
+		rows           [][]float64
+		parameters     QCParameters
+		samples        []string
+		markers        []string
+		removedSamples []RemovedSample
+		removedMarkers map[string]int
+	}{
+		{
+			name:       "no filter",
+			rows:       [][]float64{{0, nan}, {nan, 1}},
+			parameters: QCParameters{},
+			samples:    []string{"S1", "S2"},
+			markers:    []string{"M1", "M2"},
+		},
+		{
+			name:           "marker call rate",
+			rows:           [][]float64{{0, nan, 1}, {1, nan, 2}, {2, 0, 0}},
+			parameters:     QCParameters{MinMarkerCallRate: threshold(0.6)},
+			samples:        []string{"S1", "S2", "S3"},
+			markers:        []string{"M1", "M3"},
+			removedMarkers: map[string]int{"CALL_RATE": 1},
+		},
+		{
+			name:           "sample call rate",
+			rows:           [][]float64{{0, 1, 2, 0}, {nan, nan, 2, 0}, {2, 1, 0, 1}},
+			parameters:     QCParameters{MinSampleCallRate: threshold(0.75)},
+			samples:        []string{"S1", "S3"},
+			markers:        []string{"M1", "M2", "M3", "M4"},
+			removedSamples: []RemovedSample{{SampleID: "S2", Reason: QC_CALL_RATE}},
+		},
+		{
+			// S1 and S2 are only missing at M1, which goes first
+			name:           "sample call rate on the kept markers",
+			rows:           [][]float64{{nan, 0, 1, 2}, {nan, 1, 1, 0}, {0, 1, 2, 1}},
+			parameters:     QCParameters{MinMarkerCallRate: threshold(0.5), MinSampleCallRate: threshold(0.8)},
+			samples:        []string{"S1", "S2", "S3"},
+			markers:        []string{"M2", "M3", "M4"},
+			removedMarkers: map[string]int{"CALL_RATE": 1},
+		},
+		{
+			name:           "sample heterozygosity",
+			rows:           [][]float64{{1, 1, 1, 0}, {0, 1, 2, 0}, {2, nan, 1, 0}},
+			parameters:     QCParameters{MaxSampleHeterozygosity: threshold(0.5)},
+			samples:        []string{"S2", "S3"},
+			markers:        []string{"M1", "M2", "M3", "M4"},
+			removedSamples: []RemovedSample{{SampleID: "S1", Reason: QC_HETEROZYGOSITY}},
+		},
+		{
+			// S1 comes first but misses a call
+			name:           "duplicate with the lower call rate",
+			rows:           [][]float64{{0, nan, 2, 1, 0}, {0, 1, 2, 1, 0}, {2, 1, 0, 0, 1}},
+			parameters:     QCParameters{DuplicateConcordance: threshold(0.9)},
+			samples:        []string{"S2", "S3"},
+			markers:        []string{"M1", "M2", "M3", "M4", "M5"},
+			removedSamples: []RemovedSample{{SampleID: "S1", Reason: QC_DUPLICATE, DuplicateOf: "S2"}},
+		},
+		{
+			// 4 of 5 calls agree, the comparison must not stop at the first discordant call
+			name:           "duplicate at the concordance",
+			rows:           [][]float64{{0, 1, 2, 1, 0}, {2, 1, 2, 1, 0}, {2, 0, 0, 2, 1}},
+			parameters:     QCParameters{DuplicateConcordance: threshold(0.8)},
+			samples:        []string{"S1", "S3"},
+			markers:        []string{"M1", "M2", "M3", "M4", "M5"},
+			removedSamples: []RemovedSample{{SampleID: "S2", Reason: QC_DUPLICATE, DuplicateOf: "S1"}},
+		},
+		{
+			name:       "duplicate below the concordance",
+			rows:       [][]float64{{0, 1, 2, 1, 0}, {2, 1, 2, 1, 0}, {2, 0, 0, 2, 1}},
+			parameters: QCParameters{DuplicateConcordance: threshold(0.9)},
+			samples:    []string{"S1", "S2", "S3"},
+			markers:    []string{"M1", "M2", "M3", "M4", "M5"},
+		},
+		{
+			// concordance is measured on the 4 markers both are called at
+			name:       "duplicate on shared calls",
+			rows:       [][]float64{{0, 1, 2, 1, nan}, {2, 1, 2, 1, 0}, {2, 0, 0, 2, 1}},
+			parameters: QCParameters{DuplicateConcordance: threshold(0.8)},
+			samples:    []string{"S1", "S2", "S3"},
+			markers:    []string{"M1", "M2", "M3", "M4", "M5"},
+		},
+		{
+			// M1 and M2 have an allele frequency of 1/8 and 7/8, M4 is never called
+			name:           "minor allele frequency",
+			rows:           [][]float64{{0, 2, 0, nan}, {0, 2, 1, nan}, {1, 2, 2, nan}, {0, 1, 1, nan}},
+			parameters:     QCParameters{MinMAF: threshold(0.1)},
+			samples:        []string{"S1", "S2", "S3", "S4"},
+			markers:        []string{"M1", "M2", "M3"},
+			removedMarkers: map[string]int{"MINOR_ALLELE_FREQUENCY": 1},
+		},
+		{
+			name:           "minor allele frequency of either allele",
+			rows:           [][]float64{{0, 2, 0, nan}, {0, 2, 1, nan}, {1, 2, 2, nan}, {0, 1, 1, nan}},
+			parameters:     QCParameters{MinMAF: threshold(0.2)},
+			samples:        []string{"S1", "S2", "S3", "S4"},
+			markers:        []string{"M3"},
+			removedMarkers: map[string]int{"MINOR_ALLELE_FREQUENCY": 3},
+		},
+		{
+			// M1 is only polymorphic through S4, which goes first
+			name:           "minor allele frequency on the kept samples",
+			rows:           [][]float64{{0, 0, 1}, {0, 1, 1}, {0, 2, 1}, {2, nan, nan}},
+			parameters:     QCParameters{MinSampleCallRate: threshold(0.5), MinMAF: threshold(0.1)},
+			samples:        []string{"S1", "S2", "S3"},
+			markers:        []string{"M2", "M3"},
+			removedSamples: []RemovedSample{{SampleID: "S4", Reason: QC_CALL_RATE}},
+			removedMarkers: map[string]int{"MINOR_ALLELE_FREQUENCY": 1},
+		},
+		{
+			name:           "marker heterozygosity",
+			rows:           [][]float64{{1, 0, nan}, {1, 1, nan}, {1, 2, nan}, {0, nan, nan}},
+			parameters:     QCParameters{MaxMarkerHeterozygosity: threshold(0.5)},
+			samples:        []string{"S1", "S2", "S3", "S4"},
+			markers:        []string{"M2", "M3"},
+			removedMarkers: map[string]int{"HETEROZYGOSITY": 1},
+		},
+		{
+			// each filter removes one sample or marker, M2 is only polymorphic through S4
+			name: "every filter",
+			rows: [][]float64{
+				{nan, 0, 1, 0, 2, 0, 0},
+				{2, 0, 1, 0, 2, 0, 0},
+				{nan, nan, nan, nan, nan, 1, 1},
+				{1, 1, 1, 1, 1, 0, nan},
+				{0, 0, 1, 2, 0, 1, nan},
+				{0, 0, 0, 1, 2, 2, nan},
+			},
+			parameters: QCParameters{
+				MinMarkerCallRate:       threshold(0.6),
+				MinSampleCallRate:       threshold(0.5),
+				MaxSampleHeterozygosity: threshold(0.6),
+				DuplicateConcordance:    threshold(0.99),
+				MinMAF:                  threshold(0.1),
+				MaxMarkerHeterozygosity: threshold(0.6),
+			},
+			samples: []string{"S2", "S5", "S6"},
+			markers: []string{"M1", "M4", "M5", "M6"},
+			removedSamples: []RemovedSample{
+				{SampleID: "S3", Reason: QC_CALL_RATE},
+				{SampleID: "S4", Reason: QC_HETEROZYGOSITY},
+				{SampleID: "S1", Reason: QC_DUPLICATE, DuplicateOf: "S2"},
+			},
+			removedMarkers: map[string]int{"CALL_RATE": 1, "MINOR_ALLELE_FREQUENCY": 1, "HETEROZYGOSITY": 1},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			genotype := newQCGenotype(test.rows)
+			filtered, report, err := RunQC(genotype, test.parameters)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var markers []string
+			for _, marker := range filtered.Markers {
+				markers = append(markers, marker.ID)
+			}
+			if !reflect.DeepEqual(filtered.SampleIDs, test.samples) || !reflect.DeepEqual(markers, test.markers) {
+				t.Fatalf("kept %v and %v, want %v and %v", filtered.SampleIDs, markers, test.samples, test.markers)
+			}
+
+			if test.removedSamples == nil {
+				test.removedSamples = []RemovedSample{}
+			}
+			if test.removedMarkers == nil {
+				test.removedMarkers = map[string]int{}
+			}
+			if !reflect.DeepEqual(report.RemovedSamples, test.removedSamples) {
+				t.Errorf("removed samples %+v, want %+v", report.RemovedSamples, test.removedSamples)
+			}
+			if !reflect.DeepEqual(report.RemovedMarkers, test.removedMarkers) {
+				t.Errorf("removed markers %v, want %v", report.RemovedMarkers, test.removedMarkers)
+			}
+			if report.Samples != len(test.rows) || report.Markers != len(test.rows[0]) ||
+				report.KeptSamples != len(test.samples) || report.KeptMarkers != len(test.markers) {
+				t.Errorf("report counts %+v", report)
+			}
+
+			// the kept dosages are those of the source
+			samples, markerIndices, _, err := SelectQC(genotype, test.parameters)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := make([][]float64, len(samples))
+			for k, i := range samples {
+				for _, j := range markerIndices {
+					want[k] = append(want[k], genotype.Dosages[i][j])
+				}
+			}
+			checkDosages(t, filtered, want)
+		})
+	}
+}
+
+func TestRunQCErrors(t *testing.T) {
+	nan := math.NaN()
+	tests := []struct {
+		name       string
+		rows       [][]float64
+		parameters QCParameters
+		want       string
+	}{
+		{
+			name:       "every sample",
+			rows:       [][]float64{{nan, 0}, {1, nan}},
+			parameters: QCParameters{MinSampleCallRate: threshold(1)},
+			want:       "quality control removed every sample",
+		},
+		{
+			name:       "every marker",
+			rows:       [][]float64{{0, 2}, {0, 2}},
+			parameters: QCParameters{MinMAF: threshold(0.05)},
+			want:       "quality control removed every marker",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, _, err := RunQC(newQCGenotype(test.rows), test.parameters)
+			checkError(t, err, test.want)
+		})
+	}
+}
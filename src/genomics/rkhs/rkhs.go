@@ -0,0 +1,215 @@
+package rkhs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math"
+	"sort"
+
+	"github.com/khoa5773/go-server/src/genomics"
+	"github.com/khoa5773/go-server/src/genomics/gblup"
+)
+
+// AUTO is the "default" setting value that selects gamma by REML likelihood instead of the "gamma" setting
+const AUTO = "auto"
+
+// defaultGamma is the "gamma" setting seeded for every user
+const defaultGamma = 10.0
+
+// gammaSettingScale maps the "gamma" setting onto the median-distance scale of Gammas, so the seeded 10
+// gives a correlation of exp(-1) between two lines at the median distance
+const gammaSettingScale = 10.0
+
+// Gammas are the bandwidths compared when gamma is chosen automatically. Gamma divides by the median
+// squared distance between the training lines, and values far above the largest one make the kernel
+// close to the identity.
+var Gammas = []float64{0.1, 0.25, 0.5, 1, 2, 4, 8}
+
+type Method struct{}
+
+func init() {
+	genomics.RegisterMethod(genomics.RBF, Method{})
+}
+
+// Model keeps the centered training genotypes since the Gaussian kernel has no marker effect equivalent
+type Model struct {
+	Intercept     float64     `json:"intercept" bson:"intercept"`
+	Gamma         float64     `json:"gamma" bson:"gamma"`
+	Scale         float64     `json:"scale" bson:"scale"`
+	MarkerIDs     []string    `json:"markerIDs" bson:"markerIDs"`
+	Frequencies   []float64   `json:"frequencies" bson:"frequencies"`
+	Training      [][]float64 `json:"training" bson:"training"`
+	Alpha         []float64   `json:"alpha" bson:"alpha"`
+	VarianceG     float64     `json:"varianceG" bson:"varianceG"`
+	VarianceE     float64     `json:"varianceE" bson:"varianceE"`
+	Heritability  float64     `json:"heritability" bson:"heritability"`
+	LogLikelihood float64     `json:"logLikelihood" bson:"logLikelihood"`
+}
+
+func (Method) Decode(data []byte) (genomics.Model, error) {
+	model := &Model{}
+	err := json.Unmarshal(data, model)
+	if err != nil {
+		return nil, err
+	}
+	return model, nil
+}
+
+func (m *Model) Summary() map[string]float64 {
+	return map[string]float64{
+		"intercept":     m.Intercept,
+		"gamma":         m.Gamma,
+		"scale":         m.Scale,
+		"varianceG":     m.VarianceG,
+		"varianceE":     m.VarianceE,
+		"heritability":  m.Heritability,
+		"logLikelihood": m.LogLikelihood,
+		"samples":       float64(len(m.Training)),
+		"markers":       float64(len(m.MarkerIDs)),
+	}
+}
+
+// SquaredDistances returns the squared euclidean distances between the rows of a and b
+func SquaredDistances(ctx context.Context, a, b [][]float64) ([][]float64, error) {
+	normsA := make([]float64, len(a))
+	for i, row := range a {
+		normsA[i] = genomics.Dot(row, row)
+	}
+	normsB := make([]float64, len(b))
+	for i, row := range b {
+		normsB[i] = genomics.Dot(row, row)
+	}
+
+	distances := genomics.NewMatrix(len(a), len(b))
+	for i, rowA := range a {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		for j, rowB := range b {
+			distances[i][j] = math.Max(0, normsA[i]+normsB[j]-2*genomics.Dot(rowA, rowB))
+		}
+	}
+	return distances, nil
+}
+
+// Kernel applies exp(-γ·d²/scale) element-wise
+func Kernel(distances [][]float64, gamma, scale float64) [][]float64 {
+	kernel := genomics.NewMatrix(len(distances), len(distances[0]))
+	for i, row := range distances {
+		for j, v := range row {
+			kernel[i][j] = math.Exp(-gamma * v / scale)
+		}
+	}
+	return kernel
+}
+
+// medianDistance is used to make gamma independent of the number of markers
+func medianDistance(distances [][]float64) float64 {
+	var values []float64
+	for i, row := range distances {
+		for j := 0; j < i; j++ {
+			values = append(values, row[j])
+		}
+	}
+	sort.Float64s(values)
+	return values[len(values)/2]
+}
+
+func (Method) Fit(ctx context.Context, genotype *genomics.Genotype, phenotypes []float64, parameters genomics.Parameters) (genomics.Model, error) {
+	frequencies := genotype.AlleleFrequencies()
+	centered, err := genotype.Centered(frequencies)
+	if err != nil {
+		return nil, err
+	}
+
+	distances, err := SquaredDistances(ctx, centered, centered)
+	if err != nil {
+		return nil, err
+	}
+	if len(distances) < 3 {
+		return nil, errors.New("at least three phenotyped individuals are required")
+	}
+
+	scale := medianDistance(distances)
+	if scale == 0 {
+		return nil, errors.New("genotypes are identical")
+	}
+
+	gammas := Gammas
+	if parameters.String("default", "") != AUTO {
+		gammas = []float64{parameters.Float("gamma", defaultGamma) / gammaSettingScale}
+	}
+	heritability := parameters.Float("heritability", 0)
+
+	var best *gblup.Solution
+	var bestGamma float64
+	for _, gamma := range gammas {
+		if gamma <= 0 {
+			return nil, errors.New("gamma must be positive")
+		}
+
+		solution, err := gblup.Solve(ctx, Kernel(distances, gamma, scale), phenotypes, heritability)
+		if err != nil {
+			return nil, err
+		}
+		if best == nil || solution.LogLikelihood > best.LogLikelihood {
+			best, bestGamma = solution, gamma
+		}
+	}
+
+	model := &Model{
+		Intercept:     best.Intercept,
+		Gamma:         bestGamma,
+		Scale:         scale,
+		Frequencies:   frequencies,
+		Training:      centered,
+		Alpha:         best.Alpha,
+		VarianceG:     best.VarianceG,
+		VarianceE:     best.VarianceE,
+		Heritability:  best.Heritability(),
+		LogLikelihood: best.LogLikelihood,
+	}
+	model.MarkerIDs = make([]string, genotype.NumMarkers())
+	for j, v := range genotype.Markers {
+		model.MarkerIDs[j] = v.ID
+	}
+
+	return model, nil
+}
+
+func (m *Model) Predict(genotype *genomics.Genotype) ([]float64, error) {
+	columns := make(map[string]int, genotype.NumMarkers())
+	for j, v := range genotype.Markers {
+		columns[v.ID] = j
+	}
+
+	matched := 0
+	centered := genomics.NewMatrix(genotype.NumSamples(), len(m.MarkerIDs))
+	for j, markerID := range m.MarkerIDs {
+		column, ok := columns[markerID]
+		if !ok {
+			continue
+		}
+		matched++
+		for i, row := range genotype.Dosages {
+			if dosage := row[column]; !math.IsNaN(dosage) {
+				centered[i][j] = dosage - 2*m.Frequencies[j]
+			}
+		}
+	}
+	if matched == 0 {
+		return nil, errors.New("genotype shares no markers with the model")
+	}
+
+	distances, err := SquaredDistances(context.Background(), centered, m.Training)
+	if err != nil {
+		return nil, err
+	}
+
+	predicted := genomics.MulVec(Kernel(distances, m.Gamma, m.Scale), m.Alpha)
+	for i := range predicted {
+		predicted[i] += m.Intercept
+	}
+	return predicted, nil
+}
@@ -0,0 +1,125 @@
+package rkhs
+
+import (
+	"context"
+	"math"
+	"testing"
+
+	"github.com/khoa5773/go-server/src/genomics"
+)
+
+func TestKernel(t *testing.T) {
+	points := [][]float64{{0, 0}, {1, 0}, {0, 2}, {3, 4}}
+	distances, err := SquaredDistances(context.Background(), points, points)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i, a := range points {
+		for j, b := range points {
+			want := (a[0]-b[0])*(a[0]-b[0]) + (a[1]-b[1])*(a[1]-b[1])
+			if math.Abs(distances[i][j]-want) > 1e-12 {
+				t.Errorf("d²[%d][%d] = %v, want %v", i, j, distances[i][j], want)
+			}
+		}
+	}
+
+	kernel := Kernel(distances, 0.5, 5)
+	for i := range kernel {
+		if kernel[i][i] != 1 {
+			t.Errorf("K[%d][%d] = %v, want 1", i, i, kernel[i][i])
+		}
+		for j := range kernel {
+			if kernel[i][j] != kernel[j][i] || kernel[i][j] <= 0 || kernel[i][j] > 1 {
+				t.Errorf("K[%d][%d] = %v is not a symmetric value in (0, 1]", i, j, kernel[i][j])
+			}
+		}
+	}
+	if want := math.Exp(-0.5 * 25 / 5); math.Abs(kernel[0][3]-want) > 1e-12 {
+		t.Errorf("K[0][3] = %v, want %v", kernel[0][3], want)
+	}
+	if kernel[0][1] <= kernel[0][2] || kernel[0][2] <= kernel[0][3] {
+		t.Errorf("kernel does not decrease with distance: %v", kernel[0])
+	}
+}
+
+func TestFitPredictsTrainingLines(t *testing.T) {
+	genotype := &genomics.Genotype{
+		SampleIDs: []string{"a", "b", "c", "d", "e", "f"},
+		Markers:   []genomics.Marker{{ID: "m1"}, {ID: "m2"}, {ID: "m3"}, {ID: "m4"}},
+		Dosages: [][]float64{
+			{0, 1, 2, 0},
+			{0, 1, 2, 1},
+			{2, 1, 0, 2},
+			{2, 2, 0, 2},
+			{1, 0, 1, 0},
+			{1, 2, 1, 1},
+		},
+	}
+	y := []float64{1, 1.2, 4, 4.3, 2.1, 2.8}
+
+	model, err := Method{}.Fit(context.Background(), genotype, y, genomics.Parameters{"default": AUTO})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	predicted, err := model.Predict(genotype)
+	if err != nil {
+		t.Fatal(err)
+	}
+	again, err := model.Predict(genotype.Subset([]int{2}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if math.Abs(again[0]-predicted[2]) > 1e-9 {
+		t.Errorf("a training line predicted alone gives %v, with the others %v", again[0], predicted[2])
+	}
+
+	low := (predicted[0] + predicted[1]) / 2
+	high := (predicted[2] + predicted[3]) / 2
+	if low >= high {
+		t.Errorf("predictions %v do not follow the phenotypes %v", predicted, y)
+	}
+}
+
+func TestManualGamma(t *testing.T) {
+	genotype := &genomics.Genotype{
+		SampleIDs: []string{"a", "b", "c", "d", "e"},
+		Markers:   []genomics.Marker{{ID: "m1"}, {ID: "m2"}, {ID: "m3"}},
+		Dosages:   [][]float64{{0, 0, 1}, {0, 1, 1}, {2, 2, 1}, {2, 1, 0}, {1, 1, 2}},
+	}
+	y := []float64{1, 1.5, 5, 4.5, 3}
+	candidate := &genomics.Genotype{
+		SampleIDs: []string{"new"},
+		Markers:   genotype.Markers,
+		Dosages:   [][]float64{{2, 2, 0}},
+	}
+
+	tests := []struct {
+		parameters genomics.Parameters
+		gamma      float64
+	}{
+		// the setting is read on a tenth of the median-distance scale
+		{genomics.Parameters{"default": ""}, 1},
+		{genomics.Parameters{"default": "", "gamma": 5}, 0.5},
+		{genomics.Parameters{"default": "", "gamma": 40, "heritability": 0.5}, 4},
+	}
+	for _, test := range tests {
+		model, err := Method{}.Fit(context.Background(), genotype, y, test.parameters)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if gamma := model.(*Model).Gamma; gamma != test.gamma {
+			t.Errorf("%v: gamma = %v, want %v", test.parameters, gamma, test.gamma)
+		}
+
+		predicted, err := model.Predict(candidate)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// a kernel close to the identity shrinks every new line to the mean
+		if predicted[0]-model.(*Model).Intercept < 0.5 {
+			t.Errorf("%v: a line close to the best ones is predicted %v, near the mean %v", test.parameters, predicted[0], model.(*Model).Intercept)
+		}
+	}
+}
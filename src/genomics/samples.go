@@ -0,0 +1,144 @@
+package genomics
+
+import (
+	"sort"
+	"strings"
+)
+
+// SampleAlias makes a sample ID compare as another one
+type SampleAlias struct {
+	From string `json:"from" bson:"from" binding:"required"`
+	To   string `json:"to" bson:"to" binding:"required"`
+}
+
+// SampleNormalization are the rules applied to sample IDs before genotype and phenotype samples are
+// compared. Surrounding spaces are trimmed and aliases applied first, then the case is folded, one
+// prefix and one suffix stripped and separators removed.
+type SampleNormalization struct {
+	IgnoreCase       bool          `json:"ignoreCase" bson:"ignoreCase"`
+	IgnoreSeparators bool          `json:"ignoreSeparators" bson:"ignoreSeparators"`
+	StripPrefixes    []string      `json:"stripPrefixes" bson:"stripPrefixes" binding:"dive,required"`
+	StripSuffixes    []string      `json:"stripSuffixes" bson:"stripSuffixes" binding:"dive,required"`
+	Aliases          []SampleAlias `json:"aliases" bson:"aliases" binding:"dive"`
+}
+
+// sampleSeparators are removed from sample IDs when separators are ignored
+var sampleSeparators = strings.NewReplacer("-", "", "_", "", ".", "", " ", "", ":", "", "/", "")
+
+// Normalize returns the key a sample ID is compared by
+func (n *SampleNormalization) Normalize(sampleID string) string {
+	key := strings.TrimSpace(sampleID)
+	if n == nil {
+		return key
+	}
+
+	for _, alias := range n.Aliases {
+		if alias.From == key {
+			key = strings.TrimSpace(alias.To)
+			break
+		}
+	}
+
+	if n.IgnoreCase {
+		key = strings.ToLower(key)
+	}
+	for _, prefix := range n.StripPrefixes {
+		if n.IgnoreCase {
+			prefix = strings.ToLower(prefix)
+		}
+		if strings.HasPrefix(key, prefix) && len(key) > len(prefix) {
+			key = key[len(prefix):]
+			break
+		}
+	}
+	for _, suffix := range n.StripSuffixes {
+		if n.IgnoreCase {
+			suffix = strings.ToLower(suffix)
+		}
+		if strings.HasSuffix(key, suffix) && len(key) > len(suffix) {
+			key = key[:len(key)-len(suffix)]
+			break
+		}
+	}
+	if n.IgnoreSeparators {
+		key = sampleSeparators.Replace(key)
+	}
+
+	return key
+}
+
+// MatchedSample pairs a genotype and a phenotype sample whose IDs normalize to the same key
+type MatchedSample struct {
+	Key         string `json:"key"`
+	GenotypeID  string `json:"genotypeID"`
+	PhenotypeID string `json:"phenotypeID"`
+}
+
+// AmbiguousSample is a key shared by several samples of one file, its samples are not matched
+type AmbiguousSample struct {
+	Key          string   `json:"key"`
+	GenotypeIDs  []string `json:"genotypeIDs"`
+	PhenotypeIDs []string `json:"phenotypeIDs"`
+}
+
+// SampleMatch compares the samples of a genotype and a phenotype, lists are sorted by key or ID
+type SampleMatch struct {
+	Matched       []MatchedSample   `json:"matched"`
+	GenotypeOnly  []string          `json:"genotypeOnly"`
+	PhenotypeOnly []string          `json:"phenotypeOnly"`
+	Ambiguous     []AmbiguousSample `json:"ambiguous"`
+}
+
+// MatchSamples pairs genotype and phenotype sample IDs by their normalized keys. Samples without a
+// counterpart are listed per file, and keys shared by several samples of the same file are reported as
+// ambiguous instead of being matched.
+func MatchSamples(genotypeIDs, phenotypeIDs []string, normalization *SampleNormalization) SampleMatch {
+	genotypeKeys := groupSamples(genotypeIDs, normalization)
+	phenotypeKeys := groupSamples(phenotypeIDs, normalization)
+
+	match := SampleMatch{
+		Matched:       []MatchedSample{},
+		GenotypeOnly:  []string{},
+		PhenotypeOnly: []string{},
+		Ambiguous:     []AmbiguousSample{},
+	}
+	for key, genotypeSamples := range genotypeKeys {
+		phenotypeSamples, ok := phenotypeKeys[key]
+		if !ok {
+			phenotypeSamples = []string{}
+		}
+		switch {
+		case len(genotypeSamples) > 1 || len(phenotypeSamples) > 1:
+			match.Ambiguous = append(match.Ambiguous, AmbiguousSample{Key: key, GenotypeIDs: genotypeSamples, PhenotypeIDs: phenotypeSamples})
+		case len(phenotypeSamples) == 1:
+			match.Matched = append(match.Matched, MatchedSample{Key: key, GenotypeID: genotypeSamples[0], PhenotypeID: phenotypeSamples[0]})
+		default:
+			match.GenotypeOnly = append(match.GenotypeOnly, genotypeSamples[0])
+		}
+	}
+	for key, phenotypeSamples := range phenotypeKeys {
+		if _, ok := genotypeKeys[key]; ok {
+			continue
+		}
+		if len(phenotypeSamples) > 1 {
+			match.Ambiguous = append(match.Ambiguous, AmbiguousSample{Key: key, GenotypeIDs: []string{}, PhenotypeIDs: phenotypeSamples})
+			continue
+		}
+		match.PhenotypeOnly = append(match.PhenotypeOnly, phenotypeSamples[0])
+	}
+
+	sort.Slice(match.Matched, func(i, j int) bool { return match.Matched[i].Key < match.Matched[j].Key })
+	sort.Strings(match.GenotypeOnly)
+	sort.Strings(match.PhenotypeOnly)
+	sort.Slice(match.Ambiguous, func(i, j int) bool { return match.Ambiguous[i].Key < match.Ambiguous[j].Key })
+	return match
+}
+
+func groupSamples(sampleIDs []string, normalization *SampleNormalization) map[string][]string {
+	keys := make(map[string][]string, len(sampleIDs))
+	for _, sampleID := range sampleIDs {
+		key := normalization.Normalize(sampleID)
+		keys[key] = append(keys[key], sampleID)
+	}
+	return keys
+}
@@ -0,0 +1,124 @@
+package selection
+
+import (
+	"errors"
+	"math"
+	"sort"
+)
+
+// Trait is one component of the selection index, Values are keyed by sample ID
+type Trait struct {
+	Values map[string]float64
+	Weight float64
+}
+
+type Candidate struct {
+	Rank     int       `json:"rank" bson:"rank"`
+	SampleID string    `json:"sampleID" bson:"sampleID"`
+	Family   string    `json:"family" bson:"family"`
+	Index    float64   `json:"index" bson:"index"`
+	Values   []float64 `json:"values" bson:"values"`
+}
+
+// Settings select either Count candidates or the top Percent of the ranked samples
+type Settings struct {
+	Count        int
+	Percent      float64
+	MaxPerFamily int
+	Families     map[string]string
+}
+
+// Rank computes the weighted index of the samples present in every trait and sorts them from best to worst.
+// With standardize, trait values are converted to z-scores first so that weights are relative importances
+// instead of economic values per unit.
+func Rank(traits []Trait, standardize bool) ([]Candidate, error) {
+	if len(traits) == 0 {
+		return nil, errors.New("at least one trait is required")
+	}
+
+	var sampleIDs []string
+	for sampleID := range traits[0].Values {
+		shared := true
+		for _, trait := range traits[1:] {
+			if _, ok := trait.Values[sampleID]; !ok {
+				shared = false
+				break
+			}
+		}
+		if shared {
+			sampleIDs = append(sampleIDs, sampleID)
+		}
+	}
+	if len(sampleIDs) == 0 {
+		return nil, errors.New("traits share no samples")
+	}
+
+	means := make([]float64, len(traits))
+	scales := make([]float64, len(traits))
+	for t, trait := range traits {
+		scales[t] = 1
+		if !standardize {
+			continue
+		}
+
+		n := float64(len(sampleIDs))
+		for _, sampleID := range sampleIDs {
+			means[t] += trait.Values[sampleID] / n
+		}
+		variance := 0.0
+		for _, sampleID := range sampleIDs {
+			variance += math.Pow(trait.Values[sampleID]-means[t], 2) / n
+		}
+		if variance > 0 {
+			scales[t] = math.Sqrt(variance)
+		}
+	}
+
+	candidates := make([]Candidate, len(sampleIDs))
+	for i, sampleID := range sampleIDs {
+		candidate := Candidate{SampleID: sampleID, Values: make([]float64, len(traits))}
+		for t, trait := range traits {
+			value := trait.Values[sampleID]
+			candidate.Values[t] = value
+			candidate.Index += trait.Weight * (value - means[t]) / scales[t]
+		}
+		candidates[i] = candidate
+	}
+
+	sort.Slice(candidates, func(i, j int) bool {
+		if candidates[i].Index != candidates[j].Index {
+			return candidates[i].Index > candidates[j].Index
+		}
+		return candidates[i].SampleID < candidates[j].SampleID
+	})
+	return candidates, nil
+}
+
+// Truncate keeps the best ranked candidates, skipping those whose family already reached MaxPerFamily.
+// Samples without a family are not constrained.
+func Truncate(ranked []Candidate, settings Settings) []Candidate {
+	count := settings.Count
+	if settings.Percent > 0 {
+		count = int(math.Ceil(settings.Percent / 100 * float64(len(ranked))))
+	}
+
+	perFamily := map[string]int{}
+	selected := make([]Candidate, 0, count)
+	for _, candidate := range ranked {
+		if len(selected) >= count {
+			break
+		}
+
+		candidate.Family = settings.Families[candidate.SampleID]
+		if candidate.Family != "" && settings.MaxPerFamily > 0 {
+			if perFamily[candidate.Family] >= settings.MaxPerFamily {
+				continue
+			}
+			perFamily[candidate.Family]++
+		}
+
+		candidate.Rank = len(selected) + 1
+		selected = append(selected, candidate)
+	}
+	return selected
+}
@@ -0,0 +1,163 @@
+package selection
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+func sampleIDs(candidates []Candidate) []string {
+	ids := make([]string, len(candidates))
+	for i, candidate := range candidates {
+		ids[i] = candidate.SampleID
+	}
+	return ids
+}
+
+func TestRank(t *testing.T) {
+	yield := map[string]float64{"a": 10, "b": 12, "c": 8, "d": 11}
+	height := map[string]float64{"a": 100, "b": 95, "c": 110, "d": 85}
+
+	tests := []struct {
+		name        string
+		traits      []Trait
+		standardize bool
+		want        []string
+		index       []float64
+	}{
+		{
+			name:   "single trait",
+			traits: []Trait{{Values: yield, Weight: 1}},
+			want:   []string{"b", "d", "a", "c"},
+			index:  []float64{12, 11, 10, 8},
+		},
+		{
+			// per unit, height outweighs yield
+			name:   "economic weights",
+			traits: []Trait{{Values: yield, Weight: 1}, {Values: height, Weight: 1}},
+			want:   []string{"c", "a", "b", "d"},
+			index:  []float64{118, 110, 107, 96},
+		},
+		{
+			// as z-scores, the yield of b makes up for its height
+			name:        "standardized",
+			traits:      []Trait{{Values: yield, Weight: 1}, {Values: height, Weight: 1}},
+			standardize: true,
+			want:        []string{"b", "a", "c", "d"},
+		},
+		{
+			name:   "zero weight",
+			traits: []Trait{{Values: yield, Weight: 1}, {Values: height, Weight: 0}},
+			want:   []string{"b", "d", "a", "c"},
+			index:  []float64{12, 11, 10, 8},
+		},
+		{
+			name:   "negative weight",
+			traits: []Trait{{Values: height, Weight: -1}},
+			want:   []string{"d", "b", "a", "c"},
+			index:  []float64{-85, -95, -100, -110},
+		},
+		{
+			// only the samples of every trait are ranked, ties are broken by sample ID
+			name:   "shared samples and ties",
+			traits: []Trait{{Values: map[string]float64{"x": 1, "y": 2, "z": 2}, Weight: 1}, {Values: map[string]float64{"y": 0, "z": 0, "w": 5}, Weight: 1}},
+			want:   []string{"y", "z"},
+			index:  []float64{2, 2},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			candidates, err := Rank(test.traits, test.standardize)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := sampleIDs(candidates); !reflect.DeepEqual(got, test.want) {
+				t.Fatalf("order %v, want %v", got, test.want)
+			}
+			for i, index := range test.index {
+				if math.Abs(candidates[i].Index-index) > 1e-9 {
+					t.Errorf("index of %s = %v, want %v", candidates[i].SampleID, candidates[i].Index, index)
+				}
+			}
+			for _, candidate := range candidates {
+				if len(candidate.Values) != len(test.traits) {
+					t.Errorf("%s has %d values, want %d", candidate.SampleID, len(candidate.Values), len(test.traits))
+				}
+			}
+		})
+	}
+}
+
+func TestRankStandardizedIndex(t *testing.T) {
+	// z-scores of 1, 2, 3 are -1.22, 0 and 1.22
+	candidates, err := Rank([]Trait{{Values: map[string]float64{"a": 1, "b": 2, "c": 3}, Weight: 2}}, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := 2 * math.Sqrt(1.5)
+	if math.Abs(candidates[0].Index-want) > 1e-9 || math.Abs(candidates[1].Index) > 1e-9 || math.Abs(candidates[2].Index+want) > 1e-9 {
+		t.Errorf("indices %v, %v, %v, want %v, 0, %v", candidates[0].Index, candidates[1].Index, candidates[2].Index, want, -want)
+	}
+	// the values are reported as predicted
+	if !reflect.DeepEqual(candidates[0].Values, []float64{3}) {
+		t.Errorf("values %v, want [3]", candidates[0].Values)
+	}
+}
+
+func TestRankErrors(t *testing.T) {
+	if _, err := Rank(nil, false); err == nil {
+		t.Error("no error without traits")
+	}
+	_, err := Rank([]Trait{{Values: map[string]float64{"a": 1}, Weight: 1}, {Values: map[string]float64{"b": 1}, Weight: 1}}, false)
+	if err == nil {
+		t.Error("no error for traits without shared samples")
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	var ranked []Candidate
+	for _, sampleID := range []string{"a", "b", "c", "d", "e", "f", "g"} {
+		ranked = append(ranked, Candidate{SampleID: sampleID})
+	}
+	families := map[string]string{"a": "F1", "b": "F1", "c": "F1", "d": "F2", "e": "F2"}
+
+	tests := []struct {
+		name     string
+		settings Settings
+		want     []string
+	}{
+		{name: "count", settings: Settings{Count: 3}, want: []string{"a", "b", "c"}},
+		{name: "count above the ranked samples", settings: Settings{Count: 10}, want: []string{"a", "b", "c", "d", "e", "f", "g"}},
+		// 30% of 7 samples is rounded up
+		{name: "percent", settings: Settings{Percent: 30}, want: []string{"a", "b", "c"}},
+		{name: "percent over count", settings: Settings{Count: 1, Percent: 100}, want: []string{"a", "b", "c", "d", "e", "f", "g"}},
+		{name: "max per family", settings: Settings{Count: 4, MaxPerFamily: 1, Families: families}, want: []string{"a", "d", "f", "g"}},
+		{name: "max per family of two", settings: Settings{Count: 5, MaxPerFamily: 2, Families: families}, want: []string{"a", "b", "d", "e", "f"}},
+		// families are reported but do not constrain without MaxPerFamily
+		{name: "families without max", settings: Settings{Count: 3, Families: families}, want: []string{"a", "b", "c"}},
+		{name: "family limit leaves fewer", settings: Settings{Count: 4, MaxPerFamily: 1, Families: map[string]string{"a": "F", "b": "F", "c": "F", "d": "F", "e": "F", "f": "F", "g": "F"}}, want: []string{"a"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			selected := Truncate(ranked, test.settings)
+			if got := sampleIDs(selected); !reflect.DeepEqual(got, test.want) {
+				t.Fatalf("selected %v, want %v", got, test.want)
+			}
+			for i, candidate := range selected {
+				if candidate.Rank != i+1 {
+					t.Errorf("%s has rank %d, want %d", candidate.SampleID, candidate.Rank, i+1)
+				}
+				if candidate.Family != test.settings.Families[candidate.SampleID] {
+					t.Errorf("%s has family %q, want %q", candidate.SampleID, candidate.Family, test.settings.Families[candidate.SampleID])
+				}
+			}
+		})
+	}
+
+	// the ranked candidates are not modified
+	if ranked[0].Rank != 0 || ranked[0].Family != "" {
+		t.Errorf("ranked candidate changed to %+v", ranked[0])
+	}
+}
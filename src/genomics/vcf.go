@@ -0,0 +1,512 @@
+package genomics
+
+import (
+	"bufio"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+var vcfColumns = []string{"#CHROM", "POS", "ID", "REF", "ALT", "QUAL", "FILTER", "INFO"}
+
+// ChromosomeSummary counts the variants of one chromosome
+type ChromosomeSummary struct {
+	Name          string `json:"name" bson:"name"`
+	Variants      int    `json:"variants" bson:"variants"`
+	FirstPosition int64  `json:"firstPosition" bson:"firstPosition"`
+	LastPosition  int64  `json:"lastPosition" bson:"lastPosition"`
+}
+
+// VariantSummary describes the content of a genotype file
+type VariantSummary struct {
+	FileFormat   string              `json:"fileFormat" bson:"fileFormat"`
+	Samples      int                 `json:"samples" bson:"samples"`
+	Variants     int                 `json:"variants" bson:"variants"`
+	SNPs         int                 `json:"snps" bson:"snps"`
+	Indels       int                 `json:"indels" bson:"indels"`
+	Multiallelic int                 `json:"multiallelic" bson:"multiallelic"`
+	MissingRate  float64             `json:"missingRate" bson:"missingRate"`
+	Chromosomes  []ChromosomeSummary `json:"chromosomes" bson:"chromosomes"`
+	Coding       GenotypeCoding      `json:"coding,omitempty" bson:"coding,omitempty"`
+}
+
+type vcfVariant struct {
+	marker  Marker
+	dosages []float64
+}
+
+// IsVCF tells whether a file with the given extension is read as VCF
+func IsVCF(mimeType string) bool {
+	switch strings.ToLower(mimeType) {
+	case "vcf", "vcf.gz", "vcf.bgz":
+		return true
+	}
+	return false
+}
+
+// ValidateVCF reads a plain or bgzipped VCF file to the end and returns its sample IDs and a summary of
+// its variants. Errors name the line they were found on.
+func ValidateVCF(r io.Reader) ([]string, *VariantSummary, error) {
+	return scanVCF(r, nil)
+}
+
+// ReadVCF reads the genotype calls of a plain or bgzipped VCF file as alternate allele dosages
+func ReadVCF(r io.Reader) (*Genotype, error) {
+	var variants []vcfVariant
+	sampleIDs, _, err := scanVCF(r, func(variant vcfVariant) {
+		variants = append(variants, variant)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(sampleIDs) == 0 {
+		return nil, errors.New("genotype contains no samples")
+	}
+
+	genotype := &Genotype{
+		SampleIDs: sampleIDs,
+		Markers:   make([]Marker, len(variants)),
+		Dosages:   make([][]float64, len(sampleIDs)),
+	}
+	for i := range genotype.Dosages {
+		genotype.Dosages[i] = make([]float64, len(variants))
+	}
+	for j, variant := range variants {
+		genotype.Markers[j] = variant.marker
+		for i, dosage := range variant.dosages {
+			genotype.Dosages[i][j] = dosage
+		}
+	}
+
+	return genotype, nil
+}
+
+// decompress unwraps gzip content, which includes BGZF as it is a series of gzip members
+func decompress(r io.Reader) (io.Reader, error) {
+	buffered := bufio.NewReader(r)
+	magic, err := buffered.Peek(2)
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	if len(magic) == 2 && magic[0] == 0x1f && magic[1] == 0x8b {
+		return gzip.NewReader(buffered)
+	}
+	return buffered, nil
+}
+
+func scanVCF(r io.Reader, visit func(variant vcfVariant)) ([]string, *VariantSummary, error) {
+	content, err := decompress(r)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid compressed file: %v", err)
+	}
+
+	scanner := bufio.NewScanner(content)
+	scanner.Buffer(make([]byte, 1024*1024), 1024*1024*1024)
+
+	summary := &VariantSummary{Chromosomes: []ChromosomeSummary{}}
+	chromosomes := map[string]int{}
+	var sampleIDs []string
+	headerRead := false
+	calls, missingCalls := 0, 0
+
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimRight(scanner.Text(), "\r")
+
+		if lineNumber == 1 {
+			if !strings.HasPrefix(line, "##fileformat=VCFv4") {
+				return nil, nil, errors.New("line 1: expected ##fileformat=VCFv4.x, the file is not a VCF file")
+			}
+			summary.FileFormat = strings.TrimPrefix(line, "##fileformat=")
+			continue
+		}
+
+		if strings.HasPrefix(line, "##") {
+			if headerRead {
+				return nil, nil, fmt.Errorf("line %d: meta-information line after the header line", lineNumber)
+			}
+			continue
+		}
+
+		if strings.HasPrefix(line, "#") {
+			if headerRead {
+				return nil, nil, fmt.Errorf("line %d: header line repeated", lineNumber)
+			}
+			sampleIDs, err = parseVCFHeader(line)
+			if err != nil {
+				return nil, nil, fmt.Errorf("line %d: %v", lineNumber, err)
+			}
+			headerRead = true
+			continue
+		}
+
+		if !headerRead {
+			return nil, nil, fmt.Errorf("line %d: data line before the #CHROM header line", lineNumber)
+		}
+		if line == "" {
+			return nil, nil, fmt.Errorf("line %d: empty line", lineNumber)
+		}
+
+		variant, alleles, err := parseVCFLine(line, len(sampleIDs))
+		if err != nil {
+			return nil, nil, fmt.Errorf("line %d: %v", lineNumber, err)
+		}
+
+		summary.Variants++
+		switch {
+		case len(alleles) > 2:
+			summary.Multiallelic++
+		case len(alleles) == 2 && isBases(alleles[1]) && len(alleles[0]) == 1 && len(alleles[1]) == 1:
+			summary.SNPs++
+		case len(alleles) == 2 && isBases(alleles[1]):
+			summary.Indels++
+		}
+
+		index, ok := chromosomes[variant.marker.Chromosome]
+		if !ok {
+			index = len(summary.Chromosomes)
+			chromosomes[variant.marker.Chromosome] = index
+			summary.Chromosomes = append(summary.Chromosomes, ChromosomeSummary{
+				Name:          variant.marker.Chromosome,
+				FirstPosition: variant.marker.Position,
+			})
+		}
+		chromosome := &summary.Chromosomes[index]
+		chromosome.Variants++
+		if variant.marker.Position < chromosome.FirstPosition {
+			chromosome.FirstPosition = variant.marker.Position
+		}
+		if variant.marker.Position > chromosome.LastPosition {
+			chromosome.LastPosition = variant.marker.Position
+		}
+
+		for _, dosage := range variant.dosages {
+			calls++
+			if math.IsNaN(dosage) {
+				missingCalls++
+			}
+		}
+
+		if visit != nil {
+			visit(variant)
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, nil, fmt.Errorf("line %d: %v", lineNumber+1, err)
+	}
+	if lineNumber == 0 {
+		return nil, nil, errors.New("file is empty")
+	}
+	if !headerRead {
+		return nil, nil, errors.New("the #CHROM header line is missing")
+	}
+	if summary.Variants == 0 {
+		return nil, nil, errors.New("file contains no variants")
+	}
+
+	summary.Samples = len(sampleIDs)
+	if calls > 0 {
+		summary.MissingRate = float64(missingCalls) / float64(calls)
+	}
+
+	return sampleIDs, summary, nil
+}
+
+func parseVCFHeader(line string) ([]string, error) {
+	columns := strings.Split(line, "\t")
+	if len(columns) < len(vcfColumns) {
+		return nil, fmt.Errorf("header line must be tab separated and start with %s", strings.Join(vcfColumns, " "))
+	}
+	for i, name := range vcfColumns {
+		if columns[i] != name {
+			return nil, fmt.Errorf("header column %d is %q, expected %s", i+1, columns[i], name)
+		}
+	}
+
+	if len(columns) == len(vcfColumns) {
+		return []string{}, nil
+	}
+	if columns[len(vcfColumns)] != "FORMAT" {
+		return nil, fmt.Errorf("header column %d is %q, expected FORMAT", len(vcfColumns)+1, columns[len(vcfColumns)])
+	}
+
+	sampleIDs := columns[len(vcfColumns)+1:]
+	if len(sampleIDs) == 0 {
+		return nil, errors.New("FORMAT column without samples")
+	}
+	seen := make(map[string]bool, len(sampleIDs))
+	for _, sampleID := range sampleIDs {
+		if sampleID == "" {
+			return nil, errors.New("empty sample ID")
+		}
+		if seen[sampleID] {
+			return nil, fmt.Errorf("duplicate sample ID %s", sampleID)
+		}
+		seen[sampleID] = true
+	}
+
+	return sampleIDs, nil
+}
+
+// parseVCFLine checks the fixed columns and reads the genotype calls, it returns the REF and ALT alleles
+func parseVCFLine(line string, samples int) (vcfVariant, []string, error) {
+	fields := strings.Split(line, "\t")
+	expected := len(vcfColumns)
+	if samples > 0 {
+		expected += samples + 1
+	}
+	if len(fields) != expected {
+		return vcfVariant{}, nil, fmt.Errorf("expected %d tab separated columns, got %d", expected, len(fields))
+	}
+
+	chromosome := fields[0]
+	if chromosome == "" || strings.ContainsAny(chromosome, " :") {
+		return vcfVariant{}, nil, fmt.Errorf("invalid CHROM %q", chromosome)
+	}
+
+	position, err := strconv.ParseInt(fields[1], 10, 64)
+	if err != nil || position < 0 {
+		return vcfVariant{}, nil, fmt.Errorf("invalid POS %q", fields[1])
+	}
+
+	reference := fields[3]
+	if !isBases(reference) {
+		return vcfVariant{}, nil, fmt.Errorf("invalid REF %q", reference)
+	}
+
+	alleles := []string{reference}
+	if fields[4] != "." {
+		for _, allele := range strings.Split(fields[4], ",") {
+			if allele == "" || strings.Contains(allele, " ") {
+				return vcfVariant{}, nil, fmt.Errorf("invalid ALT %q", fields[4])
+			}
+			alleles = append(alleles, allele)
+		}
+	}
+
+	if fields[5] != "." {
+		_, err = strconv.ParseFloat(fields[5], 64)
+		if err != nil {
+			return vcfVariant{}, nil, fmt.Errorf("invalid QUAL %q", fields[5])
+		}
+	}
+
+	for _, column := range []int{2, 6, 7} {
+		if fields[column] == "" {
+			return vcfVariant{}, nil, fmt.Errorf("empty %s, use . for a missing value", strings.TrimPrefix(vcfColumns[column], "#"))
+		}
+	}
+
+	id := fields[2]
+	if id == "." {
+		id = chromosome + ":" + fields[1]
+	}
+	variant := vcfVariant{marker: Marker{
+		ID:         id,
+		Chromosome: chromosome,
+		Position:   position,
+		Reference:  reference,
+		Alternate:  strings.Join(alleles[1:], ","),
+	}}
+	if samples == 0 {
+		return variant, alleles, nil
+	}
+
+	format := strings.Split(fields[8], ":")
+	for i, key := range format {
+		if key == "" {
+			return vcfVariant{}, nil, fmt.Errorf("invalid FORMAT %q", fields[8])
+		}
+		if key == "GT" && i != 0 {
+			return vcfVariant{}, nil, errors.New("GT must be the first FORMAT field")
+		}
+	}
+	hasGenotype := format[0] == "GT"
+
+	variant.dosages = make([]float64, samples)
+	for i, value := range fields[len(vcfColumns)+1:] {
+		values := strings.Split(value, ":")
+		if len(values) > len(format) {
+			return vcfVariant{}, nil, fmt.Errorf("sample column %d has more values than FORMAT %s", i+1, fields[8])
+		}
+
+		variant.dosages[i] = math.NaN()
+		if !hasGenotype {
+			continue
+		}
+		variant.dosages[i], err = parseGenotypeCall(values[0], len(alleles)-1)
+		if err != nil {
+			return vcfVariant{}, nil, fmt.Errorf("sample column %d: %v", i+1, err)
+		}
+	}
+
+	return variant, alleles, nil
+}
+
+// parseGenotypeCall returns the number of alternate alleles of a GT value scaled to a diploid call,
+// NaN when an allele is missing
+func parseGenotypeCall(value string, alternates int) (float64, error) {
+	if value == "" {
+		return 0, errors.New("empty GT")
+	}
+
+	alleles := strings.FieldsFunc(value, func(r rune) bool { return r == '/' || r == '|' })
+	if len(alleles) == 0 || strings.HasPrefix(value, "/") || strings.HasPrefix(value, "|") {
+		return 0, fmt.Errorf("invalid GT %q", value)
+	}
+
+	missing := false
+	nonReference := 0
+	for _, allele := range alleles {
+		if allele == "." {
+			missing = true
+			continue
+		}
+		index, err := strconv.Atoi(allele)
+		if err != nil || index < 0 {
+			return 0, fmt.Errorf("invalid GT %q", value)
+		}
+		if index > alternates {
+			return 0, fmt.Errorf("GT %q refers to allele %d but only %d ALT alleles are listed", value, index, alternates)
+		}
+		if index > 0 {
+			nonReference++
+		}
+	}
+
+	if missing {
+		return math.NaN(), nil
+	}
+	return float64(nonReference) * 2 / float64(len(alleles)), nil
+}
+
+// CanWriteVCF tells whether WriteVCF loses nothing of a genotype read from a HapMap or PLINK file: every
+// marker has a chromosome and a single nucleotide ALT allele and every dosage is a diploid call. A
+// genotype read from VCF is subset with FilterVCF instead, its ploidy, phasing, QUAL, FILTER and INFO are
+// not kept in the dosages.
+func CanWriteVCF(genotype *Genotype) bool {
+	for _, marker := range genotype.Markers {
+		if marker.Chromosome == "" || strings.ContainsAny(marker.Chromosome, " :\t") ||
+			!isBases(marker.Reference) || !isBases(marker.Alternate) {
+			return false
+		}
+	}
+	for _, row := range genotype.Dosages {
+		for _, dosage := range row {
+			if !math.IsNaN(dosage) && dosage != 0 && dosage != 1 && dosage != 2 {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// WriteVCF writes a genotype as unphased diploid GT calls, a dosage of 2 is homozygous for the ALT allele
+func WriteVCF(w io.Writer, genotype *Genotype) error {
+	calls := map[float64]string{0: "0/0", 1: "0/1", 2: "1/1"}
+	buffered := bufio.NewWriter(w)
+
+	fmt.Fprintln(buffered, "##fileformat=VCFv4.2")
+	fmt.Fprintln(buffered, `##FORMAT=<ID=GT,Number=1,Type=String,Description="Genotype">`)
+	header := append(append([]string{}, vcfColumns...), "FORMAT")
+	fmt.Fprintln(buffered, strings.Join(append(header, genotype.SampleIDs...), "\t"))
+	for j, marker := range genotype.Markers {
+		fmt.Fprintf(buffered, "%s\t%d\t%s\t%s\t%s\t.\t.\t.\tGT", marker.Chromosome, marker.Position, marker.ID, marker.Reference, marker.Alternate)
+		for _, row := range genotype.Dosages {
+			call, ok := calls[row[j]]
+			if !ok {
+				call = "./."
+			}
+			buffered.WriteString("\t" + call)
+		}
+		buffered.WriteString("\n")
+	}
+
+	return buffered.Flush()
+}
+
+// FilterVCF copies a plain or bgzipped VCF file as plain VCF with only the given sample columns and
+// data lines, both counted from 0 in file order as ReadVCF reads them. The meta-information lines and
+// the fixed and FORMAT columns of the kept lines are written as they are.
+func FilterVCF(w io.Writer, r io.Reader, samples []int, markers []int) error {
+	content, err := decompress(r)
+	if err != nil {
+		return fmt.Errorf("invalid compressed file: %v", err)
+	}
+
+	scanner := bufio.NewScanner(content)
+	scanner.Buffer(make([]byte, 1024*1024), 1024*1024*1024)
+	buffered := bufio.NewWriter(w)
+
+	fixed := len(vcfColumns) + 1
+	writeColumns := func(fields []string) {
+		buffered.WriteString(strings.Join(fields[:fixed], "\t"))
+		for _, i := range samples {
+			buffered.WriteString("\t" + fields[fixed+i])
+		}
+		buffered.WriteString("\n")
+	}
+
+	columns, variant, next := 0, 0, 0
+	lineNumber := 0
+	for next < len(markers) && scanner.Scan() {
+		lineNumber++
+		line := strings.TrimRight(scanner.Text(), "\r")
+
+		if strings.HasPrefix(line, "##") {
+			buffered.WriteString(line + "\n")
+			continue
+		}
+
+		fields := strings.Split(line, "\t")
+		if columns == 0 {
+			columns = len(fields)
+			for _, i := range samples {
+				if fixed+i >= columns {
+					return fmt.Errorf("line %d: no column for sample %d", lineNumber, i+1)
+				}
+			}
+			writeColumns(fields)
+			continue
+		}
+		if len(fields) != columns {
+			return fmt.Errorf("line %d: expected %d tab separated columns, got %d", lineNumber, columns, len(fields))
+		}
+
+		if variant == markers[next] {
+			writeColumns(fields)
+			next++
+		}
+		variant++
+	}
+
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("line %d: %v", lineNumber+1, err)
+	}
+	if next < len(markers) {
+		return fmt.Errorf("the file has %d variants, variant %d is missing", variant, markers[next]+1)
+	}
+
+	return buffered.Flush()
+}
+
+func isBases(allele string) bool {
+	if allele == "" {
+		return false
+	}
+	for _, r := range allele {
+		switch r {
+		case 'A', 'C', 'G', 'T', 'N', 'a', 'c', 'g', 't', 'n':
+		default:
+			return false
+		}
+	}
+	return true
+}
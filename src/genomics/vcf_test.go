@@ -0,0 +1,187 @@
+package genomics
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestValidateVCF(t *testing.T) {
+	sampleIDs, summary, err := ValidateVCF(openFixture(t, "sample.vcf"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(sampleIDs, []string{"S1", "S2", "S3"}) {
+		t.Errorf("sample IDs %v", sampleIDs)
+	}
+	want := &VariantSummary{
+		FileFormat:   "VCFv4.2",
+		Samples:      3,
+		Variants:     3,
+		SNPs:         1,
+		Indels:       1,
+		Multiallelic: 1,
+		MissingRate:  1.0 / 9,
+		Chromosomes: []ChromosomeSummary{
+			{Name: "1", Variants: 2, FirstPosition: 100, LastPosition: 200},
+			{Name: "2", Variants: 1, FirstPosition: 50, LastPosition: 50},
+		},
+	}
+	if !reflect.DeepEqual(summary, want) {
+		t.Errorf("summary %+v, want %+v", summary, want)
+	}
+}
+
+func TestReadVCF(t *testing.T) {
+	var compressed bytes.Buffer
+	writer := gzip.NewWriter(&compressed)
+	writer.Write(readFixture(t, "sample.vcf"))
+	writer.Close()
+
+	for name, content := range map[string][]byte{"plain": readFixture(t, "sample.vcf"), "gzipped": compressed.Bytes()} {
+		t.Run(name, func(t *testing.T) {
+			genotype, err := ReadVCF(bytes.NewReader(content))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			wantMarkers := []Marker{
+				{ID: "rs1", Chromosome: "1", Position: 100, Reference: "A", Alternate: "G"},
+				{ID: "1:200", Chromosome: "1", Position: 200, Reference: "C", Alternate: "T,G"},
+				{ID: "rs3", Chromosome: "2", Position: 50, Reference: "AT", Alternate: "A"},
+			}
+			if !reflect.DeepEqual(genotype.Markers, wantMarkers) {
+				t.Errorf("markers %+v", genotype.Markers)
+			}
+			// a call of any ALT allele counts, haploid calls are scaled to diploid
+			checkDosages(t, genotype, [][]float64{
+				{0, 1, 0},
+				{1, math.NaN(), 2},
+				{2, 2, 1},
+			})
+		})
+	}
+}
+
+func TestVCFErrors(t *testing.T) {
+	const meta = "##fileformat=VCFv4.2\n"
+	const header = "#CHROM\tPOS\tID\tREF\tALT\tQUAL\tFILTER\tINFO\tFORMAT\tS1\tS2\n"
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{name: "empty", content: "", want: "file is empty"},
+		{name: "not a VCF", content: "sample,m1\n", want: "line 1: expected ##fileformat=VCFv4.x"},
+		{name: "no header", content: meta, want: "the #CHROM header line is missing"},
+		{name: "no variants", content: meta + header, want: "file contains no variants"},
+		{name: "data before header", content: meta + "1\t1\t.\tA\tG\t.\t.\t.\tGT\t0/0\t0/1\n", want: "line 2: data line before the #CHROM header line"},
+		{name: "meta after header", content: meta + header + "##INFO=<ID=AF>\n", want: "line 3: meta-information line after the header line"},
+		{name: "header repeated", content: meta + header + header, want: "line 3: header line repeated"},
+		{name: "header column", content: meta + "#CHROM\tPOS\tID\tREF\tALT\tQUAL\tFILTER\tINFO\tSAMPLES\tS1\n", want: `line 2: header column 9 is "SAMPLES", expected FORMAT`},
+		{name: "duplicate sample", content: meta + "#CHROM\tPOS\tID\tREF\tALT\tQUAL\tFILTER\tINFO\tFORMAT\tS1\tS1\n", want: "line 2: duplicate sample ID S1"},
+		{name: "empty line", content: meta + header + "\n", want: "line 3: empty line"},
+		{name: "columns", content: meta + header + "1\t1\t.\tA\tG\t.\t.\t.\tGT\t0/0\n", want: "line 3: expected 11 tab separated columns, got 10"},
+		{name: "position", content: meta + header + "1\tx\t.\tA\tG\t.\t.\t.\tGT\t0/0\t0/1\n", want: `line 3: invalid POS "x"`},
+		{name: "reference", content: meta + header + "1\t1\t.\tN-\tG\t.\t.\t.\tGT\t0/0\t0/1\n", want: `line 3: invalid REF "N-"`},
+		{name: "quality", content: meta + header + "1\t1\t.\tA\tG\thigh\t.\t.\tGT\t0/0\t0/1\n", want: `line 3: invalid QUAL "high"`},
+		{name: "GT position", content: meta + header + "1\t1\t.\tA\tG\t.\t.\t.\tDP:GT\t3:0/0\t3:0/1\n", want: "line 3: GT must be the first FORMAT field"},
+		{name: "call", content: meta + header + "1\t1\t.\tA\tG\t.\t.\t.\tGT\t0/0\t0/x\n", want: `line 3: sample column 2: invalid GT "0/x"`},
+		{name: "allele index", content: meta + header + "1\t1\t.\tA\tG\t.\t.\t.\tGT\t0/0\t0/2\n", want: `line 3: sample column 2: GT "0/2" refers to allele 2 but only 1 ALT alleles are listed`},
+		{name: "compressed", content: "\x1f\x8b not gzip", want: "invalid compressed file"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, _, err := ValidateVCF(strings.NewReader(test.content))
+			checkError(t, err, test.want)
+		})
+	}
+}
+
+func TestWriteVCF(t *testing.T) {
+	genotype, err := ReadGenotype(openFixture(t, "nucleotide.hmp.txt"), "hmp.txt", NUCLEOTIDE)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !CanWriteVCF(genotype) {
+		t.Fatal("a biallelic HapMap genotype can not be written as VCF")
+	}
+
+	var written bytes.Buffer
+	if err := WriteVCF(&written, genotype); err != nil {
+		t.Fatal(err)
+	}
+	read, err := ReadVCF(&written)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(read.SampleIDs, genotype.SampleIDs) || !reflect.DeepEqual(read.Markers, genotype.Markers) {
+		t.Errorf("read back %v %+v", read.SampleIDs, read.Markers)
+	}
+	checkDosages(t, read, genotype.Dosages)
+
+	// multiallelic and haploid calls do not survive the dosages
+	vcf, err := ReadVCF(openFixture(t, "sample.vcf"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if CanWriteVCF(vcf) {
+		t.Error("a multiallelic VCF genotype can be written as VCF")
+	}
+}
+
+func TestFilterVCF(t *testing.T) {
+	var written bytes.Buffer
+	if err := FilterVCF(&written, openFixture(t, "sample.vcf"), []int{0, 2}, []int{1, 2}); err != nil {
+		t.Fatal(err)
+	}
+	want := strings.Join([]string{
+		"##fileformat=VCFv4.2",
+		"##source=fixture",
+		`##FORMAT=<ID=GT,Number=1,Type=String,Description="Genotype">`,
+		`##FORMAT=<ID=DP,Number=1,Type=Integer,Description="Read depth">`,
+		"#CHROM\tPOS\tID\tREF\tALT\tQUAL\tFILTER\tINFO\tFORMAT\tS1\tS3",
+		"1\t200\t.\tC\tT,G\t.\tPASS\tAF=0.5\tGT\t0|2\t1|1",
+		"2\t50\trs3\tAT\tA\t.\t.\t.\tGT\t0\t0/1",
+	}, "\n") + "\n"
+	if written.String() != want {
+		t.Errorf("wrote\n%s\nwant\n%s", written.String(), want)
+	}
+
+	// a bgzipped source is written as plain VCF
+	var compressed, unzipped bytes.Buffer
+	writer := gzip.NewWriter(&compressed)
+	writer.Write(readFixture(t, "sample.vcf"))
+	writer.Close()
+	if err := FilterVCF(&unzipped, &compressed, []int{0, 2}, []int{1, 2}); err != nil {
+		t.Fatal(err)
+	}
+	if unzipped.String() != want {
+		t.Errorf("wrote from gzip\n%s", unzipped.String())
+	}
+
+	// the output reads as the kept part of the source
+	source, err := ReadVCF(openFixture(t, "sample.vcf"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	read, err := ReadVCF(&written)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(read.SampleIDs, []string{"S1", "S3"}) || !reflect.DeepEqual(read.Markers, source.Markers[1:]) {
+		t.Errorf("read back %v %+v", read.SampleIDs, read.Markers)
+	}
+	checkDosages(t, read, [][]float64{source.Dosages[0][1:], source.Dosages[2][1:]})
+
+	err = FilterVCF(ioutil.Discard, openFixture(t, "sample.vcf"), []int{0}, []int{3})
+	checkError(t, err, "the file has 3 variants, variant 4 is missing")
+	err = FilterVCF(ioutil.Discard, openFixture(t, "sample.vcf"), []int{3}, []int{0})
+	checkError(t, err, "line 5: no column for sample 4")
+}
@@ -0,0 +1,240 @@
+package genomics
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path"
+	"strconv"
+	"strings"
+)
+
+const (
+	// maxXLSXSize bounds the workbooks read in memory, zip archives can not be streamed
+	maxXLSXSize = 64 * 1024 * 1024
+	// maxXLSXPartSize bounds the decompressed size of each part, whatever size its zip header claims
+	maxXLSXPartSize = 256 * 1024 * 1024
+)
+
+type xlsxWorkbook struct {
+	Sheets []struct {
+		Name string `xml:"name,attr"`
+		ID   string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr"`
+	} `xml:"sheets>sheet"`
+}
+
+type xlsxRelationships struct {
+	Relationships []struct {
+		ID     string `xml:"Id,attr"`
+		Target string `xml:"Target,attr"`
+	} `xml:"Relationship"`
+}
+
+// xlsxText is a shared or inline string, either plain or made of formatted runs
+type xlsxText struct {
+	T    string `xml:"t"`
+	Runs []struct {
+		T string `xml:"t"`
+	} `xml:"r"`
+}
+
+func (t xlsxText) String() string {
+	if len(t.Runs) == 0 {
+		return t.T
+	}
+	var text strings.Builder
+	for _, run := range t.Runs {
+		text.WriteString(run.T)
+	}
+	return text.String()
+}
+
+type xlsxSharedStrings struct {
+	Items []xlsxText `xml:"si"`
+}
+
+type xlsxSheet struct {
+	Rows []struct {
+		R     int `xml:"r,attr"`
+		Cells []struct {
+			R      string   `xml:"r,attr"`
+			T      string   `xml:"t,attr"`
+			V      string   `xml:"v"`
+			Inline xlsxText `xml:"is"`
+		} `xml:"c"`
+	} `xml:"sheetData>row"`
+}
+
+// readXLSX returns the rows of the first worksheet of an XLSX workbook as text, numbered as in the
+// workbook. Empty rows are skipped and error cells such as #N/A read as empty.
+func readXLSX(r io.Reader) ([]tableRow, error) {
+	data, err := ioutil.ReadAll(io.LimitReader(r, maxXLSXSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxXLSXSize {
+		return nil, fmt.Errorf("XLSX workbooks over %d MB can not be read, save the sheet as CSV", maxXLSXSize/1024/1024)
+	}
+
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, errors.New("not an XLSX workbook")
+	}
+	files := make(map[string]*zip.File, len(archive.File))
+	for _, file := range archive.File {
+		files[file.Name] = file
+	}
+
+	sheetPath, err := firstSheetPath(files)
+	if err != nil {
+		return nil, err
+	}
+
+	var sharedStrings xlsxSharedStrings
+	if files["xl/sharedStrings.xml"] != nil {
+		err = readXMLFile(files["xl/sharedStrings.xml"], &sharedStrings)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var sheet xlsxSheet
+	err = readXMLFile(files[sheetPath], &sheet)
+	if err != nil {
+		return nil, err
+	}
+
+	rows := []tableRow{}
+	for i, row := range sheet.Rows {
+		number := row.R
+		if number == 0 {
+			number = i + 1
+		}
+
+		var fields []string
+		empty := true
+		for j, cell := range row.Cells {
+			column := j
+			if cell.R != "" {
+				column, err = cellColumn(cell.R)
+				if err != nil {
+					return nil, fmt.Errorf("row %d: %v", number, err)
+				}
+			}
+
+			var value string
+			switch cell.T {
+			case "s":
+				index, err := strconv.Atoi(cell.V)
+				if err != nil || index < 0 || index >= len(sharedStrings.Items) {
+					return nil, fmt.Errorf("row %d: invalid shared string %q", number, cell.V)
+				}
+				value = sharedStrings.Items[index].String()
+			case "inlineStr":
+				value = cell.Inline.String()
+			case "e":
+			default:
+				value = cell.V
+			}
+			value = strings.TrimSpace(value)
+
+			for len(fields) <= column {
+				fields = append(fields, "")
+			}
+			fields[column] = value
+			if value != "" {
+				empty = false
+			}
+		}
+
+		if !empty {
+			rows = append(rows, tableRow{number: number, fields: fields})
+		}
+	}
+
+	return rows, nil
+}
+
+// firstSheetPath finds the part of the first sheet of the workbook through its relationships
+func firstSheetPath(files map[string]*zip.File) (string, error) {
+	if files["xl/workbook.xml"] == nil {
+		return "", errors.New("not an XLSX workbook")
+	}
+
+	var workbook xlsxWorkbook
+	err := readXMLFile(files["xl/workbook.xml"], &workbook)
+	if err != nil {
+		return "", err
+	}
+	if len(workbook.Sheets) == 0 {
+		return "", errors.New("workbook contains no sheets")
+	}
+
+	var relationships xlsxRelationships
+	if files["xl/_rels/workbook.xml.rels"] != nil {
+		err = readXMLFile(files["xl/_rels/workbook.xml.rels"], &relationships)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	for _, relationship := range relationships.Relationships {
+		if relationship.ID != workbook.Sheets[0].ID {
+			continue
+		}
+		sheetPath := path.Join("xl", relationship.Target)
+		if strings.HasPrefix(relationship.Target, "/") {
+			sheetPath = strings.TrimPrefix(relationship.Target, "/")
+		}
+		if files[sheetPath] == nil {
+			return "", fmt.Errorf("sheet %s is missing", workbook.Sheets[0].Name)
+		}
+		return sheetPath, nil
+	}
+
+	return "", fmt.Errorf("sheet %s is missing", workbook.Sheets[0].Name)
+}
+
+func readXMLFile(file *zip.File, v interface{}) error {
+	tooLarge := fmt.Errorf("XLSX part %s is over %d MB uncompressed, save the sheet as CSV", file.Name, maxXLSXPartSize/1024/1024)
+	if file.UncompressedSize64 > maxXLSXPartSize {
+		return tooLarge
+	}
+
+	content, err := file.Open()
+	if err != nil {
+		return err
+	}
+	defer content.Close()
+
+	limited := &io.LimitedReader{R: content, N: maxXLSXPartSize + 1}
+	err = xml.NewDecoder(limited).Decode(v)
+	if limited.N <= 0 {
+		return tooLarge
+	}
+	if err != nil {
+		return fmt.Errorf("invalid XLSX part %s: %v", file.Name, err)
+	}
+	return nil
+}
+
+// cellColumn returns the zero based column of a cell reference such as "AB12"
+func cellColumn(reference string) (int, error) {
+	column := 0
+	letters := 0
+	for _, c := range strings.ToUpper(reference) {
+		if c < 'A' || c > 'Z' {
+			break
+		}
+		column = column*26 + int(c-'A'+1)
+		letters++
+	}
+	if letters == 0 || letters > 3 {
+		return 0, fmt.Errorf("invalid cell reference %q", reference)
+	}
+	return column - 1, nil
+}
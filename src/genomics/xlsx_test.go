@@ -0,0 +1,34 @@
+package genomics
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/flate"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestReadXLSXPartSize(t *testing.T) {
+	var workbook bytes.Buffer
+	archive := zip.NewWriter(&workbook)
+	archive.RegisterCompressor(zip.Deflate, func(w io.Writer) (io.WriteCloser, error) {
+		return flate.NewWriter(w, flate.BestSpeed)
+	})
+	part, err := archive.Create("xl/workbook.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// whitespace compresses to almost nothing but the decoder has to read through all of it
+	padding := []byte(strings.Repeat(" ", 1024*1024))
+	for written := 0; written <= maxXLSXPartSize; written += len(padding) {
+		part.Write(padding)
+	}
+	archive.Close()
+	if workbook.Len() > maxXLSXSize {
+		t.Fatalf("workbook has %d bytes, over the limit of the compressed file", workbook.Len())
+	}
+
+	_, err = ReadPhenotype(&workbook, "xlsx")
+	checkError(t, err, "XLSX part xl/workbook.xml is over 256 MB uncompressed")
+}
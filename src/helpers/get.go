@@ -32,15 +32,27 @@ func GetRoleScopes(userScopes []string) (*RoleScopes, error) {
 	}, nil
 }
 
+// compoundExtensions are kept whole, so that "panel.vcf.gz" is told apart from other gzipped files
+var compoundExtensions = []string{"vcf.gz", "vcf.bgz", "plink.tar", "hmp.txt"}
+
+// GetMimeTypeFromFilename returns the lowercased extension of a file name, the last one unless the name
+// ends with a compound extension
 func GetMimeTypeFromFilename(fileName string) (string, error) {
+	fileName = strings.ToLower(fileName[strings.LastIndexAny(fileName, `/\`)+1:])
 	if fileName == "" || !strings.Contains(fileName, ".") {
 		return ",", errors.New("invalid filename")
 	}
 
-	s := strings.Split(fileName, ".")
-	if len(s) != 2 {
+	for _, extension := range compoundExtensions {
+		if strings.HasSuffix(fileName, "."+extension) {
+			return extension, nil
+		}
+	}
+
+	extension := fileName[strings.LastIndex(fileName, ".")+1:]
+	if extension == "" {
 		return ",", errors.New("invalid filename")
 	}
 
-	return strings.ToLower(s[1]), nil
+	return extension, nil
 }
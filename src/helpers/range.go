@@ -0,0 +1,59 @@
+package helpers
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// ParseRange reads a single "bytes=" range, several ranges are answered with the whole file as
+// RFC 7233 allows. It returns the offset and length to send and whether they are a part of the file.
+func ParseRange(header string, size int64) (int64, int64, bool, error) {
+	if !strings.HasPrefix(header, "bytes=") {
+		return 0, 0, false, errors.New("only byte ranges are supported")
+	}
+	spec := strings.TrimSpace(strings.TrimPrefix(header, "bytes="))
+	if strings.Contains(spec, ",") {
+		return 0, size, false, nil
+	}
+
+	bounds := strings.SplitN(spec, "-", 2)
+	if len(bounds) != 2 {
+		return 0, 0, false, errors.New("invalid range")
+	}
+
+	if bounds[0] == "" {
+		suffix, err := strconv.ParseInt(bounds[1], 10, 64)
+		if err != nil || suffix <= 0 {
+			return 0, 0, false, errors.New("invalid range")
+		}
+		if suffix > size {
+			suffix = size
+		}
+		if suffix == 0 {
+			return 0, 0, false, errors.New("range is not satisfiable")
+		}
+		return size - suffix, suffix, true, nil
+	}
+
+	start, err := strconv.ParseInt(bounds[0], 10, 64)
+	if err != nil || start < 0 {
+		return 0, 0, false, errors.New("invalid range")
+	}
+	if start >= size {
+		return 0, 0, false, errors.New("range is not satisfiable")
+	}
+
+	end := size - 1
+	if bounds[1] != "" {
+		end, err = strconv.ParseInt(bounds[1], 10, 64)
+		if err != nil || end < start {
+			return 0, 0, false, errors.New("invalid range")
+		}
+		if end > size-1 {
+			end = size - 1
+		}
+	}
+
+	return start, end - start + 1, true, nil
+}
@@ -0,0 +1,56 @@
+package helpers
+
+import "testing"
+
+func TestParseRange(t *testing.T) {
+	tests := []struct {
+		header  string
+		size    int64
+		offset  int64
+		length  int64
+		partial bool
+		invalid bool
+	}{
+		{header: "bytes=0-99", size: 1000, offset: 0, length: 100, partial: true},
+		{header: "bytes=100-", size: 1000, offset: 100, length: 900, partial: true},
+		{header: "bytes=900-5000", size: 1000, offset: 900, length: 100, partial: true},
+		{header: "bytes=999-999", size: 1000, offset: 999, length: 1, partial: true},
+		{header: "bytes= 10-19 ", size: 1000, offset: 10, length: 10, partial: true},
+		// suffix ranges count from the end and are clipped to the file
+		{header: "bytes=-100", size: 1000, offset: 900, length: 100, partial: true},
+		{header: "bytes=-5000", size: 1000, offset: 0, length: 1000, partial: true},
+		{header: "bytes=-0", size: 1000, invalid: true},
+		{header: "bytes=-1", size: 0, invalid: true},
+		// a start past the end can not be satisfied
+		{header: "bytes=1000-", size: 1000, invalid: true},
+		{header: "bytes=1000-1001", size: 1000, invalid: true},
+		{header: "bytes=0-", size: 0, invalid: true},
+		// several ranges get the whole file
+		{header: "bytes=0-9,20-29", size: 1000, offset: 0, length: 1000},
+		{header: "bytes=0-9, -5", size: 1000, offset: 0, length: 1000},
+		{header: "items=0-9", size: 1000, invalid: true},
+		{header: "bytes=10-5", size: 1000, invalid: true},
+		{header: "bytes=a-b", size: 1000, invalid: true},
+		{header: "bytes=-", size: 1000, invalid: true},
+		{header: "bytes=5", size: 1000, invalid: true},
+		{header: "bytes=-5-10", size: 1000, invalid: true},
+	}
+
+	for _, test := range tests {
+		offset, length, partial, err := ParseRange(test.header, test.size)
+		if test.invalid {
+			if err == nil {
+				t.Errorf("ParseRange(%q, %d) = %d, %d, %v, want an error", test.header, test.size, offset, length, partial)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ParseRange(%q, %d) failed: %v", test.header, test.size, err)
+			continue
+		}
+		if offset != test.offset || length != test.length || partial != test.partial {
+			t.Errorf("ParseRange(%q, %d) = %d, %d, %v, want %d, %d, %v",
+				test.header, test.size, offset, length, partial, test.offset, test.length, test.partial)
+		}
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"errors"
 
 	"github.com/gin-gonic/gin"
+	"github.com/khoa5773/go-server/src/configs"
 	"github.com/khoa5773/go-server/src/constant"
 	"github.com/khoa5773/go-server/src/helpers"
 	"gopkg.in/mgo.v2/bson"
@@ -84,3 +85,28 @@ func Scopes(requiredScopes []string) gin.HandlerFunc {
 		c.Abort()
 	}
 }
+
+// HasProjectScope tells whether the user holds a scope in the project of the route, for the routes that
+// let the creator of a resource do more than its scopes allow
+func HasProjectScope(c *gin.Context, scope string) bool {
+	projectID := c.Param("projectID")
+	for index, v := range c.MustGet("userProjectIDs").([]bson.ObjectId) {
+		if v.Hex() == projectID {
+			_, result := helpers.CheckItemExists(c.MustGet("userScopes").([][]string)[index], scope)
+			return result
+		}
+	}
+	return false
+}
+
+// Admin lets through the users listed in ADMIN_IDS only
+func Admin(c *gin.Context) {
+	_, isAdmin := helpers.CheckItemExists(configs.ConfigsService.AdminIDs, c.MustGet("userID").(string))
+	if !isAdmin {
+		_ = c.Error(errors.New("no permission"))
+		c.Abort()
+		return
+	}
+
+	c.Next()
+}
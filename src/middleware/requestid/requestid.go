@@ -0,0 +1,27 @@
+package requestid
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"regexp"
+
+	"github.com/gin-gonic/gin"
+)
+
+const Header = "X-Request-ID"
+
+var validRequestID = regexp.MustCompile(`^[A-Za-z0-9._-]{1,64}$`)
+
+// RequestID keeps the id sent by a proxy when it looks sane, or generates one, and echoes it back
+func RequestID(c *gin.Context) {
+	id := c.GetHeader(Header)
+	if !validRequestID.MatchString(id) {
+		buffer := make([]byte, 16)
+		_, _ = rand.Read(buffer)
+		id = hex.EncodeToString(buffer)
+	}
+
+	c.Set("requestID", id)
+	c.Header(Header, id)
+	c.Next()
+}
@@ -13,6 +13,7 @@ type Credentials struct {
 	HasProjectScopes  bool
 	ProjectIDs        []bson.ObjectId
 	IsAdmin           bool
+	RequestID         string
 }
 
 type ValidateAccess struct {